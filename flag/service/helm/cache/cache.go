@@ -0,0 +1,7 @@
+package cache
+
+type Cache struct {
+	Directory string
+	MaxAge    string
+	MaxSize   string
+}
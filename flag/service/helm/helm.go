@@ -1,15 +1,30 @@
 package helm
 
 import (
+	"github.com/giantswarm/chart-operator/v4/flag/service/helm/cache"
 	"github.com/giantswarm/chart-operator/v4/flag/service/helm/http"
 	"github.com/giantswarm/chart-operator/v4/flag/service/helm/kubernetes"
+	"github.com/giantswarm/chart-operator/v4/flag/service/helm/maintenancewindow"
+	"github.com/giantswarm/chart-operator/v4/flag/service/helm/orphanrelease"
+	"github.com/giantswarm/chart-operator/v4/flag/service/helm/retry"
+	"github.com/giantswarm/chart-operator/v4/flag/service/helm/verification"
 )
 
 type Helm struct {
+	Cache       cache.Cache
 	HTTP        http.HTTP
 	Kubernetes  kubernetes.Kubernetes
 	MaxRollback string
 
+	// MigrateHelmV2Releases enables migrating the Tiller config maps of Helm 2
+	// releases to Helm 3 release secrets. The config maps are archived with
+	// a label rather than deleted.
+	MigrateHelmV2Releases string
+
+	// MaintenanceWindow defers upgrades of releases to the times matched by
+	// its cron schedule. It can be overridden per chart CR with annotations.
+	MaintenanceWindow maintenancewindow.MaintenanceWindow
+
 	// SplitClient determines usage of additional pubHelmClient impersonating
 	// `default:automation` Service Account for App CRs created outside the
 	// `giantswarm` namespace. When `false` Chart Operator runs under full
@@ -17,5 +32,15 @@ type Helm struct {
 	SplitClient        string
 	NamespaceWhitelist string
 
+	// OrphanRelease configures the garbage collection of Helm releases
+	// managed by chart-operator whose chart CR was deleted.
+	OrphanRelease orphanrelease.OrphanRelease
+
+	// Retry configures the back-off between retries of failed installs and
+	// upgrades. It can be overridden per chart CR with annotations.
+	Retry retry.Retry
+
 	TillerNamespace string
+
+	Verification verification.Verification
 }
@@ -0,0 +1,6 @@
+package maintenancewindow
+
+type MaintenanceWindow struct {
+	Duration string
+	Schedule string
+}
@@ -0,0 +1,6 @@
+package orphanrelease
+
+type OrphanRelease struct {
+	GC          string
+	GracePeriod string
+}
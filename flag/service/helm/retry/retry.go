@@ -0,0 +1,7 @@
+package retry
+
+type Retry struct {
+	BaseDelay string
+	Jitter    string
+	MaxDelay  string
+}
@@ -0,0 +1,5 @@
+package verification
+
+type Verification struct {
+	Keyrings string
+}
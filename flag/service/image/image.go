@@ -1,5 +1,6 @@
 package image
 
 type Image struct {
-	Registry string
+	Registry          string
+	RewriteRegistries string
 }
@@ -107,13 +107,26 @@ func mainWithError() error {
 	daemonCommand := newCommand.DaemonCommand().CobraCommand()
 
 	daemonCommand.PersistentFlags().String(f.Service.Controller.ResyncPeriod, "5m", "Duration after which a complete sync with all known runtime objects the controller watches is performed.")
+	daemonCommand.PersistentFlags().String(f.Service.Helm.Cache.Directory, "/tmp/chart-operator/cache", "Directory for caching chart tarballs.")
+	daemonCommand.PersistentFlags().String(f.Service.Helm.Cache.MaxAge, "10m", "Duration after which chart tarballs cached for a tarball URL are pulled again. Tarballs are never pulled again when set to 0.")
+	daemonCommand.PersistentFlags().String(f.Service.Helm.Cache.MaxSize, "32Mi", "Maximum size of the chart tarball cache. Caching is disabled when set to 0.")
 	daemonCommand.PersistentFlags().String(f.Service.Helm.HTTP.ClientTimeout, "5s", "HTTP timeout for pulling chart tarballs.")
 	daemonCommand.PersistentFlags().String(f.Service.Helm.Kubernetes.WaitTimeout, "10s", "Wait timeout when calling the Kubernetes API.")
+	daemonCommand.PersistentFlags().String(f.Service.Helm.MaintenanceWindow.Duration, "", "Duration the cluster-wide maintenance window is open, e.g. 4h.")
+	daemonCommand.PersistentFlags().String(f.Service.Helm.MaintenanceWindow.Schedule, "", "Cron schedule in UTC at which the cluster-wide maintenance window for upgrades opens. Upgrades are not deferred when empty.")
 	daemonCommand.PersistentFlags().Int(f.Service.Helm.MaxRollback, 3, "the maximum number of rollback attempts for pending apps.")
+	daemonCommand.PersistentFlags().Bool(f.Service.Helm.MigrateHelmV2Releases, false, "Migrate Helm 2 releases found in the Tiller namespace to Helm 3 instead of not reconciling their chart CRs.")
+	daemonCommand.PersistentFlags().Bool(f.Service.Helm.OrphanRelease.GC, false, "Uninstall Helm releases managed by chart-operator whose chart CR was deleted after the grace period.")
+	daemonCommand.PersistentFlags().String(f.Service.Helm.OrphanRelease.GracePeriod, "24h", "Duration a Helm release must be without chart CR before it is uninstalled.")
 	daemonCommand.PersistentFlags().StringSlice(f.Service.Helm.NamespaceWhitelist, []string{}, "Namespaces to use the privileged Helm Client for.")
 	daemonCommand.PersistentFlags().Bool(f.Service.Helm.SplitClient, false, "Use separate Helm Client for apps outside Giantswarm-protected namespace.")
+	daemonCommand.PersistentFlags().String(f.Service.Helm.Retry.BaseDelay, "30s", "Delay before retrying a failed install or upgrade. It is doubled with every failed attempt in a row.")
+	daemonCommand.PersistentFlags().Float64(f.Service.Helm.Retry.Jitter, 0.2, "Fraction between 0 and 1 by which retry delays are randomly shortened.")
+	daemonCommand.PersistentFlags().String(f.Service.Helm.Retry.MaxDelay, "30m", "Maximum delay before retrying a failed install or upgrade.")
 	daemonCommand.PersistentFlags().String(f.Service.Helm.TillerNamespace, "giantswarm", "Namespace for the Tiller pod.")
+	daemonCommand.PersistentFlags().StringSlice(f.Service.Helm.Verification.Keyrings, []string{}, "Keyrings to verify chart provenance with as <tarball URL prefix>=<keyring path>.")
 	daemonCommand.PersistentFlags().String(f.Service.Image.Registry, "gsoci.azurecr.io", "Container image registry.")
+	daemonCommand.PersistentFlags().StringSlice(f.Service.Image.RewriteRegistries, []string{}, "Registries whose container images in rendered manifests are rewritten to the container image registry. Images are not rewritten when empty.")
 	daemonCommand.PersistentFlags().String(f.Service.Kubernetes.Address, "", "Address used to connect to Kubernetes. When empty in-cluster config is created.")
 	daemonCommand.PersistentFlags().Bool(f.Service.Kubernetes.InCluster, false, "Whether to use the in-cluster config to authenticate with Kubernetes.")
 	daemonCommand.PersistentFlags().String(f.Service.Kubernetes.KubeConfig, "", "KubeConfig used to connect to Kubernetes. When empty other settings are used.")
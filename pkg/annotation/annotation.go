@@ -7,6 +7,19 @@ const (
 	// reconciling the resource.
 	ChartOperatorPaused = "chart-operator.giantswarm.io/paused"
 
+	// AtomicRollbackCount is the name of the annotation storing the number of
+	// rollbacks performed after failed upgrades of atomic releases.
+	AtomicRollbackCount = "chart-operator.giantswarm.io/atomic-rollback-count"
+
+	// AtomicUpgrade is the name of the annotation that when set to true makes
+	// chart-operator roll back failed upgrades to the last deployed revision.
+	AtomicUpgrade = "chart-operator.giantswarm.io/atomic-upgrade"
+
+	// ChartDigest is the name of the annotation storing the expected SHA-256
+	// digest of the chart tarball, e.g. sha256:4b1e... Pulled tarballs with
+	// a different digest are rejected.
+	ChartDigest = "chart-operator.giantswarm.io/chart-digest"
+
 	// CordonReason is the name of the annotation that indicates
 	// the reason of why chart-operator should not apply any update on this chart CR.
 	CordonReason = "chart-operator.giantswarm.io/cordon-reason"
@@ -15,17 +28,164 @@ const (
 	// the expiration date of rule of this cordon.
 	CordonUntilDate = "chart-operator.giantswarm.io/cordon-until"
 
+	// CRDChanges is the name of the annotation summarizing the CRDs created
+	// and updated according to the CRD policy of the chart CR by the last
+	// install or upgrade.
+	CRDChanges = "chart-operator.giantswarm.io/crd-changes"
+
+	// CRDPolicy is the name of the annotation selecting how the CRDs of the
+	// crds directories of the chart are managed on install and upgrade. It
+	// is one of Skip, Create or CreateReplace. When not set Helm creates
+	// missing CRDs on install only, unless the chart CR skips CRDs.
+	CRDPolicy = "chart-operator.giantswarm.io/crd-policy"
+
+	// DependsOn is the name of the annotation listing the chart CRs which
+	// must be deployed before the release is installed or upgraded as JSON.
+	// The namespace defaults to the namespace of the chart CR and the
+	// optional version is a semver constraint, e.g.
+	//
+	//	[{"name":"prometheus-operator-crd","namespace":"giantswarm","version":">=1.0.0"}]
+	//
+	DependsOn = "chart-operator.giantswarm.io/depends-on"
+
+	// DriftDetection is the name of the annotation that when set to true
+	// makes chart-operator compare the objects of the deployed release with
+	// its manifest and report drifted objects in the CR status.
+	DriftDetection = "chart-operator.giantswarm.io/drift-detection"
+
+	// DryRun is the name of the annotation that when set to true makes
+	// chart-operator render and diff upgrades against the deployed release
+	// instead of applying them.
+	DryRun = "chart-operator.giantswarm.io/dry-run"
+
+	// DryRunChecksum is the name of the annotation storing the checksum of
+	// the chart, values and deployed revision the last dry-run diff was
+	// computed for. The chart is only diffed again when one of them changes.
+	DryRunChecksum = "chart-operator.giantswarm.io/dry-run-checksum"
+
+	// FailedAttempts is the name of the annotation storing the number of
+	// failed installs and upgrades in a row. It is removed once an install or
+	// upgrade succeeds.
+	FailedAttempts = "chart-operator.giantswarm.io/failed-attempts"
+
 	// ForceHelmUpgrade is the name of the annotation that controls whether
 	// force is used when upgrading the Helm release.
 	ForceHelmUpgrade = "chart-operator.giantswarm.io/force-helm-upgrade"
 
+	// HealthCheck is the name of the annotation that when set to true makes
+	// chart-operator check the readiness of the Deployments, StatefulSets,
+	// DaemonSets and Jobs of deployed releases.
+	HealthCheck = "chart-operator.giantswarm.io/health-check"
+
+	// IgnoreMaintenanceWindow is the name of the annotation that when set to
+	// true makes chart-operator upgrade the release outside its maintenance
+	// window, e.g. for emergency fixes.
+	IgnoreMaintenanceWindow = "chart-operator.giantswarm.io/ignore-maintenance-window"
+
+	// MaintenanceWindow is the name of the annotation with the cron schedule
+	// in UTC at which the maintenance window of the chart CR opens, e.g.
+	// `0 22 * * 1-5`. It overrides the cluster-wide maintenance window.
+	MaintenanceWindow = "chart-operator.giantswarm.io/maintenance-window"
+
+	// MaintenanceWindowDuration is the name of the annotation with the
+	// duration the maintenance window of the chart CR is open, e.g. 4h.
+	MaintenanceWindowDuration = "chart-operator.giantswarm.io/maintenance-window-duration"
+
+	// NextRetry is the name of the annotation storing the RFC3339 time before
+	// which a failed install or upgrade is not retried.
+	NextRetry = "chart-operator.giantswarm.io/next-retry"
+
+	// PostRenderPatches is the name of the annotation referencing the config
+	// map of Kustomize patches applied to the rendered manifests on installs
+	// and upgrades, either as name in the namespace of the chart CR or as
+	// namespace/name. Every key of the config map holds a YAML list of
+	// patches with an inline strategic merge or JSON 6902 patch and an
+	// optional target, e.g.
+	//
+	//	- target:
+	//	    kind: Deployment
+	//	  patch: |
+	//	    - op: add
+	//	      path: /spec/template/spec/tolerations
+	//	      value: [{"operator": "Exists"}]
+	//
+	// The chart is rendered by chart-operator before Helm installs or
+	// upgrades the release with a chart of the post-rendered manifests.
+	// Hooks are not patched, .Release.Revision is always 1 and charts using
+	// the lookup function are rejected, since templates are rendered without
+	// access to the cluster.
+	PostRenderPatches = "chart-operator.giantswarm.io/post-render-patches"
+
+	// PullSecret is the name of the annotation referencing the docker config
+	// secret used to authenticate pulls of oci:// charts, either as name in
+	// the namespace of the chart CR or as namespace/name.
+	PullSecret = "chart-operator.giantswarm.io/pull-secret"
+
+	// ReleaseHistory is the name of the annotation storing the last revisions
+	// of the Helm release of the chart CR as a JSON list, newest first. The
+	// chart CRD does not define a history in its status so it is kept in an
+	// annotation instead.
+	ReleaseHistory = "chart-operator.giantswarm.io/release-history"
+
+	// RetryBaseDelay is the name of the annotation overriding the delay
+	// before retrying the first failed install or upgrade, e.g. 1m.
+	RetryBaseDelay = "chart-operator.giantswarm.io/retry-base-delay"
+
+	// RetryJitter is the name of the annotation overriding the fraction
+	// between 0 and 1 by which retry delays are randomly shortened.
+	RetryJitter = "chart-operator.giantswarm.io/retry-jitter"
+
+	// RetryMaxDelay is the name of the annotation overriding the maximum
+	// delay before retrying a failed install or upgrade, e.g. 1h.
+	RetryMaxDelay = "chart-operator.giantswarm.io/retry-max-delay"
+
 	// RollbackCount is the name of the annotation storing the number of
 	// rollbacks performed from the previous pending status.
 	RollbackCount = "chart-operator.giantswarm.io/rollback-count"
 
+	// SelfHeal is the name of the annotation that when set to true makes
+	// chart-operator re-apply the deployed release when its objects drifted
+	// from its manifest. It implies drift detection.
+	SelfHeal = "chart-operator.giantswarm.io/self-heal"
+
+	// SelfHealAttempts is the name of the annotation storing the number of
+	// times in a row the release was re-applied to restore drifted objects.
+	// Self-healing stops after 3 attempts until the annotation is removed.
+	SelfHealAttempts = "chart-operator.giantswarm.io/self-heal-attempts"
+
+	// SkipImageRewrite is the name of the annotation that when set to true
+	// makes chart-operator keep the container images of the release
+	// unchanged when rewriting images to the configured registry is enabled.
+	SkipImageRewrite = "chart-operator.giantswarm.io/skip-image-rewrite"
+
+	// ValuesChecksum is the name of the annotation storing a checksum of the
+	// Helm release values.
+	ValuesChecksum = "chart-operator.giantswarm.io/values-checksum"
+
+	// ValuesChecksumAlgorithm is the name of the annotation storing the
+	// algorithm of the values checksum, e.g. sha256.
+	ValuesChecksumAlgorithm = "chart-operator.giantswarm.io/values-checksum-algorithm"
+
 	// ValuesMD5Checksum is the name of the annotation storing an MD5 checksum
 	// of the Helm release values.
+	//
+	// Deprecated: It is replaced by ValuesChecksum and only read to migrate
+	// chart CRs without upgrading their releases.
 	ValuesMD5Checksum = "chart-operator.giantswarm.io/values-md5-checksum"
 
+	// ValuesSources is the name of the annotation listing additional config
+	// maps and secrets with Helm values as JSON. They are merged in declared
+	// order after the config map and secret of the chart CR spec, e.g.
+	//
+	//	[{"kind":"ConfigMap","name":"cluster-values","namespace":"org-acme"},
+	//	 {"kind":"Secret","name":"user-secrets","key":"values"}]
+	//
+	ValuesSources = "chart-operator.giantswarm.io/values-sources"
+
+	// VerifiedDigest is the name of the annotation storing the digest of the
+	// chart tarball verified against its provenance file, e.g. sha256:4b1e...
+	// The chart CRD status has no field for it.
+	VerifiedDigest = "chart-operator.giantswarm.io/verified-digest"
+
 	Webhook = "chart-operator.giantswarm.io/webhook-url"
 )
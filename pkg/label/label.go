@@ -5,4 +5,9 @@ package label
 const (
 	// App is a standard label for Kubernetes resources.
 	App = "app"
+
+	// HelmV2Archived is the label chart-operator sets to true on the Tiller
+	// config maps of Helm 2 releases it migrated to Helm 3. The config maps
+	// are kept for reference but no longer block the chart CR.
+	HelmV2Archived = "chart-operator.giantswarm.io/helm-v2-archived"
 )
@@ -9,10 +9,10 @@ var (
 )
 
 const (
-	// ReleaseFailedMaxAttempts when a release fails this number of times in a
-	// row we stop updating. This is because the Helm max history setting does
-	// not apply for failures.
-	ReleaseFailedMaxAttempts = 5
+	// ReleaseFailedMaxRevisions is the number of failed revisions in a row
+	// kept for a release. Older failed revisions are deleted because the Helm
+	// max history setting does not apply for failures.
+	ReleaseFailedMaxRevisions = 5
 )
 
 // ChartVersion is fixed for chart CRs. This is because they exist in both
@@ -0,0 +1,104 @@
+package collector
+
+import (
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/giantswarm/chart-operator/v4/service/internal/chartcache"
+)
+
+const (
+	chartCacheSubsystem = "chart_cache"
+)
+
+var (
+	chartCacheEvictionsDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, chartCacheSubsystem, "evictions_total"),
+		"Number of chart tarballs evicted from the cache.",
+		[]string{},
+		nil,
+	)
+	chartCacheHitsDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, chartCacheSubsystem, "hits_total"),
+		"Number of chart tarballs served from the cache.",
+		[]string{},
+		nil,
+	)
+	chartCacheMissesDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, chartCacheSubsystem, "misses_total"),
+		"Number of chart tarballs not found in the cache.",
+		[]string{},
+		nil,
+	)
+	chartCacheSizeDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, chartCacheSubsystem, "size_bytes"),
+		"Size of all chart tarballs in the cache in bytes.",
+		[]string{},
+		nil,
+	)
+)
+
+type ChartCacheConfig struct {
+	ChartCache *chartcache.Cache
+	Logger     micrologger.Logger
+}
+
+type ChartCache struct {
+	chartCache *chartcache.Cache
+	logger     micrologger.Logger
+}
+
+func NewChartCache(config ChartCacheConfig) (*ChartCache, error) {
+	if config.ChartCache == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ChartCache must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	cc := &ChartCache{
+		chartCache: config.ChartCache,
+		logger:     config.Logger,
+	}
+
+	return cc, nil
+}
+
+// Collect emits the counters and the size of the chart tarball cache.
+func (cc *ChartCache) Collect(ch chan<- prometheus.Metric) error {
+	stats := cc.chartCache.Stats()
+
+	ch <- prometheus.MustNewConstMetric(
+		chartCacheEvictionsDesc,
+		prometheus.CounterValue,
+		float64(stats.Evictions),
+	)
+	ch <- prometheus.MustNewConstMetric(
+		chartCacheHitsDesc,
+		prometheus.CounterValue,
+		float64(stats.Hits),
+	)
+	ch <- prometheus.MustNewConstMetric(
+		chartCacheMissesDesc,
+		prometheus.CounterValue,
+		float64(stats.Misses),
+	)
+	ch <- prometheus.MustNewConstMetric(
+		chartCacheSizeDesc,
+		prometheus.GaugeValue,
+		float64(stats.Size),
+	)
+
+	return nil
+}
+
+// Describe emits the description for the metrics collected here.
+func (cc *ChartCache) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- chartCacheEvictionsDesc
+	ch <- chartCacheHitsDesc
+	ch <- chartCacheMissesDesc
+	ch <- chartCacheSizeDesc
+
+	return nil
+}
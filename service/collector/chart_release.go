@@ -0,0 +1,235 @@
+package collector
+
+import (
+	"context"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v7/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+)
+
+const (
+	chartSubsystem = "chart"
+
+	// unknownReleaseStatus is exported for chart CRs whose release status
+	// has not been reported yet.
+	unknownReleaseStatus = "unknown"
+)
+
+var (
+	chartLabels = []string{"name", "namespace"}
+
+	chartReleaseStatusDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, chartSubsystem, "release_status"),
+		"Status of the Helm release of the chart CR, always 1.",
+		[]string{"name", "namespace", "status"},
+		nil,
+	)
+	chartReleaseInfoDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, chartSubsystem, "release_info"),
+		"Deployed chart and app version of the Helm release of the chart CR, always 1.",
+		[]string{"name", "namespace", "release", "release_namespace", "version", "app_version"},
+		nil,
+	)
+	chartReleaseRevisionDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, chartSubsystem, "release_revision"),
+		"Revision of the deployed Helm release of the chart CR.",
+		chartLabels,
+		nil,
+	)
+	chartReleaseLastDeployedDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, chartSubsystem, "release_last_deployed_timestamp_seconds"),
+		"Unix time the Helm release of the chart CR was last deployed.",
+		chartLabels,
+		nil,
+	)
+	chartCordonedDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, chartSubsystem, "cordoned"),
+		"Whether the chart CR is cordoned, 1 when cordoned and 0 otherwise.",
+		chartLabels,
+		nil,
+	)
+	chartFailedAttemptsDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, chartSubsystem, "failed_attempts"),
+		"Failed installs and upgrades in a row of the chart CR.",
+		chartLabels,
+		nil,
+	)
+	chartRetryBackoffDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, chartSubsystem, "retry_backoff"),
+		"Whether retries of the failed release of the chart CR are backing off, 1 when backing off and 0 otherwise.",
+		chartLabels,
+		nil,
+	)
+	chartRollbacksDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, chartSubsystem, "rollbacks"),
+		"Rollbacks of the Helm release of the chart CR, from the pending status or after failed atomic upgrades.",
+		[]string{"name", "namespace", "type"},
+		nil,
+	)
+)
+
+type ChartReleaseConfig struct {
+	K8sClient k8sclient.Interface
+	Logger    micrologger.Logger
+}
+
+// ChartRelease exports the state of the Helm release of every chart CR as
+// reported in its status and annotations.
+type ChartRelease struct {
+	k8sClient k8sclient.Interface
+	logger    micrologger.Logger
+}
+
+func NewChartRelease(config ChartReleaseConfig) (*ChartRelease, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	cr := &ChartRelease{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+	}
+
+	return cr, nil
+}
+
+func (cr *ChartRelease) Collect(ch chan<- prometheus.Metric) error {
+	ctx := context.Background()
+
+	chartList := &v1alpha1.ChartList{}
+	err := cr.k8sClient.CtrlClient().List(
+		ctx,
+		chartList,
+	)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	now := time.Now()
+
+	for _, chart := range chartList.Items {
+		collectChart(ch, chart, now)
+	}
+
+	return nil
+}
+
+// Describe emits the description for the metrics collected here.
+func (cr *ChartRelease) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- chartReleaseStatusDesc
+	ch <- chartReleaseInfoDesc
+	ch <- chartReleaseRevisionDesc
+	ch <- chartReleaseLastDeployedDesc
+	ch <- chartCordonedDesc
+	ch <- chartFailedAttemptsDesc
+	ch <- chartRetryBackoffDesc
+	ch <- chartRollbacksDesc
+	return nil
+}
+
+func collectChart(ch chan<- prometheus.Metric, chart v1alpha1.Chart, now time.Time) {
+	status := key.ChartStatus(chart)
+
+	releaseStatus := status.Release.Status
+	if releaseStatus == "" {
+		releaseStatus = unknownReleaseStatus
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		chartReleaseStatusDesc,
+		prometheus.GaugeValue,
+		1,
+		chart.Name,
+		chart.Namespace,
+		releaseStatus,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		chartReleaseInfoDesc,
+		prometheus.GaugeValue,
+		1,
+		chart.Name,
+		chart.Namespace,
+		key.ReleaseName(chart),
+		key.Namespace(chart),
+		status.Version,
+		status.AppVersion,
+	)
+
+	if status.Release.Revision != nil {
+		ch <- prometheus.MustNewConstMetric(
+			chartReleaseRevisionDesc,
+			prometheus.GaugeValue,
+			float64(*status.Release.Revision),
+			chart.Name,
+			chart.Namespace,
+		)
+	}
+
+	if status.Release.LastDeployed != nil {
+		ch <- prometheus.MustNewConstMetric(
+			chartReleaseLastDeployedDesc,
+			prometheus.GaugeValue,
+			float64(status.Release.LastDeployed.Unix()),
+			chart.Name,
+			chart.Namespace,
+		)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		chartCordonedDesc,
+		prometheus.GaugeValue,
+		boolToFloat(key.IsCordoned(chart)),
+		chart.Name,
+		chart.Namespace,
+	)
+
+	attempts := key.FailedAttempts(chart)
+
+	ch <- prometheus.MustNewConstMetric(
+		chartFailedAttemptsDesc,
+		prometheus.GaugeValue,
+		float64(attempts),
+		chart.Name,
+		chart.Namespace,
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		chartRetryBackoffDesc,
+		prometheus.GaugeValue,
+		boolToFloat(attempts > 0 && now.Before(key.NextRetry(chart))),
+		chart.Name,
+		chart.Namespace,
+	)
+
+	for rollbackType, count := range map[string]int{
+		"atomic":  key.AtomicRollbackCount(chart),
+		"pending": key.RollbackCount(chart),
+	} {
+		ch <- prometheus.MustNewConstMetric(
+			chartRollbacksDesc,
+			prometheus.GaugeValue,
+			float64(count),
+			chart.Name,
+			chart.Namespace,
+			rollbackType,
+		)
+	}
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
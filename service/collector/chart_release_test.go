@@ -0,0 +1,136 @@
+package collector
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v7/pkg/k8sclienttest"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
+)
+
+func Test_ChartRelease_Collect(t *testing.T) {
+	revision := 3
+	lastDeployed := metav1.NewTime(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	charts := []runtime.Object{
+		&v1alpha1.Chart{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "hello-world",
+				Namespace: "giantswarm",
+				Annotations: map[string]string{
+					annotation.AtomicRollbackCount: "1",
+					annotation.CordonReason:        "maintenance",
+					annotation.CordonUntilDate:     time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+					annotation.FailedAttempts:      "2",
+					annotation.NextRetry:           time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+				},
+			},
+			Spec: v1alpha1.ChartSpec{
+				Name:      "hello-world-app",
+				Namespace: "default",
+			},
+			Status: v1alpha1.ChartStatus{
+				AppVersion: "0.2.0",
+				Release: v1alpha1.ChartStatusRelease{
+					LastDeployed: &lastDeployed,
+					Revision:     &revision,
+					Status:       "deployed",
+				},
+				Version: "1.0.0",
+			},
+		},
+		&v1alpha1.Chart{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "pending",
+				Namespace: "giantswarm",
+			},
+			Spec: v1alpha1.ChartSpec{
+				Name:      "pending",
+				Namespace: "giantswarm",
+			},
+		},
+	}
+
+	s := runtime.NewScheme()
+	err := v1alpha1.AddToScheme(s)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	k8sClient := k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+		CtrlClient: fake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(charts...).Build(),
+	})
+
+	c, err := NewChartRelease(ChartReleaseConfig{
+		K8sClient: k8sClient,
+		Logger:    microloggertest.New(),
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	expected := map[string]float64{
+		`chart_operator_chart_cordoned{name="hello-world",namespace="giantswarm"}`:                                                                                               1,
+		`chart_operator_chart_cordoned{name="pending",namespace="giantswarm"}`:                                                                                                   0,
+		`chart_operator_chart_failed_attempts{name="hello-world",namespace="giantswarm"}`:                                                                                        2,
+		`chart_operator_chart_failed_attempts{name="pending",namespace="giantswarm"}`:                                                                                            0,
+		`chart_operator_chart_release_info{app_version="0.2.0",name="hello-world",namespace="giantswarm",release="hello-world-app",release_namespace="default",version="1.0.0"}`: 1,
+		`chart_operator_chart_release_info{app_version="",name="pending",namespace="giantswarm",release="pending",release_namespace="giantswarm",version=""}`:                    1,
+		`chart_operator_chart_release_last_deployed_timestamp_seconds{name="hello-world",namespace="giantswarm"}`:                                                                1704164645,
+		`chart_operator_chart_release_revision{name="hello-world",namespace="giantswarm"}`:                                                                                       3,
+		`chart_operator_chart_release_status{name="hello-world",namespace="giantswarm",status="deployed"}`:                                                                       1,
+		`chart_operator_chart_release_status{name="pending",namespace="giantswarm",status="unknown"}`:                                                                            1,
+		`chart_operator_chart_retry_backoff{name="hello-world",namespace="giantswarm"}`:                                                                                          1,
+		`chart_operator_chart_retry_backoff{name="pending",namespace="giantswarm"}`:                                                                                              0,
+		`chart_operator_chart_rollbacks{name="hello-world",namespace="giantswarm",type="atomic"}`:                                                                                1,
+		`chart_operator_chart_rollbacks{name="hello-world",namespace="giantswarm",type="pending"}`:                                                                               0,
+		`chart_operator_chart_rollbacks{name="pending",namespace="giantswarm",type="atomic"}`:                                                                                    0,
+		`chart_operator_chart_rollbacks{name="pending",namespace="giantswarm",type="pending"}`:                                                                                   0,
+	}
+
+	ch := make(chan prometheus.Metric, 100)
+	err = c.Collect(ch)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	close(ch)
+
+	metrics := map[string]float64{}
+	for m := range ch {
+		metric := &dto.Metric{}
+		err = m.Write(metric)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+
+		labels := make([]string, 0, len(metric.GetLabel()))
+		for _, l := range metric.GetLabel() {
+			labels = append(labels, fmt.Sprintf("%s=%q", l.GetName(), l.GetValue()))
+		}
+
+		metrics[fmt.Sprintf("%s{%s}", fqName(m.Desc()), strings.Join(labels, ","))] = metric.GetGauge().GetValue()
+	}
+
+	if !reflect.DeepEqual(metrics, expected) {
+		t.Fatalf("metrics == %v, want %v", metrics, expected)
+	}
+}
+
+// fqName returns the fully-qualified name of the metric description.
+func fqName(desc *prometheus.Desc) string {
+	s := desc.String()
+	s = s[strings.Index(s, `fqName: "`)+len(`fqName: "`):]
+
+	return s[:strings.Index(s, `"`)]
+}
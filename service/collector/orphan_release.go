@@ -0,0 +1,81 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/giantswarm/k8sclient/v7/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/giantswarm/chart-operator/v4/service/internal/orphanrelease"
+)
+
+var (
+	orphanReleaseDesc *prometheus.Desc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, "release", "orphan"),
+		"Helm releases managed by chart-operator without a chart CR.",
+		[]string{},
+		nil,
+	)
+)
+
+type OrphanReleaseConfig struct {
+	K8sClient k8sclient.Interface
+	Logger    micrologger.Logger
+}
+
+type OrphanRelease struct {
+	k8sClient k8sclient.Interface
+	logger    micrologger.Logger
+}
+
+func NewOrphanRelease(config OrphanReleaseConfig) (*OrphanRelease, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	oc := &OrphanRelease{
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+	}
+
+	return oc, nil
+}
+
+func (oc *OrphanRelease) Collect(ch chan<- prometheus.Metric) error {
+	ctx := context.Background()
+
+	orphans, err := orphanrelease.Find(ctx, oc.k8sClient)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		orphanReleaseDesc,
+		prometheus.GaugeValue,
+		float64(len(orphans)),
+	)
+
+	if len(orphans) > 0 {
+		var orphanReleases []string
+		for _, o := range orphans {
+			orphanReleases = append(orphanReleases, o.String())
+		}
+
+		oc.logger.Log("level", "debug", "message", fmt.Sprintf("found %d orphan releases %s", len(orphans), strings.Join(orphanReleases, " ")))
+	}
+
+	return nil
+}
+
+// Describe emits the description for the metrics collected here.
+func (oc *OrphanRelease) Describe(ch chan<- *prometheus.Desc) error {
+	ch <- orphanReleaseDesc
+	return nil
+}
@@ -0,0 +1,50 @@
+package collector
+
+import (
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/giantswarm/chart-operator/v4/service/internal/releasemetrics"
+)
+
+type ReleaseMetricsConfig struct {
+	Logger         micrologger.Logger
+	ReleaseMetrics *releasemetrics.Metrics
+}
+
+type ReleaseMetrics struct {
+	logger         micrologger.Logger
+	releaseMetrics *releasemetrics.Metrics
+}
+
+func NewReleaseMetrics(config ReleaseMetricsConfig) (*ReleaseMetrics, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.ReleaseMetrics == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ReleaseMetrics must not be empty", config)
+	}
+
+	rm := &ReleaseMetrics{
+		logger:         config.Logger,
+		releaseMetrics: config.ReleaseMetrics,
+	}
+
+	return rm, nil
+}
+
+// Collect emits the operation and drift metrics recorded by the release
+// resource.
+func (rm *ReleaseMetrics) Collect(ch chan<- prometheus.Metric) error {
+	rm.releaseMetrics.Collect(ch)
+
+	return nil
+}
+
+// Describe emits the description for the metrics collected here.
+func (rm *ReleaseMetrics) Describe(ch chan<- *prometheus.Desc) error {
+	rm.releaseMetrics.Describe(ch)
+
+	return nil
+}
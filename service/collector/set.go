@@ -5,11 +5,16 @@ import (
 	"github.com/giantswarm/k8sclient/v7/pkg/k8sclient"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/chart-operator/v4/service/internal/chartcache"
+	"github.com/giantswarm/chart-operator/v4/service/internal/releasemetrics"
 )
 
 type SetConfig struct {
-	K8sClient k8sclient.Interface
-	Logger    micrologger.Logger
+	ChartCache     *chartcache.Cache
+	K8sClient      k8sclient.Interface
+	Logger         micrologger.Logger
+	ReleaseMetrics *releasemetrics.Metrics
 
 	TillerNamespace string
 }
@@ -22,12 +27,18 @@ type Set struct {
 }
 
 func NewSet(config SetConfig) (*Set, error) {
+	if config.ChartCache == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ChartCache must not be empty", config)
+	}
 	if config.K8sClient == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
 	}
 	if config.Logger == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
 	}
+	if config.ReleaseMetrics == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ReleaseMetrics must not be empty", config)
+	}
 
 	if config.TillerNamespace == "" {
 		return nil, microerror.Maskf(invalidConfigError, "%T.TillerNamespace must not be empty", config)
@@ -35,6 +46,32 @@ func NewSet(config SetConfig) (*Set, error) {
 
 	var err error
 
+	var chartCacheCollector *ChartCache
+	{
+		c := ChartCacheConfig{
+			ChartCache: config.ChartCache,
+			Logger:     config.Logger,
+		}
+
+		chartCacheCollector, err = NewChartCache(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var chartReleaseCollector *ChartRelease
+	{
+		c := ChartReleaseConfig{
+			K8sClient: config.K8sClient,
+			Logger:    config.Logger,
+		}
+
+		chartReleaseCollector, err = NewChartRelease(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var orphanConfigMapCollector *OrphanConfigMap
 	{
 		c := OrphanConfigMapConfig{
@@ -61,12 +98,42 @@ func NewSet(config SetConfig) (*Set, error) {
 		}
 	}
 
+	var orphanReleaseCollector *OrphanRelease
+	{
+		c := OrphanReleaseConfig{
+			K8sClient: config.K8sClient,
+			Logger:    config.Logger,
+		}
+
+		orphanReleaseCollector, err = NewOrphanRelease(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var releaseMetricsCollector *ReleaseMetrics
+	{
+		c := ReleaseMetricsConfig{
+			Logger:         config.Logger,
+			ReleaseMetrics: config.ReleaseMetrics,
+		}
+
+		releaseMetricsCollector, err = NewReleaseMetrics(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var collectorSet *collector.Set
 	{
 		c := collector.SetConfig{
 			Collectors: []collector.Interface{
+				chartCacheCollector,
+				chartReleaseCollector,
 				orphanConfigMapCollector,
+				orphanReleaseCollector,
 				orphanSecretCollector,
+				releaseMetricsCollector,
 			},
 			Logger: config.Logger,
 		}
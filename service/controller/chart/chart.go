@@ -11,30 +11,45 @@ import (
 	"github.com/giantswarm/operatorkit/v7/pkg/controller"
 	"github.com/giantswarm/operatorkit/v7/pkg/resource"
 	"github.com/spf13/afero"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
 	"github.com/giantswarm/chart-operator/v4/pkg/project"
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
 
+	"github.com/giantswarm/chart-operator/v4/service/internal/chartcache"
+	"github.com/giantswarm/chart-operator/v4/service/internal/chartverifier"
 	"github.com/giantswarm/chart-operator/v4/service/internal/clientpair"
+	"github.com/giantswarm/chart-operator/v4/service/internal/imagerewrite"
+	"github.com/giantswarm/chart-operator/v4/service/internal/maintenancewindow"
+	"github.com/giantswarm/chart-operator/v4/service/internal/releasemetrics"
+	"github.com/giantswarm/chart-operator/v4/service/internal/retrybackoff"
 )
 
 const chartControllerSuffix = "-chart"
 
 type Config struct {
-	Fs          afero.Fs
-	HelmClients *clientpair.ClientPair
-	K8sClient   k8sclient.Interface
-	Logger      micrologger.Logger
+	ChartCache     *chartcache.Cache
+	ChartVerifier  *chartverifier.Verifier
+	Event          record.EventRecorder
+	Fs             afero.Fs
+	HelmClients    *clientpair.ClientPair
+	K8sClient      k8sclient.Interface
+	Logger         micrologger.Logger
+	ReleaseMetrics *releasemetrics.Metrics
 
 	ResyncPeriod time.Duration
 
-	HTTPClientTimeout time.Duration
-	K8sWaitTimeout    time.Duration
-	K8sWatchNamespace string
-	MaxRollback       int
-	TillerNamespace   string
+	HTTPClientTimeout     time.Duration
+	ImageRewriter         *imagerewrite.Rewriter
+	K8sWaitTimeout        time.Duration
+	K8sWatchNamespace     string
+	MaintenanceWindow     maintenancewindow.Window
+	MaxRollback           int
+	MigrateHelmV2Releases bool
+	RetryPolicy           retrybackoff.Policy
+	TillerNamespace       string
 }
 
 type Chart struct {
@@ -44,6 +59,15 @@ type Chart struct {
 func NewChart(config Config) (*Chart, error) {
 	var err error
 
+	if config.ChartCache == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ChartCache must not be empty", config)
+	}
+	if config.ChartVerifier == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ChartVerifier must not be empty", config)
+	}
+	if config.Event == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Event must not be empty", config)
+	}
 	if config.HelmClients == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.HelmClients must not be empty", config)
 	}
@@ -56,6 +80,9 @@ func NewChart(config Config) (*Chart, error) {
 	if config.Logger == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
 	}
+	if config.ReleaseMetrics == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ReleaseMetrics must not be empty", config)
+	}
 
 	if config.TillerNamespace == "" {
 		return nil, microerror.Maskf(invalidConfigError, "%T.TillerNamespace must not be empty", config)
@@ -75,16 +102,24 @@ func NewChart(config Config) (*Chart, error) {
 	var resources []resource.Interface
 	{
 		c := chartResourcesConfig{
-			Fs:          config.Fs,
-			CtrlClient:  config.K8sClient.CtrlClient(),
-			HelmClients: config.HelmClients,
-			K8sClient:   config.K8sClient.K8sClient(),
-			Logger:      config.Logger,
-
-			HTTPClientTimeout: config.HTTPClientTimeout,
-			K8sWaitTimeout:    config.K8sWaitTimeout,
-			MaxRollback:       config.MaxRollback,
-			TillerNamespace:   config.TillerNamespace,
+			ChartCache:     config.ChartCache,
+			ChartVerifier:  config.ChartVerifier,
+			Event:          config.Event,
+			Fs:             config.Fs,
+			CtrlClient:     config.K8sClient.CtrlClient(),
+			HelmClients:    config.HelmClients,
+			K8sClient:      config.K8sClient.K8sClient(),
+			Logger:         config.Logger,
+			ReleaseMetrics: config.ReleaseMetrics,
+
+			HTTPClientTimeout:     config.HTTPClientTimeout,
+			ImageRewriter:         config.ImageRewriter,
+			K8sWaitTimeout:        config.K8sWaitTimeout,
+			MaintenanceWindow:     config.MaintenanceWindow,
+			MaxRollback:           config.MaxRollback,
+			MigrateHelmV2Releases: config.MigrateHelmV2Releases,
+			RetryPolicy:           config.RetryPolicy,
+			TillerNamespace:       config.TillerNamespace,
 		}
 
 		resources, err = newChartResources(c)
@@ -2,7 +2,9 @@ package controllercontext
 
 import (
 	"context"
+	"time"
 
+	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
 	"github.com/giantswarm/microerror"
 )
 
@@ -11,7 +13,10 @@ type contextKey string
 const controllerKey contextKey = "controller"
 
 type Context struct {
-	Status Status
+	// ReleaseHistory is the history of the Helm release fetched by the
+	// releasemaxhistory resource, sorted by descending revision.
+	ReleaseHistory []helmclient.ReleaseHistory
+	Status         Status
 }
 
 type Status struct {
@@ -20,8 +25,13 @@ type Status struct {
 }
 
 type Release struct {
-	FailedMaxAttempts bool
-	Status            string
+	// FailedAttempts is the number of failed installs and upgrades in a row
+	// when the release resource is backing off.
+	FailedAttempts int
+	// NextRetry is the time of the next retry when the release resource is
+	// backing off.
+	NextRetry time.Time
+	Status    string
 }
 
 func NewContext(ctx context.Context, c Context) context.Context {
@@ -11,6 +11,15 @@ func IsEmptyValueError(err error) bool {
 	return microerror.Cause(err) == emptyValueError
 }
 
+var invalidCordonUntilError = &microerror.Error{
+	Kind: "invalidCordonUntilError",
+}
+
+// IsInvalidCordonUntil asserts invalidCordonUntilError.
+func IsInvalidCordonUntil(err error) bool {
+	return microerror.Cause(err) == invalidCordonUntilError
+}
+
 var wrongTypeError = &microerror.Error{
 	Kind: "wrongTypeError",
 }
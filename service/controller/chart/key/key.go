@@ -2,6 +2,8 @@ package key
 
 import (
 	"strconv"
+	"strings"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -21,6 +23,13 @@ func AppNamespace(customResource v1alpha1.Chart) string {
 	return customResource.GetAnnotations()[annotation.AppNamespace]
 }
 
+// AtomicRollbackCount returns the number of rollbacks after failed upgrades
+// of the atomic release of the chart CR. It returns 0 when the annotation is
+// missing or invalid.
+func AtomicRollbackCount(customResource v1alpha1.Chart) int {
+	return annotationCount(customResource, chartmeta.AtomicRollbackCount)
+}
+
 func ChartStatus(customResource v1alpha1.Chart) v1alpha1.ChartStatus {
 	return customResource.Status
 }
@@ -29,6 +38,11 @@ func SkipCRDs(customResource v1alpha1.Chart) bool {
 	return customResource.Spec.Install.SkipCRDs
 }
 
+// ChartDigest returns the expected digest of the chart tarball.
+func ChartDigest(customResource v1alpha1.Chart) string {
+	return customResource.Annotations[chartmeta.ChartDigest]
+}
+
 func ConfigMapName(customResource v1alpha1.Chart) string {
 	return customResource.Spec.Config.ConfigMap.Name
 }
@@ -45,42 +59,134 @@ func CordonUntil(customResource v1alpha1.Chart) string {
 	return customResource.GetAnnotations()[chartmeta.CordonUntilDate]
 }
 
+// CordonUntilDate parses the cordon expiration date which must be in RFC3339
+// format.
+func CordonUntilDate(customResource v1alpha1.Chart) (time.Time, error) {
+	until, err := time.Parse(time.RFC3339, CordonUntil(customResource))
+	if err != nil {
+		return time.Time{}, microerror.Maskf(invalidCordonUntilError, "%#q is not a RFC3339 date", CordonUntil(customResource))
+	}
+
+	return until, nil
+}
+
+// FailedAttempts returns the number of failed installs and upgrades in a
+// row of the chart CR. It returns 0 when the annotation is missing or
+// invalid.
+func FailedAttempts(customResource v1alpha1.Chart) int {
+	return annotationCount(customResource, chartmeta.FailedAttempts)
+}
+
+// CRDPolicyAnnotation returns the CRD policy of the chart CR.
+func CRDPolicyAnnotation(customResource v1alpha1.Chart) string {
+	return customResource.Annotations[chartmeta.CRDPolicy]
+}
+
+// DependsOnAnnotation returns the dependencies of the chart CR as JSON.
+func DependsOnAnnotation(customResource v1alpha1.Chart) string {
+	return customResource.Annotations[chartmeta.DependsOn]
+}
+
+// DryRunChecksumAnnotation returns the checksum of the last dry-run diff of
+// the chart CR.
+func DryRunChecksumAnnotation(customResource v1alpha1.Chart) string {
+	return customResource.Annotations[chartmeta.DryRunChecksum]
+}
+
+func HasAtomicUpgradeAnnotation(customResource v1alpha1.Chart) bool {
+	return isAnnotationTrue(customResource, chartmeta.AtomicUpgrade)
+}
+
+func HasDriftDetectionAnnotation(customResource v1alpha1.Chart) bool {
+	return isAnnotationTrue(customResource, chartmeta.DriftDetection)
+}
+
+func HasDryRunAnnotation(customResource v1alpha1.Chart) bool {
+	return isAnnotationTrue(customResource, chartmeta.DryRun)
+}
+
 func HasForceUpgradeAnnotation(customResource v1alpha1.Chart) bool {
-	val, ok := customResource.Annotations[chartmeta.ForceHelmUpgrade]
-	if !ok {
+	return isAnnotationTrue(customResource, chartmeta.ForceHelmUpgrade)
+}
+
+func HasHealthCheckAnnotation(customResource v1alpha1.Chart) bool {
+	return isAnnotationTrue(customResource, chartmeta.HealthCheck)
+}
+
+func HasIgnoreMaintenanceWindowAnnotation(customResource v1alpha1.Chart) bool {
+	return isAnnotationTrue(customResource, chartmeta.IgnoreMaintenanceWindow)
+}
+
+func HasSelfHealAnnotation(customResource v1alpha1.Chart) bool {
+	return isAnnotationTrue(customResource, chartmeta.SelfHeal)
+}
+
+func HasSkipImageRewriteAnnotation(customResource v1alpha1.Chart) bool {
+	return isAnnotationTrue(customResource, chartmeta.SkipImageRewrite)
+}
+
+func InstallTimeout(customResource v1alpha1.Chart) *metav1.Duration {
+	return customResource.Spec.Install.Timeout
+}
+
+// IsCordonExpired returns true when the chart CR has cordon annotations but
+// the cordon-until date has already passed. Invalid dates never expire.
+func IsCordonExpired(customResource v1alpha1.Chart) bool {
+	if !hasCordonAnnotations(customResource) {
 		return false
 	}
 
-	result, err := strconv.ParseBool(val)
+	until, err := CordonUntilDate(customResource)
 	if err != nil {
-		// If we cannot parse the boolean we return false and this is shown
-		// in the logs.
 		return false
 	}
 
-	return result
+	return time.Now().After(until)
 }
 
-func InstallTimeout(customResource v1alpha1.Chart) *metav1.Duration {
-	return customResource.Spec.Install.Timeout
+// IsCordonInvalid returns true when the chart CR has cordon annotations but
+// the cordon-until date cannot be parsed.
+func IsCordonInvalid(customResource v1alpha1.Chart) bool {
+	if !hasCordonAnnotations(customResource) {
+		return false
+	}
+
+	_, err := CordonUntilDate(customResource)
+	return err != nil
 }
 
+// IsCordoned returns true when the chart CR has cordon annotations with a
+// valid cordon-until date which has not passed yet. A cordon with an invalid
+// date is not active so a typo does not freeze the chart forever.
 func IsCordoned(customResource v1alpha1.Chart) bool {
-	_, reasonOk := customResource.Annotations[chartmeta.CordonReason]
-	_, untilOk := customResource.Annotations[chartmeta.CordonUntilDate]
+	if !hasCordonAnnotations(customResource) {
+		return false
+	}
 
-	if reasonOk && untilOk {
-		return true
-	} else {
+	until, err := CordonUntilDate(customResource)
+	if err != nil {
 		return false
 	}
 
+	return !time.Now().After(until)
 }
 
 func IsDeleted(customResource v1alpha1.Chart) bool {
 	return customResource.GetDeletionTimestamp() != nil
 }
 
+// MaintenanceWindowAnnotation returns the maintenance window schedule of the
+// chart CR.
+func MaintenanceWindowAnnotation(customResource v1alpha1.Chart) string {
+	return customResource.Annotations[chartmeta.MaintenanceWindow]
+}
+
+// MaintenanceWindowDurationAnnotation returns the maintenance window duration
+// of the chart CR.
+func MaintenanceWindowDurationAnnotation(customResource v1alpha1.Chart) string {
+	return customResource.Annotations[chartmeta.MaintenanceWindowDuration]
+}
+
 func Namespace(customResource v1alpha1.Chart) string {
 	return customResource.Spec.Namespace
 }
@@ -93,10 +199,58 @@ func NamespaceLabels(customResource v1alpha1.Chart) map[string]string {
 	return customResource.Spec.NamespaceConfig.Labels
 }
 
+// NextRetry returns the time before which a failed install or upgrade of the
+// chart CR is not retried. It returns the zero time when the annotation is
+// missing or invalid.
+func NextRetry(customResource v1alpha1.Chart) time.Time {
+	next, err := time.Parse(time.RFC3339, customResource.Annotations[chartmeta.NextRetry])
+	if err != nil {
+		return time.Time{}
+	}
+
+	return next
+}
+
+// PostRenderPatchesAnnotation returns the reference of the config map of
+// post-render patches of the chart CR.
+func PostRenderPatchesAnnotation(customResource v1alpha1.Chart) string {
+	return customResource.Annotations[chartmeta.PostRenderPatches]
+}
+
+// PullSecretAnnotation returns the reference of the docker config secret used
+// to pull the chart of the chart CR.
+func PullSecretAnnotation(customResource v1alpha1.Chart) string {
+	return customResource.Annotations[chartmeta.PullSecret]
+}
+
 func ReleaseName(customResource v1alpha1.Chart) string {
 	return customResource.Spec.Name
 }
 
+// RetryBaseDelayAnnotation returns the retry base delay override of the
+// chart CR.
+func RetryBaseDelayAnnotation(customResource v1alpha1.Chart) string {
+	return customResource.Annotations[chartmeta.RetryBaseDelay]
+}
+
+// RetryJitterAnnotation returns the retry jitter override of the chart CR.
+func RetryJitterAnnotation(customResource v1alpha1.Chart) string {
+	return customResource.Annotations[chartmeta.RetryJitter]
+}
+
+// RetryMaxDelayAnnotation returns the retry max delay override of the chart
+// CR.
+func RetryMaxDelayAnnotation(customResource v1alpha1.Chart) string {
+	return customResource.Annotations[chartmeta.RetryMaxDelay]
+}
+
+// RollbackCount returns the number of rollbacks of the release of the chart
+// CR from the pending status. It returns 0 when the annotation is missing or
+// invalid.
+func RollbackCount(customResource v1alpha1.Chart) int {
+	return annotationCount(customResource, chartmeta.RollbackCount)
+}
+
 func RollbackTimeout(customResource v1alpha1.Chart) *metav1.Duration {
 	return customResource.Spec.Rollback.Timeout
 }
@@ -109,10 +263,23 @@ func SecretNamespace(customResource v1alpha1.Chart) string {
 	return customResource.Spec.Config.Secret.Namespace
 }
 
+// SelfHealAttempts returns the number of times in a row the release of the
+// chart CR was re-applied to restore drifted objects. It returns 0 when the
+// annotation is missing or invalid.
+func SelfHealAttempts(customResource v1alpha1.Chart) int {
+	return annotationCount(customResource, chartmeta.SelfHealAttempts)
+}
+
 func TarballURL(customResource v1alpha1.Chart) string {
 	return customResource.Spec.TarballURL
 }
 
+// TarballURLIsOCI returns true when the tarball URL of the chart CR is an
+// oci:// chart reference.
+func TarballURLIsOCI(customResource v1alpha1.Chart) bool {
+	return strings.HasPrefix(TarballURL(customResource), "oci://")
+}
+
 // ToCustomResource converts value to v1alpha1.Chart and returns it or error
 // if type does not match.
 func ToCustomResource(v interface{}) (v1alpha1.Chart, error) {
@@ -136,6 +303,18 @@ func UpgradeTimeout(customResource v1alpha1.Chart) *metav1.Duration {
 	return customResource.Spec.Upgrade.Timeout
 }
 
+// ValuesChecksumAnnotation returns the annotation value to determine if the
+// Helm release values have changed.
+func ValuesChecksumAnnotation(customResource v1alpha1.Chart) string {
+	return customResource.Annotations[chartmeta.ValuesChecksum]
+}
+
+// ValuesChecksumAlgorithmAnnotation returns the algorithm of the values
+// checksum annotation.
+func ValuesChecksumAlgorithmAnnotation(customResource v1alpha1.Chart) string {
+	return customResource.Annotations[chartmeta.ValuesChecksumAlgorithm]
+}
+
 // ValuesMD5ChecksumAnnotation returns the annotation value to determine if the
 // Helm release values have changed.
 func ValuesMD5ChecksumAnnotation(customResource v1alpha1.Chart) string {
@@ -146,6 +325,18 @@ func ValuesMD5ChecksumAnnotation(customResource v1alpha1.Chart) string {
 	}
 }
 
+// ValuesSourcesAnnotation returns the JSON list of additional values sources
+// of the chart CR.
+func ValuesSourcesAnnotation(customResource v1alpha1.Chart) string {
+	return customResource.Annotations[chartmeta.ValuesSources]
+}
+
+// VerifiedDigestAnnotation returns the digest of the chart tarball verified
+// against its provenance file.
+func VerifiedDigestAnnotation(customResource v1alpha1.Chart) string {
+	return customResource.Annotations[chartmeta.VerifiedDigest]
+}
+
 func Version(customResource v1alpha1.Chart) string {
 	return customResource.Spec.Version
 }
@@ -159,3 +350,35 @@ func VersionLabel(customResource v1alpha1.Chart) string {
 		return ""
 	}
 }
+
+func annotationCount(customResource v1alpha1.Chart, name string) int {
+	count, err := strconv.Atoi(customResource.Annotations[name])
+	if err != nil || count < 0 {
+		return 0
+	}
+
+	return count
+}
+
+func hasCordonAnnotations(customResource v1alpha1.Chart) bool {
+	_, reasonOk := customResource.Annotations[chartmeta.CordonReason]
+	_, untilOk := customResource.Annotations[chartmeta.CordonUntilDate]
+
+	return reasonOk && untilOk
+}
+
+func isAnnotationTrue(customResource v1alpha1.Chart, name string) bool {
+	val, ok := customResource.Annotations[name]
+	if !ok {
+		return false
+	}
+
+	result, err := strconv.ParseBool(val)
+	if err != nil {
+		// If we cannot parse the boolean we return false and this is shown
+		// in the logs.
+		return false
+	}
+
+	return result
+}
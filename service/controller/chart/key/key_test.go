@@ -98,6 +98,198 @@ func Test_CordonUntil(t *testing.T) {
 	}
 }
 
+func Test_CordonUntilDate(t *testing.T) {
+	testCases := []struct {
+		name         string
+		chart        v1alpha1.Chart
+		expectedDate time.Time
+		errorMatcher func(error) bool
+	}{
+		{
+			name: "case 0: valid date",
+			chart: v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						chartmeta.CordonUntilDate: "2019-12-31T23:59:59Z",
+					},
+				},
+			},
+			expectedDate: time.Date(2019, 12, 31, 23, 59, 59, 0, time.UTC),
+		},
+		{
+			name: "case 1: invalid date",
+			chart: v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						chartmeta.CordonUntilDate: "2019-12-31",
+					},
+				},
+			},
+			errorMatcher: IsInvalidCordonUntil,
+		},
+		{
+			name:         "case 2: missing date",
+			chart:        v1alpha1.Chart{},
+			errorMatcher: IsInvalidCordonUntil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := CordonUntilDate(tc.chart)
+			switch {
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case err != nil && !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if !result.Equal(tc.expectedDate) {
+				t.Fatalf("cordon until date %s, want %s", result, tc.expectedDate)
+			}
+		})
+	}
+}
+
+func Test_FailedAttempts(t *testing.T) {
+	testCases := []struct {
+		name             string
+		chart            v1alpha1.Chart
+		expectedAttempts int
+	}{
+		{
+			name: "case 0: failed attempts",
+			chart: v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						chartmeta.FailedAttempts: "3",
+					},
+				},
+			},
+			expectedAttempts: 3,
+		},
+		{
+			name: "case 1: invalid failed attempts",
+			chart: v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						chartmeta.FailedAttempts: "three",
+					},
+				},
+			},
+			expectedAttempts: 0,
+		},
+		{
+			name:             "case 2: missing failed attempts",
+			chart:            v1alpha1.Chart{},
+			expectedAttempts: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := FailedAttempts(tc.chart)
+			if result != tc.expectedAttempts {
+				t.Fatalf("FailedAttempts == %d, want %d", result, tc.expectedAttempts)
+			}
+		})
+	}
+}
+
+func Test_HasDryRunAnnotation(t *testing.T) {
+	testCases := []struct {
+		name           string
+		input          v1alpha1.Chart
+		expectedResult bool
+	}{
+		{
+			name:           "case 0: no annotations",
+			input:          v1alpha1.Chart{},
+			expectedResult: false,
+		},
+		{
+			name: "case 1: annotation present",
+			input: v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						chartmeta.DryRun: "true",
+					},
+				},
+			},
+			expectedResult: true,
+		},
+		{
+			name: "case 2: annotation present but invalid value",
+			input: v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						chartmeta.DryRun: "yes please",
+					},
+				},
+			},
+			expectedResult: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := HasDryRunAnnotation(tc.input)
+
+			if result != tc.expectedResult {
+				t.Fatalf("HasDryRunAnnotation == %t, want %t", result, tc.expectedResult)
+			}
+		})
+	}
+}
+
+func Test_HasHealthCheckAnnotation(t *testing.T) {
+	testCases := []struct {
+		name           string
+		input          v1alpha1.Chart
+		expectedResult bool
+	}{
+		{
+			name:           "case 0: no annotations",
+			input:          v1alpha1.Chart{},
+			expectedResult: false,
+		},
+		{
+			name: "case 1: annotation present",
+			input: v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						chartmeta.HealthCheck: "true",
+					},
+				},
+			},
+			expectedResult: true,
+		},
+		{
+			name: "case 2: annotation set to false",
+			input: v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						chartmeta.HealthCheck: "false",
+					},
+				},
+			},
+			expectedResult: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := HasHealthCheckAnnotation(tc.input)
+
+			if result != tc.expectedResult {
+				t.Fatalf("HasHealthCheckAnnotation == %t, want %t", result, tc.expectedResult)
+			}
+		})
+	}
+}
+
 func Test_HasForceUpgradeAnnotation(t *testing.T) {
 	testCases := []struct {
 		name           string
@@ -170,12 +362,32 @@ func Test_HasForceUpgradeAnnotation(t *testing.T) {
 
 func Test_IsCordoned(t *testing.T) {
 	tests := []struct {
-		name           string
-		chart          v1alpha1.Chart
-		expectedResult bool
+		name            string
+		chart           v1alpha1.Chart
+		expectedResult  bool
+		expectedExpired bool
 	}{
 		{
 			name: "case 0: chart cordoned",
+			chart: v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						chartmeta.CordonReason:    "testing manual upgrade",
+						chartmeta.CordonUntilDate: "2999-12-31T23:59:59Z",
+					},
+				},
+			},
+			expectedResult:  true,
+			expectedExpired: false,
+		},
+		{
+			name:            "case 1: chart did not cordon",
+			chart:           v1alpha1.Chart{},
+			expectedResult:  false,
+			expectedExpired: false,
+		},
+		{
+			name: "case 2: chart cordon expired",
 			chart: v1alpha1.Chart{
 				ObjectMeta: metav1.ObjectMeta{
 					Annotations: map[string]string{
@@ -184,12 +396,33 @@ func Test_IsCordoned(t *testing.T) {
 					},
 				},
 			},
-			expectedResult: true,
+			expectedResult:  false,
+			expectedExpired: true,
 		},
 		{
-			name:           "case 1: chart did not cordon",
-			chart:          v1alpha1.Chart{},
-			expectedResult: false,
+			name: "case 3: chart cordoned with invalid date",
+			chart: v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						chartmeta.CordonReason:    "testing manual upgrade",
+						chartmeta.CordonUntilDate: "tomorrow",
+					},
+				},
+			},
+			expectedResult:  false,
+			expectedExpired: false,
+		},
+		{
+			name: "case 4: chart with cordon date but without reason",
+			chart: v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						chartmeta.CordonUntilDate: "2019-12-31T23:59:59Z",
+					},
+				},
+			},
+			expectedResult:  false,
+			expectedExpired: false,
 		},
 	}
 	for _, tt := range tests {
@@ -197,6 +430,48 @@ func Test_IsCordoned(t *testing.T) {
 			if got := IsCordoned(tt.chart); got != tt.expectedResult {
 				t.Errorf("IsCordoned() = %v, want %v", got, tt.expectedResult)
 			}
+			if got := IsCordonExpired(tt.chart); got != tt.expectedExpired {
+				t.Errorf("IsCordonExpired() = %v, want %v", got, tt.expectedExpired)
+			}
+		})
+	}
+}
+
+func Test_NextRetry(t *testing.T) {
+	testCases := []struct {
+		name         string
+		chart        v1alpha1.Chart
+		expectedTime time.Time
+	}{
+		{
+			name: "case 0: valid time",
+			chart: v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						chartmeta.NextRetry: "2019-12-31T23:59:59Z",
+					},
+				},
+			},
+			expectedTime: time.Date(2019, 12, 31, 23, 59, 59, 0, time.UTC),
+		},
+		{
+			name: "case 1: invalid time",
+			chart: v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						chartmeta.NextRetry: "2019-12-31",
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := NextRetry(tc.chart)
+			if !result.Equal(tc.expectedTime) {
+				t.Fatalf("next retry %s, want %s", result, tc.expectedTime)
+			}
 		})
 	}
 }
@@ -215,6 +490,58 @@ func Test_ReleaseName(t *testing.T) {
 	}
 }
 
+func Test_RollbackCount(t *testing.T) {
+	testCases := []struct {
+		name                        string
+		chart                       v1alpha1.Chart
+		expectedRollbackCount       int
+		expectedAtomicRollbackCount int
+	}{
+		{
+			name: "case 0: rollback counts",
+			chart: v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						chartmeta.AtomicRollbackCount: "1",
+						chartmeta.RollbackCount:       "2",
+					},
+				},
+			},
+			expectedRollbackCount:       2,
+			expectedAtomicRollbackCount: 1,
+		},
+		{
+			name: "case 1: invalid rollback counts",
+			chart: v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						chartmeta.AtomicRollbackCount: "-1",
+						chartmeta.RollbackCount:       "two",
+					},
+				},
+			},
+		},
+		{
+			name:  "case 2: missing rollback counts",
+			chart: v1alpha1.Chart{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := RollbackCount(tc.chart)
+			if result != tc.expectedRollbackCount {
+				t.Fatalf("RollbackCount == %d, want %d", result, tc.expectedRollbackCount)
+			}
+
+			result = AtomicRollbackCount(tc.chart)
+			if result != tc.expectedAtomicRollbackCount {
+				t.Fatalf("AtomicRollbackCount == %d, want %d", result, tc.expectedAtomicRollbackCount)
+			}
+		})
+	}
+}
+
 func Test_SecretName(t *testing.T) {
 	expectedSecretName := "prometheus-secret-values"
 
@@ -267,6 +594,46 @@ func Test_TarballURL(t *testing.T) {
 	}
 }
 
+func Test_TarballURLIsOCI(t *testing.T) {
+	testCases := []struct {
+		name           string
+		tarballURL     string
+		expectedResult bool
+	}{
+		{
+			name:           "case 0: https tarball url",
+			tarballURL:     "https://path.to/chart-1.0.0.tgz",
+			expectedResult: false,
+		},
+		{
+			name:           "case 1: oci reference with tag",
+			tarballURL:     "oci://registry.example.com/charts/chart:1.0.0",
+			expectedResult: true,
+		},
+		{
+			name:           "case 2: oci reference with digest",
+			tarballURL:     "oci://registry.example.com/charts/chart@sha256:4b1e",
+			expectedResult: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			obj := v1alpha1.Chart{
+				Spec: v1alpha1.ChartSpec{
+					TarballURL: tc.tarballURL,
+				},
+			}
+
+			result := TarballURLIsOCI(obj)
+
+			if result != tc.expectedResult {
+				t.Fatalf("TarballURLIsOCI == %t, want %t", result, tc.expectedResult)
+			}
+		})
+	}
+}
+
 func Test_Timeouts(t *testing.T) {
 	type expectedTimeouts struct {
 		Install   *metav1.Duration
@@ -442,6 +809,27 @@ func Test_ToCustomResource(t *testing.T) {
 	}
 }
 
+func Test_ValuesChecksumAnnotation(t *testing.T) {
+	expectedChecksum := "3e80b3778b3b03766e7be993131c0af2ad05630c5d96fb7fa132d05b77336e04"
+	expectedAlgorithm := "sha256"
+
+	obj := v1alpha1.Chart{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				chartmeta.ValuesChecksum:          "3e80b3778b3b03766e7be993131c0af2ad05630c5d96fb7fa132d05b77336e04",
+				chartmeta.ValuesChecksumAlgorithm: "sha256",
+			},
+		},
+	}
+
+	if ValuesChecksumAnnotation(obj) != expectedChecksum {
+		t.Fatalf("values checksum %#q, want %#q", ValuesChecksumAnnotation(obj), expectedChecksum)
+	}
+	if ValuesChecksumAlgorithmAnnotation(obj) != expectedAlgorithm {
+		t.Fatalf("values checksum algorithm %#q, want %#q", ValuesChecksumAlgorithmAnnotation(obj), expectedAlgorithm)
+	}
+}
+
 func Test_ValuesMD5ChecksumAnnotation(t *testing.T) {
 	expectedMD5Checksum := "1ee001c5286ca00fdf64d9660c04bde2"
 
@@ -0,0 +1,115 @@
+// Package releasestatus defines the statuses chart-operator sets in the chart
+// CR status in addition to the statuses of Helm releases. They are set by the
+// release resource and evaluated by the status resource.
+package releasestatus
+
+const (
+	// AlreadyExists is set in the CR status when it failed to create
+	// a manifest object because it exists already.
+	AlreadyExists = "already-exists"
+
+	// ChartPullFailed is set in the CR status when it failed to pull
+	// chart tarball for various reasons: network issues, tarball does not
+	// exists, connection timeout etc.
+	ChartPullFailed = "chart-pull-failed"
+
+	// ChartVerificationFailed is set in the CR status when the pulled
+	// chart tarball does not match the expected digest or cannot be verified
+	// against its provenance file.
+	ChartVerificationFailed = "chart-verification-failed"
+
+	// Cordoned is set in the CR status when the chart CR is cordoned.
+	Cordoned = "CORDONED"
+
+	// CRDPolicyFailed is set in the CR status when the CRD policy of
+	// the chart CR is invalid or applying CRDs according to it was refused.
+	CRDPolicyFailed = "crd-policy-failed"
+
+	// DependencyCycle is set in the CR status when the chart CR
+	// depends on itself through the depends-on annotations.
+	DependencyCycle = "dependency-cycle"
+
+	// DeployedUnhealthy is set in the CR status for deployed releases with
+	// workloads that are not ready when the health check is enabled.
+	DeployedUnhealthy = "deployed-unhealthy"
+
+	// Drifted is set in the CR status when objects of the deployed
+	// release were modified or deleted and the chart CR has drift detection
+	// enabled.
+	Drifted = "drifted"
+
+	// DryRun is set in the CR status when the chart CR has dry-run
+	// enabled and the pending upgrade was diffed instead of applied.
+	DryRun = "dry-run"
+
+	// HelmV2MigrationFailed is set in the CR status when Tiller config
+	// maps of the release could not be migrated to Helm 3.
+	HelmV2MigrationFailed = "helm-v2-migration-failed"
+
+	// HelmV2Release is set in the CR status when Tiller config maps of
+	// the release exist and migrating Helm 2 releases is disabled.
+	HelmV2Release = "helm-v2-release"
+
+	// InvalidCordon is set in the CR status when the chart CR has cordon
+	// annotations but the cordon-until date cannot be parsed. The chart
+	// is reconciled as if it was not cordoned.
+	InvalidCordon = "invalid-cordon"
+
+	// InvalidDependencies is set in the CR status when the depends-on
+	// annotation cannot be parsed.
+	InvalidDependencies = "invalid-dependencies"
+
+	// InvalidMaintenanceWindow is set in the CR status when the
+	// maintenance window annotations of the chart CR cannot be parsed.
+	InvalidMaintenanceWindow = "invalid-maintenance-window"
+
+	// InvalidManifest is set in the CR status when it failed to create
+	// manifest objects with helm resources.
+	InvalidManifest = "invalid-manifest"
+
+	// InvalidValuesSources is set in the CR status when the
+	// values-sources annotation cannot be parsed.
+	InvalidValuesSources = "invalid-values-sources"
+
+	// NotInstalled is set in the CR status when there is no Helm
+	// Release to check.
+	NotInstalled = "not-installed"
+
+	// PostRenderFailed is set in the CR status when the post-render
+	// patches config map of the chart CR does not exist or its patches
+	// cannot be applied.
+	PostRenderFailed = "post-render-failed"
+
+	// RetryBackoff is set in the CR status when the last installs or
+	// upgrades failed and the next retry time has not been reached yet.
+	RetryBackoff = "retry-backoff"
+
+	// RolledBack is set in the CR status when a failed upgrade of a
+	// chart CR with the atomic-upgrade annotation was rolled back.
+	RolledBack = "rolled-back"
+
+	// UnknownError is set in the CR status when a release fails for unknown
+	// reasons.
+	UnknownError = "unknown-error"
+
+	// UpgradePending is set in the CR status when an upgrade is
+	// deferred until the maintenance window of the chart CR opens.
+	UpgradePending = "upgrade-pending"
+
+	// ValidationFailed is set in the CR status when it failed to pass
+	// OpenAPI validation on release manifest.
+	ValidationFailed = "validation-failed"
+
+	// ValuesSchemaViolation is set in the CR status when Chart has not passed
+	// values.yaml validation against schema.
+	ValuesSchemaViolation = "values-schema-violation"
+
+	// ValuesSourceNotFound is set in the CR status when a config map,
+	// secret or key referenced in the values-sources annotation does not
+	// exist.
+	ValuesSourceNotFound = "values-source-not-found"
+
+	// WaitingForDependencies is set in the CR status when the install
+	// or upgrade is held off until the chart CRs it depends on are deployed.
+	WaitingForDependencies = "waiting-for-dependencies"
+)
@@ -0,0 +1,106 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
+)
+
+// atomicRollback rolls back a failed upgrade of a chart CR with the
+// atomic-upgrade annotation to the last deployed revision of the release.
+// It returns true when the release was rolled back. Rollbacks are limited by
+// the max rollback setting so an upgrade that keeps failing is eventually
+// left in failed status.
+func (r *Resource) atomicRollback(ctx context.Context, cr v1alpha1.Chart, hc helmclient.Interface, failedVersion, failedReason string) (bool, error) {
+	cc, err := controllercontext.FromContext(ctx)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	releaseName := key.ReleaseName(cr)
+
+	rollbackCount := 0
+	if count, ok := cr.GetAnnotations()[annotation.AtomicRollbackCount]; ok {
+		rollbackCount, err = strconv.Atoi(count)
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+	}
+
+	if rollbackCount >= r.maxRollback {
+		r.logger.Debugf(ctx, "the %#q release has reached max %d rollbacks after failed upgrades", releaseName, r.maxRollback)
+		return false, nil
+	}
+
+	history, err := hc.GetReleaseHistory(ctx, key.Namespace(cr), releaseName)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	deployed, ok := lastDeployedRevision(history)
+	if !ok {
+		r.logger.Debugf(ctx, "release %#q has no deployed revision to roll back to", releaseName)
+		return false, nil
+	}
+
+	r.logger.Debugf(ctx, "rolling back release %#q to revision %d after failed upgrade", releaseName, deployed.Revision)
+
+	opts := helmclient.RollbackOptions{}
+	timeout := key.RollbackTimeout(cr)
+
+	if timeout != nil {
+		r.logger.Debugf(ctx, "using custom %#q timeout to rollback release %#q", (*timeout).Duration, releaseName)
+		opts.Timeout = (*timeout).Duration
+	}
+
+	start := time.Now()
+	err = hc.Rollback(ctx, key.Namespace(cr), releaseName, deployed.Revision, opts)
+	r.observeOperation(cr, rollbackOperation, start, err)
+	if err != nil {
+		r.event.Eventf(&cr, corev1.EventTypeWarning, rollbackFailedEventReason, "rollback of release %#q to revision %d failed: %s", releaseName, deployed.Revision, err.Error())
+		return false, microerror.Mask(err)
+	}
+
+	reason := fmt.Sprintf("upgrade to version %#q failed, rolled back to version %#q (revision %d): %s", failedVersion, deployed.Version, deployed.Revision, failedReason)
+	addStatusToContext(cc, reason, releasestatus.RolledBack)
+
+	r.logger.Debugf(ctx, "rolled back release %#q to revision %d", releaseName, deployed.Revision)
+	r.event.Event(&cr, corev1.EventTypeWarning, rollbackSucceededEventReason, reason)
+
+	err = r.addAnnotation(ctx, cr, annotation.AtomicRollbackCount, fmt.Sprintf("%d", rollbackCount+1))
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	return true, nil
+}
+
+// lastDeployedRevision returns the most recent revision of the release history
+// that was successfully deployed.
+func lastDeployedRevision(history []helmclient.ReleaseHistory) (helmclient.ReleaseHistory, bool) {
+	var last helmclient.ReleaseHistory
+	var found bool
+
+	for _, h := range history {
+		if h.Status != helmclient.StatusDeployed && h.Status != helmclient.StatusSuperseded {
+			continue
+		}
+		if !found || h.Revision > last.Revision {
+			last = h
+			found = true
+		}
+	}
+
+	return last, found
+}
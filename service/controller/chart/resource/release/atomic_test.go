@@ -0,0 +1,504 @@
+package release
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
+	"github.com/giantswarm/helmclient/v4/pkg/helmclienttest"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/giantswarm/operatorkit/v7/pkg/controller/context/resourcecanceledcontext"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake" //nolint:staticcheck
+
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
+	"github.com/giantswarm/chart-operator/v4/service/internal/chartcache"
+	"github.com/giantswarm/chart-operator/v4/service/internal/clientpair"
+	"github.com/giantswarm/chart-operator/v4/service/internal/operation"
+	"github.com/giantswarm/chart-operator/v4/service/internal/releasemetrics"
+)
+
+func Test_lastDeployedRevision(t *testing.T) {
+	testCases := []struct {
+		name             string
+		history          []helmclient.ReleaseHistory
+		expectedRevision helmclient.ReleaseHistory
+		expectedFound    bool
+	}{
+		{
+			name:          "case 0: empty history",
+			history:       []helmclient.ReleaseHistory{},
+			expectedFound: false,
+		},
+		{
+			name: "case 1: failed upgrade after deployed revision",
+			history: []helmclient.ReleaseHistory{
+				{Revision: 1, Status: helmclient.StatusSuperseded, Version: "1.0.0"},
+				{Revision: 2, Status: helmclient.StatusDeployed, Version: "1.1.0"},
+				{Revision: 3, Status: helmclient.StatusFailed, Version: "2.0.0"},
+			},
+			expectedRevision: helmclient.ReleaseHistory{Revision: 2, Status: helmclient.StatusDeployed, Version: "1.1.0"},
+			expectedFound:    true,
+		},
+		{
+			name: "case 2: deployed revision superseded by failed upgrade",
+			history: []helmclient.ReleaseHistory{
+				{Revision: 4, Status: helmclient.StatusFailed, Version: "2.0.0"},
+				{Revision: 3, Status: helmclient.StatusSuperseded, Version: "1.1.0"},
+				{Revision: 2, Status: helmclient.StatusSuperseded, Version: "1.0.0"},
+				{Revision: 1, Status: helmclient.StatusFailed, Version: "1.0.0"},
+			},
+			expectedRevision: helmclient.ReleaseHistory{Revision: 3, Status: helmclient.StatusSuperseded, Version: "1.1.0"},
+			expectedFound:    true,
+		},
+		{
+			name: "case 3: no deployed revision",
+			history: []helmclient.ReleaseHistory{
+				{Revision: 1, Status: helmclient.StatusFailed, Version: "1.0.0"},
+				{Revision: 2, Status: helmclient.StatusFailed, Version: "1.0.1"},
+			},
+			expectedFound: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, found := lastDeployedRevision(tc.history)
+
+			if found != tc.expectedFound {
+				t.Fatalf("found == %t, want %t", found, tc.expectedFound)
+			}
+			if !cmp.Equal(result, tc.expectedRevision) {
+				t.Fatalf("want matching revision \n %s", cmp.Diff(result, tc.expectedRevision))
+			}
+		})
+	}
+}
+
+// atomicHelmClient returns the configured results for upgrading and getting
+// releases and records the rollbacks and the revision releases are rolled
+// back to.
+type atomicHelmClient struct {
+	helmclient.Interface
+
+	history        []helmclient.ReleaseHistory
+	releaseContent *helmclient.ReleaseContent
+	updateErr      error
+
+	rollbacks    int
+	rolledBackTo int
+}
+
+func (c *atomicHelmClient) GetReleaseContent(ctx context.Context, namespace, releaseName string) (*helmclient.ReleaseContent, error) {
+	return c.releaseContent, nil
+}
+
+func (c *atomicHelmClient) GetReleaseHistory(ctx context.Context, namespace, releaseName string) ([]helmclient.ReleaseHistory, error) {
+	return c.history, nil
+}
+
+func (c *atomicHelmClient) Rollback(ctx context.Context, namespace, releaseName string, revision int, options helmclient.RollbackOptions) error {
+	c.rollbacks++
+	c.rolledBackTo = revision
+	return nil
+}
+
+func (c *atomicHelmClient) UpdateReleaseFromTarball(ctx context.Context, chartPath, namespace, releaseName string, values map[string]interface{}, options helmclient.UpdateOptions) error {
+	return c.updateErr
+}
+
+func newAtomicChart(rollbackCount string) *v1alpha1.Chart {
+	cr := &v1alpha1.Chart{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "prometheus",
+			Namespace: "giantswarm",
+			Annotations: map[string]string{
+				annotation.AtomicUpgrade: "true",
+			},
+		},
+		Spec: v1alpha1.ChartSpec{
+			Name:       "prometheus",
+			Namespace:  "monitoring",
+			TarballURL: "https://giantswarm.github.io/app-catalog/prometheus-1.1.0.tgz",
+			Version:    "1.1.0",
+		},
+	}
+	if rollbackCount != "" {
+		cr.Annotations[annotation.AtomicRollbackCount] = rollbackCount
+	}
+
+	return cr
+}
+
+// newAtomicResource returns a release resource which rolls back releases
+// at most twice.
+func newAtomicResource(t *testing.T, cr *v1alpha1.Chart, hc *atomicHelmClient) *Resource {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+
+	err := afero.WriteFile(fs, "/tmp/prometheus-1.1.0.tgz", []byte("chart tarball"), 0644)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	hc.Interface = helmclienttest.New(helmclienttest.Config{
+		PullChartTarballPath: "/tmp/prometheus-1.1.0.tgz",
+	})
+
+	helmClients, err := clientpair.NewClientPair(clientpair.ClientPairConfig{
+		Logger: microloggertest.New(),
+
+		PrvHelmClient: hc,
+		PubHelmClient: hc,
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	chartCache, err := chartcache.New(chartcache.Config{
+		Fs:     fs,
+		Logger: microloggertest.New(),
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	s := runtime.NewScheme()
+	err = v1alpha1.AddToScheme(s)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	r, err := New(Config{
+		ChartCache:     chartCache,
+		ChartVerifier:  newTestChartVerifier(t),
+		Event:          record.NewFakeRecorder(10),
+		Fs:             fs,
+		CtrlClient:     fake.NewClientBuilder().WithScheme(s).WithObjects(cr).Build(),
+		HelmClients:    helmClients,
+		K8sClient:      k8sfake.NewClientset(),
+		Logger:         microloggertest.New(),
+		Operations:     operation.New(),
+		ReleaseMetrics: releasemetrics.New(),
+
+		MaxRollback:     2,
+		TillerNamespace: "giantswarm",
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	return r
+}
+
+func Test_Resource_Release_atomicRollback(t *testing.T) {
+	history := []helmclient.ReleaseHistory{
+		{Revision: 1, Status: helmclient.StatusSuperseded, Version: "1.0.0"},
+		{Revision: 2, Status: helmclient.StatusDeployed, Version: "1.0.1"},
+		{Revision: 3, Status: helmclient.StatusFailed, Version: "1.1.0"},
+	}
+
+	testCases := []struct {
+		name                  string
+		rollbackCount         string
+		history               []helmclient.ReleaseHistory
+		expectedRolledBack    bool
+		expectedRevision      int
+		expectedRollbackCount string
+		expectedStatus        string
+	}{
+		{
+			name:                  "case 0: first failed upgrade is rolled back",
+			history:               history,
+			expectedRolledBack:    true,
+			expectedRevision:      2,
+			expectedRollbackCount: "1",
+			expectedStatus:        releasestatus.RolledBack,
+		},
+		{
+			name:                  "case 1: failed upgrade below max rollbacks is rolled back",
+			rollbackCount:         "1",
+			history:               history,
+			expectedRolledBack:    true,
+			expectedRevision:      2,
+			expectedRollbackCount: "2",
+			expectedStatus:        releasestatus.RolledBack,
+		},
+		{
+			name:                  "case 2: failed upgrade at max rollbacks is not rolled back",
+			rollbackCount:         "2",
+			history:               history,
+			expectedRollbackCount: "2",
+		},
+		{
+			name: "case 3: release without deployed revision is not rolled back",
+			history: []helmclient.ReleaseHistory{
+				{Revision: 1, Status: helmclient.StatusFailed, Version: "1.1.0"},
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			cr := newAtomicChart(tc.rollbackCount)
+			hc := &atomicHelmClient{
+				history: tc.history,
+			}
+			r := newAtomicResource(t, cr, hc)
+
+			cc := controllercontext.Context{}
+			ctx := controllercontext.NewContext(context.Background(), cc)
+
+			rolledBack, err := r.atomicRollback(ctx, *cr, hc, "1.1.0", "upgrade failed")
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if rolledBack != tc.expectedRolledBack {
+				t.Fatalf("rolled back == %t, want %t", rolledBack, tc.expectedRolledBack)
+			}
+			if hc.rolledBackTo != tc.expectedRevision {
+				t.Fatalf("revision == %d, want %d", hc.rolledBackTo, tc.expectedRevision)
+			}
+
+			assertAtomicRollback(ctx, t, r, tc.expectedRollbackCount, tc.expectedStatus)
+		})
+	}
+}
+
+func Test_Resource_Release_ApplyUpdateChange_atomicRollback(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		rollbackCount         string
+		updateErr             error
+		expectedRevision      int
+		expectedRollbackCount string
+		expectedStatus        string
+	}{
+		{
+			name:                  "case 0: failed upgrade is rolled back",
+			updateErr:             errors.New("timed out waiting for the condition"),
+			expectedRevision:      2,
+			expectedRollbackCount: "1",
+			expectedStatus:        releasestatus.RolledBack,
+		},
+		{
+			name:                  "case 1: failed upgrade at max rollbacks is left failed",
+			rollbackCount:         "2",
+			updateErr:             errors.New("timed out waiting for the condition"),
+			expectedRollbackCount: "2",
+			expectedStatus:        helmclient.StatusFailed,
+		},
+		{
+			name:          "case 2: successful upgrade resets rollback count",
+			rollbackCount: "1",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			cr := newAtomicChart(tc.rollbackCount)
+			hc := &atomicHelmClient{
+				history: []helmclient.ReleaseHistory{
+					{Revision: 2, Status: helmclient.StatusDeployed, Version: "1.0.1"},
+					{Revision: 3, Status: helmclient.StatusFailed, Version: "1.1.0"},
+				},
+				releaseContent: &helmclient.ReleaseContent{
+					Description: "Upgrade \"prometheus\" failed",
+					Name:        "prometheus",
+					Status:      helmclient.StatusFailed,
+				},
+				updateErr: tc.updateErr,
+			}
+			r := newAtomicResource(t, cr, hc)
+
+			cc := controllercontext.Context{}
+			ctx := controllercontext.NewContext(context.Background(), cc)
+			ctx = resourcecanceledcontext.NewContext(ctx, make(chan struct{}))
+
+			err := r.ApplyUpdateChange(ctx, cr, &ReleaseState{
+				Name:    "prometheus",
+				Version: "1.1.0",
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if hc.rolledBackTo != tc.expectedRevision {
+				t.Fatalf("revision == %d, want %d", hc.rolledBackTo, tc.expectedRevision)
+			}
+
+			assertAtomicRollback(ctx, t, r, tc.expectedRollbackCount, tc.expectedStatus)
+		})
+	}
+}
+
+func Test_Resource_Release_newUpdateChange_atomicRollback(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		rollbackCount         string
+		expectedUpdateChange  bool
+		expectedRevision      int
+		expectedRollbackCount string
+		expectedStatus        string
+	}{
+		{
+			name:                  "case 0: upgrade failed in the background is rolled back",
+			expectedRevision:      2,
+			expectedRollbackCount: "1",
+			expectedStatus:        releasestatus.RolledBack,
+		},
+		{
+			name:                  "case 1: upgrade failed in the background at max rollbacks is retried",
+			rollbackCount:         "2",
+			expectedUpdateChange:  true,
+			expectedRollbackCount: "2",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			cr := newAtomicChart(tc.rollbackCount)
+			hc := &atomicHelmClient{
+				history: []helmclient.ReleaseHistory{
+					{Revision: 2, Status: helmclient.StatusDeployed, Version: "1.0.1"},
+					{Revision: 3, Status: helmclient.StatusFailed, Version: "1.1.0"},
+				},
+			}
+			r := newAtomicResource(t, cr, hc)
+
+			cc := controllercontext.Context{}
+			ctx := controllercontext.NewContext(context.Background(), cc)
+
+			result, err := r.newUpdateChange(ctx, cr, &ReleaseState{
+				Name:    "prometheus",
+				Status:  helmclient.StatusFailed,
+				Version: "1.1.0",
+			}, &ReleaseState{
+				Name:           "prometheus",
+				Status:         helmclient.StatusDeployed,
+				ValuesChecksum: "checksum",
+				Version:        "1.1.0",
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			updateChange, _ := result.(*ReleaseState)
+			if (updateChange != nil) != tc.expectedUpdateChange {
+				t.Fatalf("update change == %#v, want %t", result, tc.expectedUpdateChange)
+			}
+			if hc.rolledBackTo != tc.expectedRevision {
+				t.Fatalf("revision == %d, want %d", hc.rolledBackTo, tc.expectedRevision)
+			}
+
+			assertAtomicRollback(ctx, t, r, tc.expectedRollbackCount, tc.expectedStatus)
+		})
+	}
+}
+
+func Test_Resource_Release_rollback_maxRollback(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		rollbackCount         string
+		expectedRollbacks     int
+		expectedRollbackCount string
+	}{
+		{
+			name:                  "case 0: pending release is rolled back",
+			expectedRollbacks:     1,
+			expectedRollbackCount: "1",
+		},
+		{
+			name:                  "case 1: pending release below max rollbacks is rolled back",
+			rollbackCount:         "1",
+			expectedRollbacks:     1,
+			expectedRollbackCount: "2",
+		},
+		{
+			name:                  "case 2: pending release at max rollbacks is not rolled back",
+			rollbackCount:         "2",
+			expectedRollbackCount: "2",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			// The pending release rollback uses the same max rollbacks as
+			// the rollback of failed upgrades.
+			cr := newAtomicChart("")
+			if tc.rollbackCount != "" {
+				cr.Annotations[annotation.RollbackCount] = tc.rollbackCount
+			}
+			hc := &atomicHelmClient{}
+			r := newAtomicResource(t, cr, hc)
+
+			cc := controllercontext.Context{}
+			ctx := controllercontext.NewContext(context.Background(), cc)
+
+			err := r.rollback(ctx, cr, helmclient.StatusPendingUpgrade)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if hc.rollbacks != tc.expectedRollbacks {
+				t.Fatalf("rollbacks == %d, want %d", hc.rollbacks, tc.expectedRollbacks)
+			}
+
+			var updated v1alpha1.Chart
+			err = r.ctrlClient.Get(ctx, types.NamespacedName{Name: "prometheus", Namespace: "giantswarm"}, &updated)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if updated.Annotations[annotation.RollbackCount] != tc.expectedRollbackCount {
+				t.Fatalf("rollback count == %#q, want %#q", updated.Annotations[annotation.RollbackCount], tc.expectedRollbackCount)
+			}
+		})
+	}
+}
+
+// assertAtomicRollback checks the rollback count annotation of the chart CR
+// and the release status added to the controller context.
+func assertAtomicRollback(ctx context.Context, t *testing.T, r *Resource, expectedRollbackCount, expectedStatus string) {
+	t.Helper()
+
+	var cr v1alpha1.Chart
+
+	err := r.ctrlClient.Get(ctx, types.NamespacedName{Name: "prometheus", Namespace: "giantswarm"}, &cr)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	if cr.Annotations[annotation.AtomicRollbackCount] != expectedRollbackCount {
+		t.Fatalf("rollback count == %#q, want %#q", cr.Annotations[annotation.AtomicRollbackCount], expectedRollbackCount)
+	}
+
+	cc, err := controllercontext.FromContext(ctx)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	if cc.Status.Release.Status != expectedStatus {
+		t.Fatalf("status == %#q, want %#q", cc.Status.Release.Status, expectedStatus)
+	}
+}
@@ -0,0 +1,96 @@
+package release
+
+import (
+	"crypto/md5" // #nosec
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/giantswarm/microerror"
+)
+
+// valuesChecksumAlgorithm is the algorithm of the values checksum. It is
+// recorded in the values-checksum-algorithm annotation of the chart CR.
+const valuesChecksumAlgorithm = "sha256"
+
+// valuesChecksum returns the SHA-256 checksum of the canonical JSON
+// serialization of the values. Map keys are sorted and numbers are
+// normalized at every nesting level so the checksum does not depend on map
+// ordering or on whether a number was decoded as an integer or a float.
+func valuesChecksum(values map[string]interface{}) (string, error) {
+	if len(values) == 0 {
+		return "", nil
+	}
+
+	b, err := json.Marshal(canonicalValue(values))
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(b)), nil
+}
+
+// legacyValuesMD5Checksum returns the checksum stored in the legacy
+// values-md5-checksum annotation. It is only used to migrate chart CRs to
+// the SHA-256 checksum without upgrading their releases.
+func legacyValuesMD5Checksum(values map[string]interface{}) (string, error) {
+	if len(values) == 0 {
+		return "", nil
+	}
+
+	// MD5 is only used for comparison but we need to turn off gosec or
+	// linting errors will occur.
+	h := md5.New() // #nosec
+	_, err := fmt.Fprintf(h, "%v", values)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// canonicalValue returns a copy of the value with all numbers normalized.
+// Floats without a fractional part are converted to integers so e.g. `2`
+// and `2.0` result in the same serialization.
+func canonicalValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, e := range val {
+			m[k] = canonicalValue(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(val))
+		for i, e := range val {
+			s[i] = canonicalValue(e)
+		}
+		return s
+	case float32:
+		return canonicalFloat(float64(val))
+	case float64:
+		return canonicalFloat(val)
+	case int:
+		return int64(val)
+	case int32:
+		return int64(val)
+	case uint32:
+		return int64(val)
+	case uint64:
+		if val <= math.MaxInt64 {
+			return int64(val)
+		}
+		return val
+	default:
+		return val
+	}
+}
+
+func canonicalFloat(f float64) interface{} {
+	if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+		return int64(f)
+	}
+
+	return f
+}
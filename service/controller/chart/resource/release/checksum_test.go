@@ -0,0 +1,106 @@
+package release
+
+import (
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func Test_valuesChecksum(t *testing.T) {
+	testCases := []struct {
+		name          string
+		valuesA       string
+		valuesB       string
+		expectedEqual bool
+	}{
+		{
+			name: "case 0: different key order",
+			valuesA: `a: 1
+b:
+  c: true
+  d: test`,
+			valuesB: `b:
+  d: test
+  c: true
+a: 1`,
+			expectedEqual: true,
+		},
+		{
+			name:          "case 1: integer and float with same value",
+			valuesA:       `replicas: 2`,
+			valuesB:       `replicas: 2.0`,
+			expectedEqual: true,
+		},
+		{
+			name: "case 2: integer and float with same value in list",
+			valuesA: `ports:
+- 8080
+- port: 9090`,
+			valuesB: `ports:
+- 8080.0
+- port: 9090.0`,
+			expectedEqual: true,
+		},
+		{
+			name:          "case 3: large integers",
+			valuesA:       `memory: 1000000`,
+			valuesB:       `memory: 1e6`,
+			expectedEqual: true,
+		},
+		{
+			name:          "case 4: different values",
+			valuesA:       `replicas: 2`,
+			valuesB:       `replicas: 2.5`,
+			expectedEqual: false,
+		},
+		{
+			name: "case 5: different list order",
+			valuesA: `hosts:
+- a
+- b`,
+			valuesB: `hosts:
+- b
+- a`,
+			expectedEqual: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			checksumA := checksumYAML(t, tc.valuesA)
+			checksumB := checksumYAML(t, tc.valuesB)
+
+			if (checksumA == checksumB) != tc.expectedEqual {
+				t.Fatalf("checksum %#q == %#q is %t, want %t", checksumA, checksumB, checksumA == checksumB, tc.expectedEqual)
+			}
+		})
+	}
+}
+
+func Test_valuesChecksum_empty(t *testing.T) {
+	checksum, err := valuesChecksum(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if checksum != "" {
+		t.Fatalf("checksum == %#q, want empty", checksum)
+	}
+}
+
+func checksumYAML(t *testing.T, values string) string {
+	t.Helper()
+
+	var m map[string]interface{}
+
+	err := yaml.Unmarshal([]byte(values), &m)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	checksum, err := valuesChecksum(m)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	return checksum
+}
@@ -0,0 +1,231 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/operatorkit/v7/pkg/controller/context/resourcecanceledcontext"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
+	"github.com/giantswarm/chart-operator/v4/pkg/project"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
+)
+
+const (
+	// crdPolicyCreate creates missing CRDs on install and upgrade.
+	crdPolicyCreate = "Create"
+	// crdPolicyCreateReplace creates missing CRDs and updates existing CRDs
+	// on install and upgrade.
+	crdPolicyCreateReplace = "CreateReplace"
+	// crdPolicySkip never creates or updates CRDs.
+	crdPolicySkip = "Skip"
+)
+
+// crdChanges holds the names of the CRDs created and updated according to
+// the CRD policy of a chart CR.
+type crdChanges struct {
+	Created []string
+	Updated []string
+}
+
+// IsEmpty returns true when no CRD was created or updated.
+func (c crdChanges) IsEmpty() bool {
+	return len(c.Created) == 0 && len(c.Updated) == 0
+}
+
+func (c crdChanges) String() string {
+	var details []string
+
+	if len(c.Created) > 0 {
+		details = append(details, fmt.Sprintf("created: %s", strings.Join(c.Created, ", ")))
+	}
+	if len(c.Updated) > 0 {
+		details = append(details, fmt.Sprintf("updated: %s", strings.Join(c.Updated, ", ")))
+	}
+
+	return strings.Join(details, "; ")
+}
+
+// crdPolicy returns the CRD policy set in the crd-policy annotation of the
+// chart CR. It is empty when the annotation is not set.
+func crdPolicy(cr v1alpha1.Chart) (string, error) {
+	policy := key.CRDPolicyAnnotation(cr)
+
+	switch policy {
+	case "", crdPolicyCreate, crdPolicyCreateReplace, crdPolicySkip:
+		return policy, nil
+	}
+
+	return "", microerror.Maskf(invalidCRDPolicyError, "CRD policy %#q must be one of %#q, %#q or %#q", policy, crdPolicySkip, crdPolicyCreate, crdPolicyCreateReplace)
+}
+
+// skipHelmCRDs returns true when Helm must not install the CRDs of the crds
+// directories of the chart, because the chart CR skips them or they are
+// managed according to its CRD policy.
+func skipHelmCRDs(cr v1alpha1.Chart) bool {
+	return key.SkipCRDs(cr) || key.CRDPolicyAnnotation(cr) != ""
+}
+
+// cancelOnInvalidCRDPolicy adds the failure to the controller context and
+// cancels the resource when the CRD policy of the chart CR is invalid.
+func (r *Resource) cancelOnInvalidCRDPolicy(ctx context.Context, cr v1alpha1.Chart, cc *controllercontext.Context) bool {
+	_, err := crdPolicy(cr)
+	if err == nil {
+		return false
+	}
+
+	reason := fmt.Sprintf("CRD policy error: (%s)", err.Error())
+	addStatusToContext(cc, reason, releasestatus.CRDPolicyFailed)
+
+	r.logger.LogCtx(ctx, "level", "warning", "message", reason, "stack", microerror.JSON(err))
+	r.logger.Debugf(ctx, "canceling resource")
+	resourcecanceledcontext.SetCanceled(ctx)
+
+	return true
+}
+
+// applyCRDPolicy creates or updates the CRDs of the crds directories of the
+// chart packaged in the given tarball according to the CRD policy of the
+// chart CR. CRDs are applied server-side and created CRDs are waited for to
+// be established. The applied changes are returned, also when waiting for
+// the created CRDs failed, and are reported with reportCRDChanges once the
+// operation has finished.
+func (r *Resource) applyCRDPolicy(ctx context.Context, cr v1alpha1.Chart, tarballPath string, timeout time.Duration) (crdChanges, error) {
+	policy, err := crdPolicy(cr)
+	if err != nil {
+		return crdChanges{}, microerror.Mask(err)
+	}
+	if policy == "" || policy == crdPolicySkip {
+		return crdChanges{}, nil
+	}
+
+	chart, err := loader.Load(tarballPath)
+	if err != nil {
+		return crdChanges{}, microerror.Mask(err)
+	}
+
+	var objects []*unstructured.Unstructured
+	for _, f := range chart.CRDObjects() {
+		parsed, err := parseCRDObjects(string(f.File.Data))
+		if err != nil {
+			return crdChanges{}, microerror.Mask(err)
+		}
+
+		objects = append(objects, parsed...)
+	}
+
+	var changes crdChanges
+
+	for _, o := range objects {
+		var current apiextensionsv1.CustomResourceDefinition
+
+		exists := true
+
+		err = r.ctrlClient.Get(ctx, client.ObjectKey{Name: o.GetName()}, &current)
+		if apierrors.IsNotFound(err) {
+			exists = false
+		} else if err != nil {
+			return changes, microerror.Mask(err)
+		}
+
+		if exists && policy == crdPolicyCreate {
+			continue
+		}
+
+		if exists {
+			err = checkStoredVersions(current, o)
+			if err != nil {
+				return changes, microerror.Mask(err)
+			}
+		}
+
+		err = r.ctrlClient.Apply(ctx, client.ApplyConfigurationFromUnstructured(o), client.FieldOwner(project.Name()), client.ForceOwnership)
+		if err != nil {
+			return changes, microerror.Mask(err)
+		}
+
+		var applied apiextensionsv1.CustomResourceDefinition
+
+		err = r.ctrlClient.Get(ctx, client.ObjectKey{Name: o.GetName()}, &applied)
+		if err != nil {
+			return changes, microerror.Mask(err)
+		}
+
+		if !exists {
+			changes.Created = append(changes.Created, o.GetName())
+		} else if applied.ResourceVersion != current.ResourceVersion {
+			changes.Updated = append(changes.Updated, o.GetName())
+		}
+	}
+
+	if changes.IsEmpty() {
+		r.logger.Debugf(ctx, "CRDs of release %#q are up to date", key.ReleaseName(cr))
+		return changes, nil
+	}
+
+	r.logger.Debugf(ctx, "applied CRDs of release %#q, %s", key.ReleaseName(cr), changes.String())
+
+	if len(changes.Created) > 0 {
+		err = r.waitForCRDsEstablished(ctx, changes.Created, timeout)
+		if err != nil {
+			return changes, microerror.Mask(err)
+		}
+	}
+
+	return changes, nil
+}
+
+// reportCRDChanges reports the CRD changes applied according to the CRD
+// policy in an event and the crd-changes annotation of the chart CR.
+func (r *Resource) reportCRDChanges(ctx context.Context, cr v1alpha1.Chart, changes crdChanges) error {
+	if changes.IsEmpty() {
+		return nil
+	}
+
+	r.event.Eventf(&cr, corev1.EventTypeNormal, crdsAppliedEventReason, "applied CRDs of release %#q with policy %#q, %s", key.ReleaseName(cr), key.CRDPolicyAnnotation(cr), changes.String())
+
+	err := r.addAnnotation(ctx, cr, annotation.CRDChanges, changes.String())
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// checkStoredVersions returns a crdStoredVersionRemovedError when the
+// desired CRD does not serve a version the current CRD may still have custom
+// resources stored in.
+func checkStoredVersions(current apiextensionsv1.CustomResourceDefinition, desired *unstructured.Unstructured) error {
+	var crd apiextensionsv1.CustomResourceDefinition
+
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(desired.Object, &crd)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	versions := map[string]bool{}
+	for _, v := range crd.Spec.Versions {
+		versions[v.Name] = true
+	}
+
+	for _, v := range current.Status.StoredVersions {
+		if !versions[v] {
+			return microerror.Maskf(crdStoredVersionRemovedError, "CRD %#q removes version %#q which is still stored", current.Name, v)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,227 @@
+package release
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/micrologger/microloggertest"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
+)
+
+const greetingsCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: greetings.example.com
+spec:
+  group: example.com
+  names:
+    kind: Greeting
+    plural: greetings
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+`
+
+func Test_crdPolicy(t *testing.T) {
+	testCases := []struct {
+		name           string
+		policy         string
+		skipCRDs       bool
+		expectedSkip   bool
+		expectedPolicy string
+		errorMatcher   func(error) bool
+	}{
+		{
+			name: "case 0: no policy",
+		},
+		{
+			name:         "case 1: no policy with skip CRDs",
+			skipCRDs:     true,
+			expectedSkip: true,
+		},
+		{
+			name:           "case 2: create replace policy",
+			policy:         "CreateReplace",
+			expectedSkip:   true,
+			expectedPolicy: crdPolicyCreateReplace,
+		},
+		{
+			name:         "case 3: invalid policy",
+			policy:       "Replace",
+			expectedSkip: true,
+			errorMatcher: IsInvalidCRDPolicy,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			cr := v1alpha1.Chart{
+				Spec: v1alpha1.ChartSpec{
+					Install: v1alpha1.ChartSpecInstall{
+						SkipCRDs: tc.skipCRDs,
+					},
+				},
+			}
+			if tc.policy != "" {
+				cr.Annotations = map[string]string{
+					annotation.CRDPolicy: tc.policy,
+				}
+			}
+
+			policy, err := crdPolicy(cr)
+			switch {
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case err != nil && !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if policy != tc.expectedPolicy {
+				t.Fatalf("policy == %#q, want %#q", policy, tc.expectedPolicy)
+			}
+			if skipHelmCRDs(cr) != tc.expectedSkip {
+				t.Fatalf("skip == %t, want %t", skipHelmCRDs(cr), tc.expectedSkip)
+			}
+		})
+	}
+}
+
+func Test_applyCRDPolicy(t *testing.T) {
+	chartDir := filepath.Join(t.TempDir(), "hello-world")
+
+	err := os.MkdirAll(filepath.Join(chartDir, "crds"), 0755)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	err = os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("apiVersion: v2\nname: hello-world\nversion: 1.0.0\n"), 0600)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	err = os.WriteFile(filepath.Join(chartDir, "crds", "greetings.yaml"), []byte(greetingsCRD), 0600)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	testCases := []struct {
+		name            string
+		policy          string
+		storedVersions  []string
+		expectedChanges string
+		errorMatcher    func(error) bool
+	}{
+		{
+			name:   "case 0: create policy keeps existing CRD",
+			policy: crdPolicyCreate,
+		},
+		{
+			name:            "case 1: create replace policy updates existing CRD",
+			policy:          crdPolicyCreateReplace,
+			storedVersions:  []string{"v1"},
+			expectedChanges: "updated: greetings.example.com",
+		},
+		{
+			name:           "case 2: create replace policy refuses to remove stored version",
+			policy:         crdPolicyCreateReplace,
+			storedVersions: []string{"v1alpha1", "v1"},
+			errorMatcher:   IsCRDStoredVersionRemoved,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			ctx := context.Background()
+
+			s := runtime.NewScheme()
+			err := apiextensionsv1.AddToScheme(s)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			err = v1alpha1.AddToScheme(s)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			cr := &v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "hello-world",
+					Namespace: "giantswarm",
+					Annotations: map[string]string{
+						annotation.CRDPolicy: tc.policy,
+					},
+				},
+			}
+			crd := &apiextensionsv1.CustomResourceDefinition{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "greetings.example.com",
+				},
+				Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+					Group: "example.com",
+					Names: apiextensionsv1.CustomResourceDefinitionNames{
+						Kind:   "Greeting",
+						Plural: "greetings",
+					},
+					Scope: apiextensionsv1.ClusterScoped,
+				},
+				Status: apiextensionsv1.CustomResourceDefinitionStatus{
+					StoredVersions: tc.storedVersions,
+				},
+			}
+
+			ctrlClient := fake.NewClientBuilder().WithScheme(s).WithObjects(cr, crd).Build()
+
+			r := &Resource{
+				ctrlClient: ctrlClient,
+				event:      &record.FakeRecorder{},
+				logger:     microloggertest.New(),
+			}
+
+			changes, err := r.applyCRDPolicy(ctx, *cr, chartDir, 0)
+			switch {
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case err != nil && !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if changes.String() != tc.expectedChanges {
+				t.Fatalf("changes == %#q, want %#q", changes.String(), tc.expectedChanges)
+			}
+
+			// Changes are only reported once the operation has finished.
+			var current v1alpha1.Chart
+
+			err = ctrlClient.Get(ctx, client.ObjectKeyFromObject(cr), &current)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if _, ok := current.Annotations[annotation.CRDChanges]; ok {
+				t.Fatalf("annotation %#q set, want unset", annotation.CRDChanges)
+			}
+		})
+	}
+}
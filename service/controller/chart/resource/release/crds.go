@@ -0,0 +1,253 @@
+package release
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/backoff"
+	"github.com/giantswarm/microerror"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+)
+
+const (
+	// crdEstablishedInterval is how often the conditions of created CRDs
+	// are checked.
+	crdEstablishedInterval = 2 * time.Second
+
+	// defaultCRDEstablishedTimeout is how long to wait for created CRDs to
+	// be established when the chart CR has no upgrade timeout.
+	defaultCRDEstablishedTimeout = time.Minute
+)
+
+// applyNewCRDs creates the CRDs of the chart packaged in the given tarball
+// which do not exist in the cluster yet and waits for them to be
+// established. This is the first step of the two-step upgrade, so that
+// custom resources of new kinds can be created by the following upgrade.
+func (r *Resource) applyNewCRDs(ctx context.Context, cr v1alpha1.Chart, tarballPath string, values map[string]interface{}, timeout time.Duration) error {
+	crds, err := r.chartCRDs(ctx, cr, tarballPath, values)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	created, err := r.createNewCRDs(ctx, cr, crds)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if len(created) == 0 {
+		r.logger.Debugf(ctx, "release %#q has no new CRDs", key.ReleaseName(cr))
+		return nil
+	}
+
+	r.logger.Debugf(ctx, "waiting for CRDs %s of release %#q to be established", strings.Join(created, ", "), key.ReleaseName(cr))
+
+	err = r.waitForCRDsEstablished(ctx, created, timeout)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.logger.Debugf(ctx, "CRDs %s of release %#q are established", strings.Join(created, ", "), key.ReleaseName(cr))
+
+	return nil
+}
+
+// createNewCRDs creates the given CRDs which do not exist yet and returns
+// the names of the created CRDs. Existing CRDs are left to Helm.
+func (r *Resource) createNewCRDs(ctx context.Context, cr v1alpha1.Chart, crds []*apiextensionsv1.CustomResourceDefinition) ([]string, error) {
+	var created []string
+
+	for _, crd := range crds {
+		var current apiextensionsv1.CustomResourceDefinition
+
+		err := r.ctrlClient.Get(ctx, client.ObjectKey{Name: crd.Name}, &current)
+		if err == nil {
+			continue
+		} else if !apierrors.IsNotFound(err) {
+			return nil, microerror.Mask(err)
+		}
+
+		r.logger.Debugf(ctx, "creating CRD %#q of release %#q", crd.Name, key.ReleaseName(cr))
+
+		err = r.ctrlClient.Create(ctx, crd)
+		if apierrors.IsAlreadyExists(err) {
+			continue
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		r.logger.Debugf(ctx, "created CRD %#q of release %#q", crd.Name, key.ReleaseName(cr))
+
+		created = append(created, crd.Name)
+	}
+
+	sort.Strings(created)
+
+	return created, nil
+}
+
+// waitForCRDsEstablished waits until all CRDs with the given names have the
+// Established condition or the timeout is reached.
+func (r *Resource) waitForCRDsEstablished(ctx context.Context, names []string, timeout time.Duration) error {
+	o := func() error {
+		for _, name := range names {
+			var crd apiextensionsv1.CustomResourceDefinition
+
+			err := r.ctrlClient.Get(ctx, client.ObjectKey{Name: name}, &crd)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			if !isCRDEstablished(crd) {
+				return microerror.Maskf(crdNotEstablishedError, "CRD %#q", name)
+			}
+		}
+
+		return nil
+	}
+	b := backoff.NewConstant(timeout, crdEstablishedInterval)
+
+	err := backoff.Retry(o, b)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// chartCRDs returns the CRDs of the chart packaged in the given tarball.
+// CRDs in the crds directories of the chart and its subcharts are skipped
+// when the chart CR skips CRDs or manages them with its CRD policy. CRDs rendered from
+// templates get the Helm ownership metadata so the upgrade adopts them.
+func (r *Resource) chartCRDs(ctx context.Context, cr v1alpha1.Chart, tarballPath string, values map[string]interface{}) ([]*apiextensionsv1.CustomResourceDefinition, error) {
+	var crds []*apiextensionsv1.CustomResourceDefinition
+
+	if !skipHelmCRDs(cr) {
+		chart, err := loader.Load(tarballPath)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, f := range chart.CRDObjects() {
+			parsed, err := parseCRDs(string(f.File.Data))
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+
+			crds = append(crds, parsed...)
+		}
+	}
+
+	manifest, err := r.renderManifest(ctx, cr, tarballPath, values)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	templated, err := parseCRDs(manifest)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	for _, crd := range templated {
+		if crd.Labels == nil {
+			crd.Labels = map[string]string{}
+		}
+		crd.Labels["app.kubernetes.io/managed-by"] = "Helm"
+
+		if crd.Annotations == nil {
+			crd.Annotations = map[string]string{}
+		}
+		crd.Annotations["meta.helm.sh/release-name"] = key.ReleaseName(cr)
+		crd.Annotations["meta.helm.sh/release-namespace"] = key.Namespace(cr)
+	}
+
+	return append(crds, templated...), nil
+}
+
+// parseCRDs returns the apiextensions.k8s.io/v1 CRDs of the given manifest.
+func parseCRDs(manifest string) ([]*apiextensionsv1.CustomResourceDefinition, error) {
+	objects, err := parseCRDObjects(manifest)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var crds []*apiextensionsv1.CustomResourceDefinition
+
+	for _, o := range objects {
+		var crd apiextensionsv1.CustomResourceDefinition
+
+		err = runtime.DefaultUnstructuredConverter.FromUnstructured(o.Object, &crd)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		crds = append(crds, &crd)
+	}
+
+	return crds, nil
+}
+
+// parseCRDObjects returns the apiextensions.k8s.io/v1 CRDs of the given
+// manifest as unstructured objects holding only the fields set in the
+// manifest, e.g. for applying them server-side.
+func parseCRDObjects(manifest string) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+
+	for _, doc := range sortedManifests(manifest) {
+		var o map[string]interface{}
+
+		err := yaml.Unmarshal([]byte(doc), &o)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		u := &unstructured.Unstructured{Object: o}
+		if u.GetAPIVersion() != apiextensionsv1.SchemeGroupVersion.String() || u.GetKind() != "CustomResourceDefinition" {
+			continue
+		}
+
+		objects = append(objects, u)
+	}
+
+	return objects, nil
+}
+
+// sortedManifests splits the manifest into its documents in the order Helm
+// would install them.
+func sortedManifests(manifest string) []string {
+	split := releaseutil.SplitManifests(manifest)
+
+	keys := make([]string, 0, len(split))
+	for k := range split {
+		keys = append(keys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	docs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		docs = append(docs, split[k])
+	}
+
+	return docs
+}
+
+func isCRDEstablished(crd apiextensionsv1.CustomResourceDefinition) bool {
+	for _, c := range crd.Status.Conditions {
+		if c.Type == apiextensionsv1.Established {
+			return c.Status == apiextensionsv1.ConditionTrue
+		}
+	}
+
+	return false
+}
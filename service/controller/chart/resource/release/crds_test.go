@@ -0,0 +1,150 @@
+package release
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/micrologger/microloggertest"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const crdsManifest = `---
+# Source: hello-world/templates/crds.yaml
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: greetings.example.com
+spec:
+  group: example.com
+  names:
+    kind: Greeting
+    plural: greetings
+  scope: Namespaced
+---
+# Source: hello-world/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: hello-world
+`
+
+func Test_parseCRDs(t *testing.T) {
+	crds, err := parseCRDs(crdsManifest)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	if len(crds) != 1 {
+		t.Fatalf("len(crds) == %d, want 1", len(crds))
+	}
+	if crds[0].Name != "greetings.example.com" {
+		t.Fatalf("name == %#q, want %#q", crds[0].Name, "greetings.example.com")
+	}
+	if crds[0].Spec.Names.Kind != "Greeting" {
+		t.Fatalf("kind == %#q, want %#q", crds[0].Spec.Names.Kind, "Greeting")
+	}
+}
+
+func Test_createNewCRDs(t *testing.T) {
+	ctx := context.Background()
+
+	s := runtime.NewScheme()
+	err := apiextensionsv1.AddToScheme(s)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	newCRD := func(name string) *apiextensionsv1.CustomResourceDefinition {
+		return &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+		}
+	}
+
+	ctrlClient := fake.NewClientBuilder().WithScheme(s).WithObjects(newCRD("farewells.example.com")).Build()
+
+	r := &Resource{
+		ctrlClient: ctrlClient,
+		logger:     microloggertest.New(),
+	}
+
+	cr := v1alpha1.Chart{
+		Spec: v1alpha1.ChartSpec{
+			Name: "hello-world",
+		},
+	}
+
+	crds := []*apiextensionsv1.CustomResourceDefinition{
+		newCRD("greetings.example.com"),
+		newCRD("farewells.example.com"),
+	}
+
+	created, err := r.createNewCRDs(ctx, cr, crds)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	expected := []string{"greetings.example.com"}
+	if !reflect.DeepEqual(created, expected) {
+		t.Fatalf("created == %v, want %v", created, expected)
+	}
+
+	var crd apiextensionsv1.CustomResourceDefinition
+
+	err = ctrlClient.Get(ctx, client.ObjectKey{Name: "greetings.example.com"}, &crd)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+}
+
+func Test_waitForCRDsEstablished(t *testing.T) {
+	ctx := context.Background()
+
+	s := runtime.NewScheme()
+	err := apiextensionsv1.AddToScheme(s)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	newCRD := func(name string, established apiextensionsv1.ConditionStatus) *apiextensionsv1.CustomResourceDefinition {
+		return &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Status: apiextensionsv1.CustomResourceDefinitionStatus{
+				Conditions: []apiextensionsv1.CustomResourceDefinitionCondition{
+					{
+						Type:   apiextensionsv1.Established,
+						Status: established,
+					},
+				},
+			},
+		}
+	}
+
+	r := &Resource{
+		ctrlClient: fake.NewClientBuilder().WithScheme(s).WithObjects(
+			newCRD("greetings.example.com", apiextensionsv1.ConditionTrue),
+			newCRD("farewells.example.com", apiextensionsv1.ConditionFalse),
+		).Build(),
+		logger: microloggertest.New(),
+	}
+
+	err = r.waitForCRDsEstablished(ctx, []string{"greetings.example.com"}, time.Second)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	err = r.waitForCRDsEstablished(ctx, []string{"greetings.example.com", "farewells.example.com"}, time.Millisecond)
+	if !IsCRDNotEstablished(err) {
+		t.Fatalf("error == %#v, want matching", err)
+	}
+}
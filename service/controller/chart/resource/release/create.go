@@ -8,9 +8,12 @@ import (
 	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/operatorkit/v7/pkg/controller/context/resourcecanceledcontext"
+	corev1 "k8s.io/api/core/v1"
 
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
+	"github.com/giantswarm/chart-operator/v4/service/internal/operation"
 )
 
 const (
@@ -45,60 +48,104 @@ func (r *Resource) ApplyCreateChange(ctx context.Context, obj, createChange inte
 	r.logger.Debugf(ctx, "creating release %#q in namespace %#q", releaseState.Name, key.Namespace(cr))
 
 	ns := key.Namespace(cr)
-	tarballURL := key.TarballURL(cr)
-	skipCRDs := key.SkipCRDs(cr)
+	skipCRDs := skipHelmCRDs(cr)
 	timeout := key.InstallTimeout(cr)
 
-	tarballPath, err := hc.PullChartTarball(ctx, tarballURL)
-	if helmclient.IsPullChartFailedError(err) {
-		reason := fmt.Sprintf("pulling chart %#q failed", tarballURL)
-		addStatusToContext(cc, reason, chartPullFailedStatus)
+	crdTimeout := defaultCRDEstablishedTimeout
+	if timeout != nil {
+		crdTimeout = (*timeout).Duration
+	}
 
-		r.logger.LogCtx(ctx, "level", "warning", "message", reason, "stack", microerror.JSON(err))
-		r.logger.Debugf(ctx, "canceling resource")
-		resourcecanceledcontext.SetCanceled(ctx)
+	tarballPath, err := r.pullChartTarball(ctx, cr, hc)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if tarballPath == "" {
+		// Pulling the chart failed and the resource was canceled.
 		return nil
-	} else if helmclient.IsPullChartNotFound(err) {
-		reason := fmt.Sprintf("chart %#q not found", tarballURL)
-		addStatusToContext(cc, reason, chartPullFailedStatus)
+	}
 
-		r.logger.LogCtx(ctx, "level", "warning", "message", reason, "stack", microerror.JSON(err))
-		r.logger.Debugf(ctx, "canceling resource")
-		resourcecanceledcontext.SetCanceled(ctx)
+	// The tarball is owned by the background operation once it is started,
+	// as the operation may outlive this function.
+	tarballOwned := false
+	defer func() {
+		if !tarballOwned {
+			r.removeTarball(ctx, tarballPath)
+		}
+	}()
+
+	if r.cancelOnInvalidCRDPolicy(ctx, cr, cc) {
 		return nil
-	} else if helmclient.IsPullChartTimeout(err) {
-		reason := fmt.Sprintf("timeout pulling %#q", tarballURL)
-		addStatusToContext(cc, reason, chartPullFailedStatus)
+	}
 
-		r.logger.LogCtx(ctx, "level", "warning", "message", reason, "stack", microerror.JSON(err))
+	o, started := r.operations.Start(cr.UID, operation.Operation{
+		Kind:           operation.Install,
+		Release:        releaseState.Name,
+		Version:        releaseState.Version,
+		ValuesChecksum: releaseState.ValuesChecksum,
+	})
+	if !started {
+		r.logger.Debugf(ctx, "not installing release %#q, %s to version %#q is still running", releaseState.Name, o.Kind, o.Version)
 		r.logger.Debugf(ctx, "canceling resource")
 		resourcecanceledcontext.SetCanceled(ctx)
 		return nil
-	} else if err != nil {
-		return microerror.Mask(err)
 	}
 
-	defer func() {
-		err := r.fs.Remove(tarballPath)
-		if err != nil {
-			r.logger.Errorf(ctx, err, "deletion of %#q failed", tarballPath)
-		}
-	}()
+	r.event.Eventf(&cr, corev1.EventTypeNormal, installStartedEventReason, "installing release %#q version %#q", releaseState.Name, releaseState.Version)
 
-	ch := make(chan error)
+	ch := make(chan error, 1)
+	start := time.Now()
+	tarballOwned = true
 
 	// We create the helm release but with a wait timeout so we don't
 	// block reconciling other CRs.
 	//
 	// If we do timeout the install will continue in the background.
-	// We will check the progress in the next reconciliation loop.
+	// Its result is recorded in the operation registry and checked in the
+	// next reconciliation loop.
 	go func() {
+		var changes crdChanges
 		var e error
 
-		defer close(ch)
+		defer func() {
+			r.operations.Finish(cr.UID, e)
+			r.observeOperation(cr, installOperation, start, e)
+
+			// The result is sent before patching the Chart CR so the API
+			// calls below do not count against the k8s wait timeout. CRD
+			// changes are only reported once the operation has finished,
+			// also when it continued in the background.
+			ch <- e
+
+			err := r.reportCRDChanges(ctx, cr, changes)
+			if err != nil {
+				r.logger.Errorf(ctx, err, "reporting CRD changes of release %#q failed", releaseState.Name)
+			}
+
+			err = r.updateFailedAttempts(ctx, cr, e != nil)
+			if err != nil {
+				r.logger.Errorf(ctx, err, "updating failed attempts of release %#q failed", releaseState.Name)
+			}
+		}()
+		defer r.removeTarball(ctx, tarballPath)
+
+		changes, e = r.applyCRDPolicy(ctx, cr, tarballPath, crdTimeout)
+		if e != nil {
+			return
+		}
+
+		// The Helm client does not support post-renderers, so releases with
+		// post-render patches are installed from a post-rendered chart.
+		chartPath, e := r.postRender(ctx, cr, tarballPath, releaseState, false)
+		if e != nil {
+			return
+		}
+		if chartPath != tarballPath {
+			defer r.removePostRenderedChart(ctx, chartPath)
+		}
 
 		if skipCRDs {
-			r.logger.Debugf(ctx, "helm release %#q has SkipCRDs set to true, not installing CRDs", releaseState.Name)
+			r.logger.Debugf(ctx, "helm release %#q skips CRDs or has a CRD policy, not installing CRDs with Helm", releaseState.Name)
 		}
 		iOpts := helmclient.InstallOptions{
 			ReleaseName: releaseState.Name,
@@ -113,21 +160,20 @@ func (r *Resource) ApplyCreateChange(ctx context.Context, obj, createChange inte
 
 		// We need to pass the ValueOverrides option to make the install process
 		// use the default values and prevent errors on nested values.
-		e = hc.InstallReleaseFromTarball(ctx, tarballPath, ns, releaseState.Values, iOpts)
+		e = hc.InstallReleaseFromTarball(ctx, chartPath, ns, releaseState.Values, iOpts)
 
 		// We check the error here to return early if installation failed. There is no point
 		// in upgrading in such scenario.
 		if e != nil {
-			err = e
 			return
 		}
 
 		// Load the chart to get its annotations and verify it is a subject to
 		// internal upgrade procedure. If we experience error here we log it and
 		// return.
-		chart, e := hc.LoadChart(ctx, tarballPath)
-		if e != nil {
-			r.logger.Errorf(ctx, err, "loading chart %#q failed on internal upgrade", tarballPath)
+		chart, loadErr := hc.LoadChart(ctx, tarballPath)
+		if loadErr != nil {
+			r.logger.Errorf(ctx, loadErr, "loading chart %#q failed on internal upgrade", tarballPath)
 			return
 		}
 		if _, ok := chart.Annotations[subjectToTwoStepInstall]; !ok {
@@ -149,8 +195,8 @@ func (r *Resource) ApplyCreateChange(ctx context.Context, obj, createChange inte
 
 		r.logger.Debugf(ctx, "doing internal upgrade for release %#q", releaseState.Name)
 
-		err = hc.UpdateReleaseFromTarball(ctx,
-			tarballPath,
+		e = hc.UpdateReleaseFromTarball(ctx,
+			chartPath,
 			ns,
 			releaseState.Name,
 			releaseState.Values,
@@ -158,7 +204,7 @@ func (r *Resource) ApplyCreateChange(ctx context.Context, obj, createChange inte
 	}()
 
 	select {
-	case <-ch:
+	case err = <-ch:
 		// Fall through.
 	case <-time.After(r.k8sWaitTimeout):
 		r.logger.Debugf(ctx, "waited for %d secs. release still being created", int64(r.k8sWaitTimeout.Seconds()))
@@ -177,7 +223,8 @@ func (r *Resource) ApplyCreateChange(ctx context.Context, obj, createChange inte
 		reason := err.Error()
 		reason = fmt.Sprintf("object already exists: (%s)", reason)
 		r.logger.Debugf(ctx, "helm release %#q failed, %s", releaseState.Name, reason)
-		addStatusToContext(cc, reason, alreadyExistsStatus)
+		addStatusToContext(cc, reason, releasestatus.AlreadyExists)
+		r.event.Event(&cr, corev1.EventTypeWarning, installFailedEventReason, reason)
 
 		r.logger.Debugf(ctx, "canceling resource")
 		resourcecanceledcontext.SetCanceled(ctx)
@@ -186,7 +233,8 @@ func (r *Resource) ApplyCreateChange(ctx context.Context, obj, createChange inte
 		reason := err.Error()
 		reason = fmt.Sprintf("helm validation error: (%s)", reason)
 		r.logger.Debugf(ctx, "helm release %#q failed, %s", releaseState.Name, reason)
-		addStatusToContext(cc, reason, validationFailedStatus)
+		addStatusToContext(cc, reason, releasestatus.ValidationFailed)
+		r.event.Event(&cr, corev1.EventTypeWarning, installFailedEventReason, reason)
 
 		r.logger.Debugf(ctx, "canceling resource")
 		resourcecanceledcontext.SetCanceled(ctx)
@@ -195,7 +243,26 @@ func (r *Resource) ApplyCreateChange(ctx context.Context, obj, createChange inte
 		reason := err.Error()
 		reason = fmt.Sprintf("invalid manifest error: (%s)", reason)
 		r.logger.Debugf(ctx, "helm release %#q failed, %s", releaseState.Name, reason)
-		addStatusToContext(cc, reason, invalidManifestStatus)
+		addStatusToContext(cc, reason, releasestatus.InvalidManifest)
+		r.event.Event(&cr, corev1.EventTypeWarning, installFailedEventReason, reason)
+
+		r.logger.Debugf(ctx, "canceling resource")
+		resourcecanceledcontext.SetCanceled(ctx)
+		return nil
+	} else if IsPostRenderFailed(err) {
+		reason := fmt.Sprintf("post-render error: (%s)", err.Error())
+		r.logger.Debugf(ctx, "helm release %#q failed, %s", releaseState.Name, reason)
+		addStatusToContext(cc, reason, releasestatus.PostRenderFailed)
+		r.event.Event(&cr, corev1.EventTypeWarning, installFailedEventReason, reason)
+
+		r.logger.Debugf(ctx, "canceling resource")
+		resourcecanceledcontext.SetCanceled(ctx)
+		return nil
+	} else if IsCRDStoredVersionRemoved(err) {
+		reason := fmt.Sprintf("CRD policy error: (%s)", err.Error())
+		r.logger.Debugf(ctx, "helm release %#q failed, %s", releaseState.Name, reason)
+		addStatusToContext(cc, reason, releasestatus.CRDPolicyFailed)
+		r.event.Event(&cr, corev1.EventTypeWarning, installFailedEventReason, reason)
 
 		r.logger.Debugf(ctx, "canceling resource")
 		resourcecanceledcontext.SetCanceled(ctx)
@@ -205,7 +272,8 @@ func (r *Resource) ApplyCreateChange(ctx context.Context, obj, createChange inte
 
 		if isSchemaValidationError(err) {
 			r.logger.Errorf(ctx, err, "values schema validation for %#q failed", releaseState.Name)
-			addStatusToContext(cc, err.Error(), valuesSchemaViolation)
+			addStatusToContext(cc, err.Error(), releasestatus.ValuesSchemaViolation)
+			r.event.Event(&cr, corev1.EventTypeWarning, valuesSchemaViolationEventReason, err.Error())
 
 			r.logger.Debugf(ctx, "canceling resource")
 			resourcecanceledcontext.SetCanceled(ctx)
@@ -214,7 +282,8 @@ func (r *Resource) ApplyCreateChange(ctx context.Context, obj, createChange inte
 
 		releaseContent, relErr := hc.GetReleaseContent(ctx, ns, releaseState.Name)
 		if helmclient.IsReleaseNotFound(relErr) {
-			addStatusToContext(cc, err.Error(), releaseNotInstalledStatus)
+			addStatusToContext(cc, err.Error(), releasestatus.NotInstalled)
+			r.event.Event(&cr, corev1.EventTypeWarning, installFailedEventReason, err.Error())
 
 			r.logger.Debugf(ctx, "canceling resource")
 			resourcecanceledcontext.SetCanceled(ctx)
@@ -226,6 +295,7 @@ func (r *Resource) ApplyCreateChange(ctx context.Context, obj, createChange inte
 		// Release is failed so the status resource will check the Helm release.
 		if releaseContent.Status == helmclient.StatusFailed {
 			addStatusToContext(cc, releaseContent.Description, helmclient.StatusFailed)
+			r.event.Event(&cr, corev1.EventTypeWarning, installFailedEventReason, releaseContent.Description)
 
 			r.logger.Debugf(ctx, "failed to create release %#q", releaseContent.Name)
 			r.logger.Debugf(ctx, "canceling resource")
@@ -233,7 +303,8 @@ func (r *Resource) ApplyCreateChange(ctx context.Context, obj, createChange inte
 			return nil
 		}
 
-		addStatusToContext(cc, err.Error(), unknownError)
+		addStatusToContext(cc, err.Error(), releasestatus.UnknownError)
+		r.event.Event(&cr, corev1.EventTypeWarning, installFailedEventReason, err.Error())
 
 		r.logger.Debugf(ctx, "canceling resource")
 		resourcecanceledcontext.SetCanceled(ctx)
@@ -241,6 +312,7 @@ func (r *Resource) ApplyCreateChange(ctx context.Context, obj, createChange inte
 	}
 
 	r.logger.Debugf(ctx, "created release %#q in namespace %#q", releaseState.Name, key.Namespace(cr))
+	r.event.Eventf(&cr, corev1.EventTypeNormal, installSucceededEventReason, "installed release %#q version %#q", releaseState.Name, releaseState.Version)
 
 	// We set the hash annotation so the update state calculation
 	// is accurate when we check in the next reconciliation loop.
@@ -253,6 +325,15 @@ func (r *Resource) ApplyCreateChange(ctx context.Context, obj, createChange inte
 }
 
 func (r *Resource) newCreateChange(ctx context.Context, obj, currentState, desiredState interface{}) (interface{}, error) {
+	cr, err := key.ToCustomResource(obj)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	cc, err := controllercontext.FromContext(ctx)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
 	currentReleaseState, err := toReleaseState(currentState)
 	if err != nil {
 		return nil, microerror.Mask(err)
@@ -267,6 +348,20 @@ func (r *Resource) newCreateChange(ctx context.Context, obj, currentState, desir
 	createState := &ReleaseState{}
 
 	if isEmpty(currentReleaseState) {
+		if r.isBackingOff(ctx, cr, cc) {
+			r.logger.Debugf(ctx, "the %#q release does not need to be created yet", desiredReleaseState.Name)
+			return createState, nil
+		}
+
+		waiting, err := r.isWaitingForDependencies(ctx, cr, cc)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if waiting {
+			r.logger.Debugf(ctx, "the %#q release does not need to be created yet", desiredReleaseState.Name)
+			return createState, nil
+		}
+
 		r.logger.Debugf(ctx, "the %#q release needs to be created", desiredReleaseState.Name)
 
 		createState = &desiredReleaseState
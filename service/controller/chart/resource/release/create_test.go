@@ -4,15 +4,23 @@ import (
 	"context"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
 	"github.com/giantswarm/helmclient/v4/pkg/helmclienttest"
 	"github.com/giantswarm/micrologger/microloggertest"
 	"github.com/spf13/afero"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake" //nolint:staticcheck
 
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
+
 	"github.com/giantswarm/chart-operator/v4/service/internal/clientpair"
+	"github.com/giantswarm/chart-operator/v4/service/internal/operation"
+	"github.com/giantswarm/chart-operator/v4/service/internal/releasemetrics"
 )
 
 func Test_Resource_Release_newCreate(t *testing.T) {
@@ -66,6 +74,38 @@ func Test_Resource_Release_newCreate(t *testing.T) {
 			},
 			expectedReleaseName: "desired",
 		},
+		{
+			name: "case 5: empty current, non-empty desired, backing off, expected empty",
+			obj: v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						annotation.FailedAttempts: "2",
+						annotation.NextRetry:      time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+					},
+				},
+			},
+			currentState: &ReleaseState{},
+			desiredState: &ReleaseState{
+				Name: "desired",
+			},
+			expectedReleaseName: "",
+		},
+		{
+			name: "case 6: empty current, non-empty desired, next retry reached, expected desired",
+			obj: v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						annotation.FailedAttempts: "2",
+						annotation.NextRetry:      time.Now().Add(-time.Minute).UTC().Format(time.RFC3339),
+					},
+				},
+			},
+			currentState: &ReleaseState{},
+			desiredState: &ReleaseState{
+				Name: "desired",
+			},
+			expectedReleaseName: "desired",
+		},
 	}
 
 	var newResource *Resource
@@ -81,11 +121,16 @@ func Test_Resource_Release_newCreate(t *testing.T) {
 		}
 
 		c := Config{
-			Fs:          afero.NewMemMapFs(),
-			CtrlClient:  fake.NewFakeClient(), //nolint:staticcheck
-			HelmClients: helmClients,
-			K8sClient:   k8sfake.NewClientset(),
-			Logger:      microloggertest.New(),
+			ChartCache:     newTestChartCache(t),
+			ChartVerifier:  newTestChartVerifier(t),
+			Event:          &record.FakeRecorder{},
+			Fs:             afero.NewMemMapFs(),
+			CtrlClient:     fake.NewFakeClient(), //nolint:staticcheck
+			HelmClients:    helmClients,
+			K8sClient:      k8sfake.NewClientset(),
+			Logger:         microloggertest.New(),
+			Operations:     operation.New(),
+			ReleaseMetrics: releasemetrics.New(),
 
 			TillerNamespace: "giantswarm",
 		}
@@ -96,9 +141,15 @@ func Test_Resource_Release_newCreate(t *testing.T) {
 		}
 	}
 
+	var ctx context.Context
+	{
+		c := controllercontext.Context{}
+		ctx = controllercontext.NewContext(context.Background(), c)
+	}
+
 	for i, tc := range testCases {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			result, err := newResource.newCreateChange(context.TODO(), tc.obj, tc.currentState, tc.desiredState)
+			result, err := newResource.newCreateChange(ctx, &testCases[i].obj, tc.currentState, tc.desiredState)
 			if err != nil {
 				t.Fatal("expected", nil, "got", err)
 			}
@@ -7,10 +7,12 @@ import (
 	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/operatorkit/v7/pkg/controller/context/resourcecanceledcontext"
+	corev1 "k8s.io/api/core/v1"
 
 	"github.com/giantswarm/chart-operator/v4/pkg/project"
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
 )
 
 func (r *Resource) GetCurrentState(ctx context.Context, obj interface{}) (interface{}, error) {
@@ -23,25 +25,68 @@ func (r *Resource) GetCurrentState(ctx context.Context, obj interface{}) (interf
 		return nil, microerror.Mask(err)
 	}
 
-	if key.IsCordoned(cr) {
+	if key.IsCordonExpired(cr) {
+		// app-operator owns the cordon annotations and adds them again when
+		// they are removed, so an expired cordon is only ignored. Neither the
+		// chart CR is patched nor an event emitted as this would happen on
+		// every reconciliation.
+		r.logger.Debugf(ctx, "cordon of release %#q expired at %#q, ignoring cordon", key.ReleaseName(cr), key.CordonUntil(cr))
+	} else if key.IsCordonInvalid(cr) {
+		// A cordon with an invalid date is only reported. The release is
+		// reconciled as if it was not cordoned.
+		reason := fmt.Sprintf("release %#q is not cordoned because cordon-until date %#q is not in RFC3339 format", key.ReleaseName(cr), key.CordonUntil(cr))
+		addStatusToContext(cc, reason, releasestatus.InvalidCordon)
+		r.event.Event(&cr, corev1.EventTypeWarning, invalidCordonEventReason, reason)
+
+		r.logger.LogCtx(ctx, "level", "warning", "message", reason)
+	} else if key.IsCordoned(cr) {
 		r.logger.Debugf(ctx, "release %#q has been cordoned until %#q due to reason %#q ", key.ReleaseName(cr), key.CordonUntil(cr), key.CordonReason(cr))
 		r.logger.Debugf(ctx, "canceling resource")
 		resourcecanceledcontext.SetCanceled(ctx)
 		return nil, nil
 	}
 
-	hasConfigmap, err := r.findHelmV2ConfigMaps(ctx, key.ReleaseName(cr))
+	helmV2ConfigMaps, err := r.findHelmV2ConfigMaps(ctx, key.ReleaseName(cr))
 	if err != nil {
 		reason := fmt.Sprintf("release %#q didn't migrate to helm 3", key.ReleaseName(cr))
-		addStatusToContext(cc, reason, releaseNotInstalledStatus)
+		addStatusToContext(cc, reason, releasestatus.NotInstalled)
 		return nil, microerror.Mask(err)
 	}
 
-	if hasConfigmap {
+	if len(helmV2ConfigMaps) > 0 && !r.migrateHelmV2Releases {
+		reason := fmt.Sprintf("release %#q has not been migrated from helm 2, migration of helm 2 releases is disabled", key.ReleaseName(cr))
+		addStatusToContext(cc, reason, releasestatus.HelmV2Release)
+
 		r.logger.Debugf(ctx, "release %#q has not been migrated from helm 2", key.ReleaseName(cr))
 		r.logger.Debugf(ctx, "canceling resource")
 		resourcecanceledcontext.SetCanceled(ctx)
 		return nil, nil
+	} else if len(helmV2ConfigMaps) > 0 {
+		total := len(helmV2ConfigMaps)
+
+		r.logger.Debugf(ctx, "migrating %d revisions of release %#q from helm 2", total, key.ReleaseName(cr))
+
+		migrated, err := r.migrateHelmV2Release(ctx, cr, helmV2ConfigMaps)
+		if IsHelmV2MigrationFailed(err) {
+			reason := fmt.Sprintf("migrated %d of %d helm 2 revisions of release %#q: %s", migrated, total, key.ReleaseName(cr), err.Error())
+			addStatusToContext(cc, reason, releasestatus.HelmV2MigrationFailed)
+			r.event.Event(&cr, corev1.EventTypeWarning, helmV2MigrationFailedEventReason, reason)
+
+			r.logger.LogCtx(ctx, "level", "warning", "message", reason, "stack", microerror.JSON(err))
+			r.logger.Debugf(ctx, "canceling resource")
+			resourcecanceledcontext.SetCanceled(ctx)
+			return nil, nil
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		r.logger.Debugf(ctx, "migrated %d revisions of release %#q from helm 2", migrated, key.ReleaseName(cr))
+		r.event.Eventf(&cr, corev1.EventTypeNormal, helmV2ReleaseMigratedEventReason, "migrated %d helm 2 revisions of release %#q to helm 3", migrated, key.ReleaseName(cr))
+	}
+
+	running := r.checkOperation(ctx, cr, cc)
+	if running {
+		return nil, nil
 	}
 
 	releaseName := key.ReleaseName(cr)
@@ -51,7 +96,7 @@ func (r *Resource) GetCurrentState(ctx context.Context, obj interface{}) (interf
 		return nil, nil
 	} else if helmclient.IsReleaseNameInvalid(err) {
 		reason := fmt.Sprintf("release name %#q is invalid", releaseName)
-		addStatusToContext(cc, reason, releaseNotInstalledStatus)
+		addStatusToContext(cc, reason, releasestatus.NotInstalled)
 
 		r.logger.LogCtx(ctx, "level", "warning", "message", reason, "stack", microerror.JSON(err))
 		r.logger.Debugf(ctx, "canceling resource")
@@ -70,10 +115,17 @@ func (r *Resource) GetCurrentState(ctx context.Context, obj interface{}) (interf
 	}
 
 	releaseState := &ReleaseState{
-		Name:              releaseName,
-		Status:            releaseContent.Status,
-		ValuesMD5Checksum: key.ValuesMD5ChecksumAnnotation(cr),
-		Version:           releaseContent.Version,
+		Name:    releaseName,
+		Status:  releaseContent.Status,
+		Version: releaseContent.Version,
+	}
+
+	// Chart CRs which have not been migrated to the SHA-256 checksum yet
+	// are compared using the legacy MD5 checksum.
+	if key.ValuesChecksumAlgorithmAnnotation(cr) == valuesChecksumAlgorithm {
+		releaseState.ValuesChecksum = key.ValuesChecksumAnnotation(cr)
+	} else {
+		releaseState.ValuesMD5Checksum = key.ValuesMD5ChecksumAnnotation(cr)
 	}
 
 	return releaseState, nil
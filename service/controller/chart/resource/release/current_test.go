@@ -13,12 +13,18 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/spf13/afero"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
-	"sigs.k8s.io/controller-runtime/pkg/client/fake" //nolint:staticcheck
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
 
 	"github.com/giantswarm/chart-operator/v4/service/internal/clientpair"
+	"github.com/giantswarm/chart-operator/v4/service/internal/operation"
+	"github.com/giantswarm/chart-operator/v4/service/internal/releasemetrics"
 )
 
 func Test_CurrentState(t *testing.T) {
@@ -28,6 +34,8 @@ func Test_CurrentState(t *testing.T) {
 		releaseContent *helmclient.ReleaseContent
 		returnedError  error
 		expectedState  ReleaseState
+		expectedStatus string
+		expectedEvents int
 		expectedError  bool
 	}{
 		{
@@ -86,7 +94,32 @@ func Test_CurrentState(t *testing.T) {
 			},
 		},
 		{
-			name: "case 2: empty state when error for no release present",
+			name: "case 2: sha256 checksum",
+			obj: &v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"chart-operator.giantswarm.io/values-checksum":           "3e80b3778b3b03766e7be993131c0af2ad05630c5d96fb7fa132d05b77336e04",
+						"chart-operator.giantswarm.io/values-checksum-algorithm": "sha256",
+					},
+				},
+				Spec: v1alpha1.ChartSpec{
+					Name: "prometheus",
+				},
+			},
+			releaseContent: &helmclient.ReleaseContent{
+				Name:    "prometheus",
+				Status:  "DEPLOYED",
+				Version: "0.1.2",
+			},
+			expectedState: ReleaseState{
+				Name:           "prometheus",
+				Status:         "DEPLOYED",
+				ValuesChecksum: "3e80b3778b3b03766e7be993131c0af2ad05630c5d96fb7fa132d05b77336e04",
+				Version:        "0.1.2",
+			},
+		},
+		{
+			name: "case 3: empty state when error for no release present",
 			obj: &v1alpha1.Chart{
 				Spec: v1alpha1.ChartSpec{
 					Name: "prometheus",
@@ -97,7 +130,7 @@ func Test_CurrentState(t *testing.T) {
 			expectedError:  true,
 		},
 		{
-			name: "case 3: unexpected error",
+			name: "case 4: unexpected error",
 			obj: &v1alpha1.Chart{
 				Spec: v1alpha1.ChartSpec{
 					Name: "prometheus",
@@ -108,12 +141,12 @@ func Test_CurrentState(t *testing.T) {
 			expectedError:  true,
 		},
 		{
-			name: "case 4: chart cordoned",
+			name: "case 5: chart cordoned",
 			obj: &v1alpha1.Chart{
 				ObjectMeta: metav1.ObjectMeta{
 					Annotations: map[string]string{
 						"chart-operator.giantswarm.io/cordon-reason": "testing upgrade",
-						"chart-operator.giantswarm.io/cordon-until":  "2019-12-31T23:59:59Z",
+						"chart-operator.giantswarm.io/cordon-until":  "2999-12-31T23:59:59Z",
 					},
 				},
 				Spec: v1alpha1.ChartSpec{
@@ -122,6 +155,62 @@ func Test_CurrentState(t *testing.T) {
 			},
 			expectedState: ReleaseState{},
 		},
+		{
+			name: "case 6: chart cordoned with invalid date is reconciled",
+			obj: &v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"chart-operator.giantswarm.io/cordon-reason":       "testing upgrade",
+						"chart-operator.giantswarm.io/cordon-until":        "next tuesday",
+						"chart-operator.giantswarm.io/values-md5-checksum": "1ee001c5286ca00fdf64d9660c04bde2",
+					},
+				},
+				Spec: v1alpha1.ChartSpec{
+					Name: "prometheus",
+				},
+			},
+			releaseContent: &helmclient.ReleaseContent{
+				Name:    "prometheus",
+				Status:  "DEPLOYED",
+				Version: "0.1.2",
+			},
+			expectedState: ReleaseState{
+				Name:              "prometheus",
+				Status:            "DEPLOYED",
+				ValuesMD5Checksum: "1ee001c5286ca00fdf64d9660c04bde2",
+				Version:           "0.1.2",
+			},
+			expectedStatus: releasestatus.InvalidCordon,
+			expectedEvents: 1,
+		},
+		{
+			name: "case 7: chart with expired cordon is reconciled without removing the cordon",
+			obj: &v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "prometheus",
+					Namespace: "giantswarm",
+					Annotations: map[string]string{
+						"chart-operator.giantswarm.io/cordon-reason":       "testing upgrade",
+						"chart-operator.giantswarm.io/cordon-until":        "2019-12-31T23:59:59Z",
+						"chart-operator.giantswarm.io/values-md5-checksum": "1ee001c5286ca00fdf64d9660c04bde2",
+					},
+				},
+				Spec: v1alpha1.ChartSpec{
+					Name: "prometheus",
+				},
+			},
+			releaseContent: &helmclient.ReleaseContent{
+				Name:    "prometheus",
+				Status:  "DEPLOYED",
+				Version: "0.1.2",
+			},
+			expectedState: ReleaseState{
+				Name:              "prometheus",
+				Status:            "DEPLOYED",
+				ValuesMD5Checksum: "1ee001c5286ca00fdf64d9660c04bde2",
+				Version:           "0.1.2",
+			},
+		},
 	}
 
 	for i, tc := range testCases {
@@ -132,6 +221,21 @@ func Test_CurrentState(t *testing.T) {
 				ctx = controllercontext.NewContext(context.Background(), c)
 			}
 
+			var ctrlClient client.Client
+			{
+				s := runtime.NewScheme()
+				err := v1alpha1.AddToScheme(s)
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+
+				builder := fake.NewClientBuilder().WithScheme(s)
+				if tc.obj.Name != "" {
+					builder = builder.WithObjects(tc.obj.DeepCopy())
+				}
+				ctrlClient = builder.Build()
+			}
+
 			var helmClient helmclient.Interface
 			{
 				c := helmclienttest.Config{
@@ -151,12 +255,19 @@ func Test_CurrentState(t *testing.T) {
 				t.Fatal("expected", nil, "got", err)
 			}
 
+			recorder := record.NewFakeRecorder(10)
+
 			c := Config{
-				Fs:          afero.NewMemMapFs(),
-				CtrlClient:  fake.NewFakeClient(), //nolint:staticcheck
-				HelmClients: helmClients,
-				K8sClient:   k8sfake.NewClientset(),
-				Logger:      microloggertest.New(),
+				ChartCache:     newTestChartCache(t),
+				ChartVerifier:  newTestChartVerifier(t),
+				Event:          recorder,
+				Fs:             afero.NewMemMapFs(),
+				CtrlClient:     ctrlClient,
+				HelmClients:    helmClients,
+				K8sClient:      k8sfake.NewClientset(),
+				Logger:         microloggertest.New(),
+				Operations:     operation.New(),
+				ReleaseMetrics: releasemetrics.New(),
 
 				TillerNamespace: "giantswarm",
 			}
@@ -182,6 +293,30 @@ func Test_CurrentState(t *testing.T) {
 			if !cmp.Equal(ReleaseState, tc.expectedState) {
 				t.Fatalf("want matching ReleaseState \n %s", cmp.Diff(ReleaseState, tc.expectedState))
 			}
+
+			cc, err := controllercontext.FromContext(ctx)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if cc.Status.Release.Status != tc.expectedStatus {
+				t.Fatalf("status == %#q, want %#q", cc.Status.Release.Status, tc.expectedStatus)
+			}
+
+			if len(recorder.Events) != tc.expectedEvents {
+				t.Fatalf("events == %d, want %d", len(recorder.Events), tc.expectedEvents)
+			}
+
+			if tc.obj.Name != "" {
+				var current v1alpha1.Chart
+
+				err = ctrlClient.Get(ctx, client.ObjectKeyFromObject(tc.obj), &current)
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+				if !cmp.Equal(current.Annotations, tc.obj.Annotations) {
+					t.Fatalf("want matching annotations \n %s", cmp.Diff(current.Annotations, tc.obj.Annotations))
+				}
+			}
 		})
 	}
 
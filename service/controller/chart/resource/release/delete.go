@@ -2,12 +2,14 @@ package release
 
 import (
 	"context"
+	"time"
 
 	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/operatorkit/v7/pkg/controller/context/finalizerskeptcontext"
 	"github.com/giantswarm/operatorkit/v7/pkg/controller/context/resourcecanceledcontext"
 	"github.com/giantswarm/operatorkit/v7/pkg/resource/crud"
+	corev1 "k8s.io/api/core/v1"
 
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
 )
@@ -23,6 +25,11 @@ func (r *Resource) ApplyDeleteChange(ctx context.Context, obj, deleteChange inte
 	// see: https://github.com/giantswarm/giantswarm/issues/25731
 	hc := r.helmClients.Get(ctx, cr, true)
 
+	// The chart CR is deleted so its operations and metrics are not tracked
+	// anymore.
+	r.operations.Delete(cr.UID)
+	r.releaseMetrics.DeleteDriftedObjects(cr.Name, cr.Namespace)
+
 	releaseState, err := toReleaseState(deleteChange)
 	if err != nil {
 		return microerror.Mask(err)
@@ -38,11 +45,16 @@ func (r *Resource) ApplyDeleteChange(ctx context.Context, obj, deleteChange inte
 			opts.Timeout = (*timeout).Duration
 		}
 
+		start := time.Now()
 		err = hc.DeleteRelease(ctx, key.Namespace(cr), releaseState.Name, opts)
 		if helmclient.IsReleaseNotFound(err) {
+			// Nothing was uninstalled so the delete operation is not
+			// recorded in the metrics.
 			r.logger.Debugf(ctx, "release %#q already deleted", releaseState.Name)
 			return nil
-		} else if err != nil {
+		}
+		r.observeOperation(cr, deleteOperation, start, err)
+		if err != nil {
 			return microerror.Mask(err)
 		}
 
@@ -61,6 +73,7 @@ func (r *Resource) ApplyDeleteChange(ctx context.Context, obj, deleteChange inte
 			return nil
 		} else if helmclient.IsReleaseNotFound(err) {
 			r.logger.Debugf(ctx, "deleted release %#q", releaseState.Name)
+			r.event.Eventf(&cr, corev1.EventTypeNormal, uninstalledEventReason, "uninstalled release %#q", releaseState.Name)
 		} else if err != nil {
 			return microerror.Mask(err)
 		}
@@ -6,13 +6,19 @@ import (
 	"testing"
 
 	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
 	"github.com/giantswarm/helmclient/v4/pkg/helmclienttest"
 	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/giantswarm/operatorkit/v7/pkg/controller/context/finalizerskeptcontext"
 	"github.com/spf13/afero"
+	"helm.sh/helm/v3/pkg/storage/driver"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake" //nolint:staticcheck
 
 	"github.com/giantswarm/chart-operator/v4/service/internal/clientpair"
+	"github.com/giantswarm/chart-operator/v4/service/internal/operation"
+	"github.com/giantswarm/chart-operator/v4/service/internal/releasemetrics"
 )
 
 func Test_Resource_Release_newDeleteChange(t *testing.T) {
@@ -83,11 +89,16 @@ func Test_Resource_Release_newDeleteChange(t *testing.T) {
 		}
 
 		c := Config{
-			Fs:          afero.NewMemMapFs(),
-			CtrlClient:  fake.NewFakeClient(), //nolint:staticcheck
-			HelmClients: helmClients,
-			K8sClient:   k8sfake.NewClientset(),
-			Logger:      microloggertest.New(),
+			ChartCache:     newTestChartCache(t),
+			ChartVerifier:  newTestChartVerifier(t),
+			Event:          &record.FakeRecorder{},
+			Fs:             afero.NewMemMapFs(),
+			CtrlClient:     fake.NewFakeClient(), //nolint:staticcheck
+			HelmClients:    helmClients,
+			K8sClient:      k8sfake.NewClientset(),
+			Logger:         microloggertest.New(),
+			Operations:     operation.New(),
+			ReleaseMetrics: releasemetrics.New(),
 
 			TillerNamespace: "giantswarm",
 		}
@@ -120,3 +131,112 @@ func Test_Resource_Release_newDeleteChange(t *testing.T) {
 	}
 
 }
+
+// uninstallingHelmClient returns the configured results for deleting and
+// getting releases.
+type uninstallingHelmClient struct {
+	helmclient.Interface
+
+	deleteErr      error
+	releaseContent *helmclient.ReleaseContent
+	releaseErr     error
+}
+
+func (c *uninstallingHelmClient) DeleteRelease(ctx context.Context, namespace, releaseName string, options helmclient.DeleteOptions) error {
+	return c.deleteErr
+}
+
+func (c *uninstallingHelmClient) GetReleaseContent(ctx context.Context, namespace, releaseName string) (*helmclient.ReleaseContent, error) {
+	return c.releaseContent, c.releaseErr
+}
+
+func Test_Resource_Release_ApplyDeleteChange(t *testing.T) {
+	testCases := []struct {
+		name           string
+		helmClient     *uninstallingHelmClient
+		expectedEvents int
+		expectedKept   bool
+	}{
+		{
+			name: "case 0: release is uninstalled",
+			helmClient: &uninstallingHelmClient{
+				releaseErr: driver.ErrReleaseNotFound,
+			},
+			expectedEvents: 1,
+		},
+		{
+			name: "case 1: release still exists",
+			helmClient: &uninstallingHelmClient{
+				releaseContent: &helmclient.ReleaseContent{
+					Name:   "prometheus",
+					Status: helmclient.StatusDeployed,
+				},
+			},
+			expectedKept: true,
+		},
+		{
+			name: "case 2: release was already deleted",
+			helmClient: &uninstallingHelmClient{
+				deleteErr: driver.ErrReleaseNotFound,
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			helmClients, err := clientpair.NewClientPair(clientpair.ClientPairConfig{
+				Logger: microloggertest.New(),
+
+				PrvHelmClient: tc.helmClient,
+				PubHelmClient: tc.helmClient,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			event := record.NewFakeRecorder(10)
+
+			r, err := New(Config{
+				ChartCache:     newTestChartCache(t),
+				ChartVerifier:  newTestChartVerifier(t),
+				Event:          event,
+				Fs:             afero.NewMemMapFs(),
+				CtrlClient:     fake.NewFakeClient(), //nolint:staticcheck
+				HelmClients:    helmClients,
+				K8sClient:      k8sfake.NewClientset(),
+				Logger:         microloggertest.New(),
+				Operations:     operation.New(),
+				ReleaseMetrics: releasemetrics.New(),
+
+				TillerNamespace: "giantswarm",
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			kept := make(chan struct{})
+			ctx := finalizerskeptcontext.NewContext(context.Background(), kept)
+
+			cr := v1alpha1.Chart{
+				Spec: v1alpha1.ChartSpec{
+					Name:      "prometheus",
+					Namespace: "monitoring",
+				},
+			}
+
+			err = r.ApplyDeleteChange(ctx, &cr, &ReleaseState{Name: "prometheus"})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if len(event.Events) != tc.expectedEvents {
+				t.Fatalf("len(events) == %d, want %d", len(event.Events), tc.expectedEvents)
+			}
+			if finalizerskeptcontext.IsKept(ctx) != tc.expectedKept {
+				t.Fatalf("kept == %t, want %t", finalizerskeptcontext.IsKept(ctx), tc.expectedKept)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
+)
+
+// dependencies returns the dependencies set in the depends-on annotation of
+// the chart CR.
+func dependencies(cr v1alpha1.Chart) ([]Dependency, error) {
+	value := key.DependsOnAnnotation(cr)
+	if value == "" {
+		return nil, nil
+	}
+
+	var deps []Dependency
+
+	err := json.Unmarshal([]byte(value), &deps)
+	if err != nil {
+		return nil, microerror.Maskf(invalidDependenciesError, "parsing annotation failed: %s", err.Error())
+	}
+
+	for i, d := range deps {
+		if d.Name == "" {
+			return nil, microerror.Maskf(invalidDependenciesError, "dependency %d has no name", i)
+		}
+		if d.Namespace == "" {
+			deps[i].Namespace = cr.Namespace
+		}
+		if d.Version != "" {
+			_, err = semver.NewConstraint(d.Version)
+			if err != nil {
+				return nil, microerror.Maskf(invalidDependenciesError, "dependency %d has invalid version constraint %#q: %s", i, d.Version, err.Error())
+			}
+		}
+	}
+
+	return deps, nil
+}
+
+// isWaitingForDependencies returns true when the install or upgrade of the
+// chart CR has to be held off because the chart CRs it depends on are not
+// deployed at a satisfying version yet. The reason is added to the
+// controller context so the status resource reports it. Chart CRs with
+// invalid or cyclic dependencies are held off until the annotations are
+// fixed.
+func (r *Resource) isWaitingForDependencies(ctx context.Context, cr v1alpha1.Chart, cc *controllercontext.Context) (bool, error) {
+	deps, err := dependencies(cr)
+	if IsInvalidDependencies(err) {
+		reason := fmt.Sprintf("dependencies of release %#q are invalid: %s", key.ReleaseName(cr), err.Error())
+		addStatusToContext(cc, reason, releasestatus.InvalidDependencies)
+
+		r.logger.LogCtx(ctx, "level", "warning", "message", reason, "stack", microerror.JSON(err))
+		return true, nil
+	} else if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	if len(deps) == 0 {
+		return false, nil
+	}
+
+	cycle, err := r.findDependencyCycle(ctx, cr, deps)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+	if len(cycle) > 0 {
+		reason := fmt.Sprintf("release %#q has a dependency cycle: %s", key.ReleaseName(cr), strings.Join(cycle, " -> "))
+		addStatusToContext(cc, reason, releasestatus.DependencyCycle)
+		r.event.Event(&cr, corev1.EventTypeWarning, dependencyCycleEventReason, reason)
+
+		r.logger.LogCtx(ctx, "level", "warning", "message", reason)
+		return true, nil
+	}
+
+	var unmet []string
+	for _, d := range deps {
+		u, err := r.unmetDependency(ctx, d)
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+		if u != "" {
+			unmet = append(unmet, u)
+		}
+	}
+
+	if len(unmet) == 0 {
+		return false, nil
+	}
+
+	reason := fmt.Sprintf("waiting for dependencies: %s", strings.Join(unmet, ", "))
+	addStatusToContext(cc, reason, releasestatus.WaitingForDependencies)
+
+	r.logger.Debugf(ctx, "release %#q is %s", key.ReleaseName(cr), reason)
+
+	return true, nil
+}
+
+// unmetDependency returns why the dependency is not met yet or an empty
+// string when the chart CR is deployed at a satisfying version.
+func (r *Resource) unmetDependency(ctx context.Context, d Dependency) (string, error) {
+	ref := fmt.Sprintf("%s/%s", d.Namespace, d.Name)
+
+	var dep v1alpha1.Chart
+
+	err := r.ctrlClient.Get(ctx, types.NamespacedName{Name: d.Name, Namespace: d.Namespace}, &dep)
+	if apierrors.IsNotFound(err) {
+		return fmt.Sprintf("%s not found", ref), nil
+	} else if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	status := key.ChartStatus(dep)
+	if status.Release.Status != helmclient.StatusDeployed {
+		return fmt.Sprintf("%s not deployed", ref), nil
+	}
+
+	if d.Version == "" {
+		return "", nil
+	}
+
+	// The constraint was validated when parsing the annotation.
+	constraint, err := semver.NewConstraint(d.Version)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	version, err := semver.NewVersion(status.Version)
+	if err != nil || !constraint.Check(version) {
+		return fmt.Sprintf("%s has version %#q, want %#q", ref, status.Version, d.Version), nil
+	}
+
+	return "", nil
+}
+
+// findDependencyCycle follows the depends-on annotations starting at the
+// chart CR and returns the first cycle found as namespace/name references.
+// Chart CRs which do not exist or have invalid annotations end the search
+// along their path, as they are reported separately.
+func (r *Resource) findDependencyCycle(ctx context.Context, cr v1alpha1.Chart, deps []Dependency) ([]string, error) {
+	var path []string
+	onPath := map[string]bool{}
+	visited := map[string]bool{}
+
+	var visit func(ref string, deps []Dependency) ([]string, error)
+	visit = func(ref string, deps []Dependency) ([]string, error) {
+		path = append(path, ref)
+		onPath[ref] = true
+
+		for _, d := range deps {
+			depRef := fmt.Sprintf("%s/%s", d.Namespace, d.Name)
+
+			if onPath[depRef] {
+				for i, p := range path {
+					if p == depRef {
+						cycle := append([]string{}, path[i:]...)
+						return append(cycle, depRef), nil
+					}
+				}
+			}
+			if visited[depRef] {
+				continue
+			}
+			visited[depRef] = true
+
+			var dep v1alpha1.Chart
+
+			err := r.ctrlClient.Get(ctx, types.NamespacedName{Name: d.Name, Namespace: d.Namespace}, &dep)
+			if apierrors.IsNotFound(err) {
+				continue
+			} else if err != nil {
+				return nil, microerror.Mask(err)
+			}
+
+			depDeps, err := dependencies(dep)
+			if err != nil {
+				continue
+			}
+
+			cycle, err := visit(depRef, depDeps)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			if len(cycle) > 0 {
+				return cycle, nil
+			}
+		}
+
+		path = path[:len(path)-1]
+		delete(onPath, ref)
+
+		return nil, nil
+	}
+
+	ref := fmt.Sprintf("%s/%s", cr.Namespace, cr.Name)
+	visited[ref] = true
+
+	return visit(ref, deps)
+}
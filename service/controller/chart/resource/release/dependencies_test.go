@@ -0,0 +1,174 @@
+package release
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/micrologger/microloggertest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
+)
+
+func Test_isWaitingForDependencies(t *testing.T) {
+	newChart := func(name, dependsOn, status, version string) *v1alpha1.Chart {
+		cr := &v1alpha1.Chart{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "giantswarm",
+			},
+			Spec: v1alpha1.ChartSpec{
+				Name: name,
+			},
+			Status: v1alpha1.ChartStatus{
+				Release: v1alpha1.ChartStatusRelease{
+					Status: status,
+				},
+				Version: version,
+			},
+		}
+		if dependsOn != "" {
+			cr.Annotations = map[string]string{
+				annotation.DependsOn: dependsOn,
+			}
+		}
+
+		return cr
+	}
+
+	testCases := []struct {
+		name            string
+		dependsOn       string
+		charts          []client.Object
+		expectedWaiting bool
+		expectedStatus  string
+		expectedReason  string
+	}{
+		{
+			name: "case 0: no dependencies",
+		},
+		{
+			name:      "case 1: dependency deployed",
+			dependsOn: `[{"name":"crds"}]`,
+			charts: []client.Object{
+				newChart("crds", "", "deployed", "1.2.0"),
+			},
+		},
+		{
+			name:            "case 2: dependency not found",
+			dependsOn:       `[{"name":"crds"}]`,
+			expectedWaiting: true,
+			expectedStatus:  releasestatus.WaitingForDependencies,
+			expectedReason:  "waiting for dependencies: giantswarm/crds not found",
+		},
+		{
+			name:      "case 3: dependency in other namespace not found",
+			dependsOn: `[{"name":"crds","namespace":"org-acme"}]`,
+			charts: []client.Object{
+				newChart("crds", "", "deployed", "1.2.0"),
+			},
+			expectedWaiting: true,
+			expectedStatus:  releasestatus.WaitingForDependencies,
+			expectedReason:  "waiting for dependencies: org-acme/crds not found",
+		},
+		{
+			name:      "case 4: dependency not deployed",
+			dependsOn: `[{"name":"crds"}]`,
+			charts: []client.Object{
+				newChart("crds", "", "pending-install", "1.2.0"),
+			},
+			expectedWaiting: true,
+			expectedStatus:  releasestatus.WaitingForDependencies,
+			expectedReason:  "waiting for dependencies: giantswarm/crds not deployed",
+		},
+		{
+			name:      "case 5: dependency deployed at unsatisfying version",
+			dependsOn: `[{"name":"crds","version":">=2.0.0"}]`,
+			charts: []client.Object{
+				newChart("crds", "", "deployed", "1.2.0"),
+			},
+			expectedWaiting: true,
+			expectedStatus:  releasestatus.WaitingForDependencies,
+			expectedReason:  "waiting for dependencies: giantswarm/crds has version `1.2.0`, want `>=2.0.0`",
+		},
+		{
+			name:      "case 6: dependency deployed at satisfying version",
+			dependsOn: `[{"name":"crds","version":"^1.1.0"}]`,
+			charts: []client.Object{
+				newChart("crds", "", "deployed", "1.2.0"),
+			},
+		},
+		{
+			name:      "case 7: dependency cycle",
+			dependsOn: `[{"name":"crds"}]`,
+			charts: []client.Object{
+				newChart("crds", `[{"name":"operator"}]`, "deployed", "1.2.0"),
+				newChart("operator", `[{"name":"hello-world"}]`, "deployed", "1.0.0"),
+			},
+			expectedWaiting: true,
+			expectedStatus:  releasestatus.DependencyCycle,
+			expectedReason:  "release `hello-world` has a dependency cycle: giantswarm/hello-world -> giantswarm/crds -> giantswarm/operator -> giantswarm/hello-world",
+		},
+		{
+			name:            "case 8: invalid version constraint",
+			dependsOn:       `[{"name":"crds","version":"latest"}]`,
+			expectedWaiting: true,
+			expectedStatus:  releasestatus.InvalidDependencies,
+		},
+		{
+			name:            "case 9: invalid annotation",
+			dependsOn:       `crds`,
+			expectedWaiting: true,
+			expectedStatus:  releasestatus.InvalidDependencies,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			ctx := controllercontext.NewContext(context.Background(), controllercontext.Context{})
+
+			cc, err := controllercontext.FromContext(ctx)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			s := runtime.NewScheme()
+			err = v1alpha1.AddToScheme(s)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			r := &Resource{
+				ctrlClient: fake.NewClientBuilder().WithScheme(s).WithObjects(tc.charts...).Build(),
+				event:      &record.FakeRecorder{},
+				logger:     microloggertest.New(),
+			}
+
+			cr := newChart("hello-world", tc.dependsOn, "", "")
+
+			waiting, err := r.isWaitingForDependencies(ctx, *cr, cc)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if waiting != tc.expectedWaiting {
+				t.Fatalf("waiting == %t, want %t", waiting, tc.expectedWaiting)
+			}
+			if cc.Status.Release.Status != tc.expectedStatus {
+				t.Fatalf("status == %#q, want %#q", cc.Status.Release.Status, tc.expectedStatus)
+			}
+			if tc.expectedReason != "" && cc.Status.Reason != tc.expectedReason {
+				t.Fatalf("reason == %#q, want %#q", cc.Status.Reason, tc.expectedReason)
+			}
+		})
+	}
+}
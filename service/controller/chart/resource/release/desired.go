@@ -2,8 +2,6 @@ package release
 
 import (
 	"context"
-	"crypto/md5" // #nosec
-	"fmt"
 
 	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
 	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
@@ -13,7 +11,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
 
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
 )
 
 func (r *Resource) GetDesiredState(ctx context.Context, obj interface{}) (interface{}, error) {
@@ -22,6 +22,11 @@ func (r *Resource) GetDesiredState(ctx context.Context, obj interface{}) (interf
 		return nil, microerror.Mask(err)
 	}
 
+	cc, err := controllercontext.FromContext(ctx)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
 	configMapData, err := r.getConfigMapData(ctx, cr)
 	if err != nil {
 		return nil, microerror.Mask(err)
@@ -38,27 +43,64 @@ func (r *Resource) GetDesiredState(ctx context.Context, obj interface{}) (interf
 		return nil, microerror.Mask(err)
 	}
 
-	// Convert all floats to integers if they have the same value to return the same md5 hash.
-	convertFloat(configMapData)
-
-	var valuesMD5Checksum string
+	// Additional values sources are layered on top in their declared order.
+	{
+		sourcesData, err := r.getValuesSourcesData(ctx, cr)
+		if IsInvalidValuesSources(err) {
+			r.cancelOnValuesSourcesError(ctx, cc, err, releasestatus.InvalidValuesSources)
+			return nil, nil
+		} else if IsValuesSourceNotFound(err) {
+			r.cancelOnValuesSourcesError(ctx, cc, err, releasestatus.ValuesSourceNotFound)
+			return nil, nil
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
 
-	if len(configMapData) > 0 {
-		// MD5 is only used for comparison but we need to turn off gosec or
-		// linting errors will occur.
-		h := md5.New() // #nosec
-		_, err := fmt.Fprintf(h, "%v", configMapData)
+		err = mergo.Merge(&configMapData, sourcesData, mergo.WithOverride)
 		if err != nil {
 			return nil, microerror.Mask(err)
 		}
+	}
+
+	// Convert all floats to integers if they have the same value so Helm
+	// renders them as integers.
+	convertFloat(configMapData)
+
+	patches, err := r.getPostRenderPatches(ctx, cr)
+	if IsPostRenderFailed(err) {
+		r.cancelOnValuesSourcesError(ctx, cc, err, releasestatus.PostRenderFailed)
+		return nil, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	rewriter, err := r.checksumImageRewriter(ctx, cr)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// Post-render patches and image rewriting are part of the checksums so
+	// changing them upgrades the release.
+	checksumData := postRenderChecksumData(configMapData, patches, rewriter)
 
-		valuesMD5Checksum = fmt.Sprintf("%x", h.Sum(nil))
+	checksum, err := valuesChecksum(checksumData)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// The legacy checksum is still computed so chart CRs annotated with
+	// it can be migrated without upgrading their releases.
+	md5Checksum, err := legacyValuesMD5Checksum(checksumData)
+	if err != nil {
+		return nil, microerror.Mask(err)
 	}
 
 	releaseState := &ReleaseState{
 		Name:              key.ReleaseName(cr),
 		Status:            helmclient.StatusDeployed,
-		ValuesMD5Checksum: valuesMD5Checksum,
+		ValuesChecksum:    checksum,
+		ValuesMD5Checksum: md5Checksum,
+		PostRenderPatches: patches,
 		Values:            configMapData,
 		Version:           key.Version(cr),
 	}
@@ -15,12 +15,15 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake" //nolint:staticcheck
 	"sigs.k8s.io/yaml"
 
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
 
 	"github.com/giantswarm/chart-operator/v4/service/internal/clientpair"
+	"github.com/giantswarm/chart-operator/v4/service/internal/operation"
+	"github.com/giantswarm/chart-operator/v4/service/internal/releasemetrics"
 )
 
 func Test_DesiredState(t *testing.T) {
@@ -43,6 +46,7 @@ func Test_DesiredState(t *testing.T) {
 			expectedState: ReleaseState{
 				Name:              "chart-operator-chart",
 				Status:            helmclient.StatusDeployed,
+				ValuesChecksum:    "",
 				ValuesMD5Checksum: "",
 				Values:            map[string]interface{}{},
 				Version:           "0.1.2",
@@ -72,6 +76,7 @@ func Test_DesiredState(t *testing.T) {
 			expectedState: ReleaseState{
 				Name:              "chart-operator-chart",
 				Status:            helmclient.StatusDeployed,
+				ValuesChecksum:    "",
 				ValuesMD5Checksum: "",
 				Values:            map[string]interface{}{},
 				Version:           "1.2.3",
@@ -103,6 +108,7 @@ func Test_DesiredState(t *testing.T) {
 			expectedState: ReleaseState{
 				Name:              "chart-operator-chart",
 				Status:            helmclient.StatusDeployed,
+				ValuesChecksum:    "3e80b3778b3b03766e7be993131c0af2ad05630c5d96fb7fa132d05b77336e04",
 				ValuesMD5Checksum: "6e5ae9a10fd227006b0f938c51cb300b",
 				Values: map[string]interface{}{
 					"test": "test",
@@ -136,6 +142,7 @@ func Test_DesiredState(t *testing.T) {
 			expectedState: ReleaseState{
 				Name:              "chart-operator-chart",
 				Status:            helmclient.StatusDeployed,
+				ValuesChecksum:    "2365f1f0952eed971e36c8fb95b0221f03cdd8b1a60d6133999917b6e709d8ce",
 				ValuesMD5Checksum: "4845bfb2cf922d7527886ac13599ea3b",
 				Values: map[string]interface{}{
 					"provider": "azure",
@@ -192,6 +199,7 @@ func Test_DesiredState(t *testing.T) {
 			expectedState: ReleaseState{
 				Name:              "chart-operator-chart",
 				Status:            helmclient.StatusDeployed,
+				ValuesChecksum:    "3e80b3778b3b03766e7be993131c0af2ad05630c5d96fb7fa132d05b77336e04",
 				ValuesMD5Checksum: "6e5ae9a10fd227006b0f938c51cb300b",
 				Values: map[string]interface{}{
 					"test": "test",
@@ -226,6 +234,7 @@ func Test_DesiredState(t *testing.T) {
 			expectedState: ReleaseState{
 				Name:              "chart-operator-chart",
 				Status:            helmclient.StatusDeployed,
+				ValuesChecksum:    "cd09a11ebd3548dfae41a46aeec52bd92975ddbb2521d04df62a25ddec480b75",
 				ValuesMD5Checksum: "2187a8fce91c3765a74d462062af7526",
 				Values: map[string]interface{}{
 					"secretnumber":   2,
@@ -298,6 +307,7 @@ func Test_DesiredState(t *testing.T) {
 			expectedState: ReleaseState{
 				Name:              "chart-operator-chart",
 				Status:            helmclient.StatusDeployed,
+				ValuesChecksum:    "a9bdb30833cef67249f23339c6f979379c1f87e86dc54d310eb6dd7ea5a4cddb",
 				ValuesMD5Checksum: "3b8440387b1462ecdceb25c4cb9ff065",
 				Values: map[string]interface{}{
 					"replicas":     2,
@@ -337,11 +347,16 @@ func Test_DesiredState(t *testing.T) {
 			}
 
 			c := Config{
-				Fs:          afero.NewMemMapFs(),
-				CtrlClient:  fake.NewFakeClient(), //nolint:staticcheck
-				HelmClients: helmClients,
-				K8sClient:   k8sfake.NewClientset(objs...),
-				Logger:      microloggertest.New(),
+				ChartCache:     newTestChartCache(t),
+				ChartVerifier:  newTestChartVerifier(t),
+				Event:          &record.FakeRecorder{},
+				Fs:             afero.NewMemMapFs(),
+				CtrlClient:     fake.NewFakeClient(), //nolint:staticcheck
+				HelmClients:    helmClients,
+				K8sClient:      k8sfake.NewClientset(objs...),
+				Logger:         microloggertest.New(),
+				Operations:     operation.New(),
+				ReleaseMetrics: releasemetrics.New(),
 
 				TillerNamespace: "giantswarm",
 			}
@@ -0,0 +1,130 @@
+package release
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"sigs.k8s.io/yaml"
+)
+
+// maxDiffObjectIDs is the maximum number of added, changed and removed
+// object IDs each listed in the summary of a manifest diff.
+const maxDiffObjectIDs = 10
+
+// manifestDiff holds the objects that differ between two release manifests.
+// Objects are identified by kind, namespace and name.
+type manifestDiff struct {
+	Added   []string
+	Changed []string
+	Removed []string
+}
+
+func (d manifestDiff) String() string {
+	var details []string
+
+	if len(d.Added) > 0 {
+		details = append(details, fmt.Sprintf("added: %s", joinObjectIDs(d.Added)))
+	}
+	if len(d.Changed) > 0 {
+		details = append(details, fmt.Sprintf("changed: %s", joinObjectIDs(d.Changed)))
+	}
+	if len(d.Removed) > 0 {
+		details = append(details, fmt.Sprintf("removed: %s", joinObjectIDs(d.Removed)))
+	}
+
+	summary := fmt.Sprintf("%d added, %d changed, %d removed", len(d.Added), len(d.Changed), len(d.Removed))
+	if len(details) == 0 {
+		return summary
+	}
+
+	return fmt.Sprintf("%s (%s)", summary, strings.Join(details, "; "))
+}
+
+// joinObjectIDs joins the first maxDiffObjectIDs object IDs and summarizes
+// the remaining ones, so the CR status stays readable for large releases.
+func joinObjectIDs(ids []string) string {
+	if len(ids) <= maxDiffObjectIDs {
+		return strings.Join(ids, ", ")
+	}
+
+	return fmt.Sprintf("%s ... and %d more", strings.Join(ids[:maxDiffObjectIDs], ", "), len(ids)-maxDiffObjectIDs)
+}
+
+// diffManifests compares the objects of the current and the desired release
+// manifests.
+func diffManifests(current, desired string) (manifestDiff, error) {
+	currentObjects, err := parseManifest(current)
+	if err != nil {
+		return manifestDiff{}, microerror.Mask(err)
+	}
+	desiredObjects, err := parseManifest(desired)
+	if err != nil {
+		return manifestDiff{}, microerror.Mask(err)
+	}
+
+	var d manifestDiff
+
+	for id, desiredObject := range desiredObjects {
+		currentObject, ok := currentObjects[id]
+		if !ok {
+			d.Added = append(d.Added, id)
+		} else if !reflect.DeepEqual(currentObject, desiredObject) {
+			d.Changed = append(d.Changed, id)
+		}
+	}
+	for id := range currentObjects {
+		if _, ok := desiredObjects[id]; !ok {
+			d.Removed = append(d.Removed, id)
+		}
+	}
+
+	sort.Strings(d.Added)
+	sort.Strings(d.Changed)
+	sort.Strings(d.Removed)
+
+	return d, nil
+}
+
+// parseManifest splits a release manifest into its objects keyed by their
+// identifier.
+func parseManifest(manifest string) (map[string]map[string]interface{}, error) {
+	objects := map[string]map[string]interface{}{}
+
+	for _, doc := range releaseutil.SplitManifests(manifest) {
+		var object map[string]interface{}
+
+		err := yaml.Unmarshal([]byte(doc), &object)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if len(object) == 0 {
+			continue
+		}
+
+		objects[objectID(object)] = object
+	}
+
+	return objects, nil
+}
+
+// objectID returns the identifier of an object in the format
+// kind/namespace/name or kind/name for cluster scoped objects.
+func objectID(object map[string]interface{}) string {
+	kind, _ := object["kind"].(string)
+
+	var name, namespace string
+	if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+		name, _ = metadata["name"].(string)
+		namespace, _ = metadata["namespace"].(string)
+	}
+
+	if namespace == "" {
+		return fmt.Sprintf("%s/%s", kind, name)
+	}
+
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
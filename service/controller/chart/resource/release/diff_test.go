@@ -0,0 +1,110 @@
+package release
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_diffManifests(t *testing.T) {
+	testCases := []struct {
+		name           string
+		current        string
+		desired        string
+		expectedDiff   manifestDiff
+		expectedString string
+	}{
+		{
+			name: "case 0: equal manifests",
+			current: `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  namespace: default
+data:
+  key: value
+`,
+			desired: `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  namespace: default
+data:
+  key: value
+`,
+			expectedDiff:   manifestDiff{},
+			expectedString: "0 added, 0 changed, 0 removed",
+		},
+		{
+			name: "case 1: added, changed and removed objects",
+			current: `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  namespace: default
+data:
+  key: value
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: old
+  namespace: default
+`,
+			desired: `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  namespace: default
+data:
+  key: new-value
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: new
+`,
+			expectedDiff: manifestDiff{
+				Added:   []string{"ClusterRole/new"},
+				Changed: []string{"ConfigMap/default/test"},
+				Removed: []string{"Secret/default/old"},
+			},
+			expectedString: "1 added, 1 changed, 1 removed (added: ClusterRole/new; changed: ConfigMap/default/test; removed: Secret/default/old)",
+		},
+		{
+			name:    "case 2: nothing deployed",
+			current: "",
+			desired: `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  namespace: default
+`,
+			expectedDiff: manifestDiff{
+				Added: []string{"ConfigMap/default/test"},
+			},
+			expectedString: "1 added, 0 changed, 0 removed (added: ConfigMap/default/test)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := diffManifests(tc.current, tc.desired)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if !cmp.Equal(result, tc.expectedDiff) {
+				t.Fatalf("want matching diff \n %s", cmp.Diff(result, tc.expectedDiff))
+			}
+			if result.String() != tc.expectedString {
+				t.Fatalf("diff string == %#q, want %#q", result.String(), tc.expectedString)
+			}
+		})
+	}
+}
@@ -0,0 +1,405 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/microerror"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
+)
+
+// maxSelfHealAttempts is how many times in a row a release is re-applied
+// to restore drifted objects before self-healing gives up, e.g. because
+// another controller keeps modifying the objects.
+const maxSelfHealAttempts = 3
+
+// driftedObject is an object of the deployed release manifest which was
+// modified or deleted in the cluster.
+type driftedObject struct {
+	ID      string
+	Deleted bool
+}
+
+func (o driftedObject) String() string {
+	if o.Deleted {
+		return fmt.Sprintf("%s (deleted)", o.ID)
+	}
+
+	return fmt.Sprintf("%s (modified)", o.ID)
+}
+
+// isDrifted returns true when objects of the deployed release drifted from
+// its manifest and the release has to be upgraded to restore them. Drift is
+// only checked for chart CRs with the drift-detection or self-heal
+// annotation. Drifted objects of chart CRs without self-healing, or with
+// self-healing which did not restore them within maxSelfHealAttempts, are
+// added to the controller context so the status resource reports them.
+func (r *Resource) isDrifted(ctx context.Context, cr v1alpha1.Chart, cc *controllercontext.Context) (bool, error) {
+	if !key.HasDriftDetectionAnnotation(cr) && !key.HasSelfHealAnnotation(cr) {
+		r.releaseMetrics.DeleteDriftedObjects(cr.Name, cr.Namespace)
+		return false, nil
+	}
+
+	r.logger.Debugf(ctx, "checking release %#q for drift", key.ReleaseName(cr))
+
+	manifest, err := r.deployedManifest(ctx, cr)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	drifted, err := r.driftedObjects(ctx, cr, manifest)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	r.releaseMetrics.SetDriftedObjects(cr.Name, cr.Namespace, len(drifted))
+
+	if len(drifted) == 0 {
+		r.logger.Debugf(ctx, "release %#q has not drifted", key.ReleaseName(cr))
+
+		err = r.removeAnnotation(ctx, cr, annotation.SelfHealAttempts)
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+
+		return false, nil
+	}
+
+	objects := make([]string, 0, len(drifted))
+	for _, o := range drifted {
+		objects = append(objects, o.String())
+	}
+
+	if !key.HasSelfHealAnnotation(cr) {
+		reason := fmt.Sprintf("drifted objects: %s", strings.Join(objects, ", "))
+		addStatusToContext(cc, reason, releasestatus.Drifted)
+
+		r.logger.Debugf(ctx, "release %#q has %d drifted objects", key.ReleaseName(cr), len(drifted))
+		return false, nil
+	}
+
+	if attempts := key.SelfHealAttempts(cr); attempts >= maxSelfHealAttempts {
+		reason := fmt.Sprintf("drifted objects not restored after %d self-heal attempts: %s", attempts, strings.Join(objects, ", "))
+		addStatusToContext(cc, reason, releasestatus.Drifted)
+
+		r.logger.Debugf(ctx, "release %#q has %d drifted objects, not self-healing after %d attempts", key.ReleaseName(cr), len(drifted), attempts)
+		return false, nil
+	}
+
+	r.event.Eventf(&cr, corev1.EventTypeWarning, driftDetectedEventReason, "re-applying release %#q to restore drifted objects: %s", key.ReleaseName(cr), strings.Join(objects, ", "))
+
+	return true, nil
+}
+
+// addSelfHealAttempt counts the re-apply of the release to restore drifted
+// objects in the self-heal-attempts annotation of the chart CR. The count is
+// removed once the release has not drifted.
+func (r *Resource) addSelfHealAttempt(ctx context.Context, cr v1alpha1.Chart) error {
+	attempts := key.SelfHealAttempts(cr) + 1
+
+	err := r.addAnnotation(ctx, cr, annotation.SelfHealAttempts, strconv.Itoa(attempts))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// driftedObjects compares the objects of the given release manifest with the
+// live objects in the cluster. Only fields set in the manifest are compared,
+// so defaults and fields managed by other controllers are not reported as
+// drift. The replicas of objects scaled by a HorizontalPodAutoscaler are not
+// compared.
+func (r *Resource) driftedObjects(ctx context.Context, cr v1alpha1.Chart, manifest string) ([]driftedObject, error) {
+	objects, err := parseManifest(manifest)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var drifted []driftedObject
+
+	// autoscaled holds the IDs of the objects scaled by autoscalers by
+	// namespace. Autoscalers are only listed in namespaces of the manifest.
+	autoscaled := map[string]map[string]bool{}
+
+	for _, object := range objects {
+		desired := &unstructured.Unstructured{Object: object}
+
+		if desired.GetNamespace() == "" {
+			namespaced, err := r.ctrlClient.IsObjectNamespaced(desired)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			if namespaced {
+				desired.SetNamespace(key.Namespace(cr))
+			}
+		}
+
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(desired.GroupVersionKind())
+
+		err = r.ctrlClient.Get(ctx, client.ObjectKeyFromObject(desired), live)
+		if apierrors.IsNotFound(err) {
+			drifted = append(drifted, driftedObject{ID: objectID(desired.Object), Deleted: true})
+			continue
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		if _, ok := autoscaled[desired.GetNamespace()]; !ok {
+			autoscaled[desired.GetNamespace()], err = r.autoscaledObjects(ctx, desired.GetNamespace())
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+		if autoscaled[desired.GetNamespace()][objectID(desired.Object)] {
+			unstructured.RemoveNestedField(desired.Object, "spec", "replicas")
+		}
+
+		if !containsFields(live.Object, comparableFields(desired)) {
+			drifted = append(drifted, driftedObject{ID: objectID(desired.Object)})
+		}
+	}
+
+	sort.Slice(drifted, func(i, j int) bool {
+		return drifted[i].ID < drifted[j].ID
+	})
+
+	return drifted, nil
+}
+
+// autoscaledObjects returns the IDs of the objects scaled by the
+// HorizontalPodAutoscalers in the given namespace.
+func (r *Resource) autoscaledObjects(ctx context.Context, namespace string) (map[string]bool, error) {
+	if namespace == "" {
+		return nil, nil
+	}
+
+	var hpas autoscalingv2.HorizontalPodAutoscalerList
+
+	err := r.ctrlClient.List(ctx, &hpas, client.InNamespace(namespace))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	autoscaled := map[string]bool{}
+	for _, hpa := range hpas.Items {
+		ref := hpa.Spec.ScaleTargetRef
+		autoscaled[fmt.Sprintf("%s/%s/%s", ref.Kind, namespace, ref.Name)] = true
+	}
+
+	return autoscaled, nil
+}
+
+// comparableFields returns the fields of the desired object which are
+// compared with the live object. The status, metadata other than labels and
+// annotations, and the write-only stringData of secrets are left out.
+func comparableFields(desired *unstructured.Unstructured) map[string]interface{} {
+	fields := map[string]interface{}{}
+
+	for k, v := range desired.Object {
+		switch k {
+		case "metadata", "status":
+			continue
+		case "stringData":
+			if desired.GetKind() == "Secret" {
+				continue
+			}
+		}
+
+		fields[k] = v
+	}
+
+	metadata := map[string]interface{}{}
+	for _, k := range []string{"annotations", "labels"} {
+		v, ok, _ := unstructured.NestedFieldNoCopy(desired.Object, "metadata", k)
+		if ok {
+			metadata[k] = v
+		}
+	}
+	fields["metadata"] = metadata
+
+	return fields
+}
+
+// containsFields returns true when the live value contains the desired
+// value. Maps may have additional keys and quantities and numbers are
+// compared by value. Items of lists of named objects, e.g. containers,
+// volumes or env vars, are matched by name so items added by other
+// controllers like injected sidecars are ignored. Other lists must have the
+// same length. Missing live fields match desired zero values since the API
+// server drops them.
+func containsFields(live, desired interface{}) bool {
+	switch d := desired.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return live == nil && len(d) == 0
+		}
+
+		for k, v := range d {
+			lv, ok := l[k]
+			if !ok {
+				if !isZero(v) {
+					return false
+				}
+				continue
+			}
+			if !containsFields(lv, v) {
+				return false
+			}
+		}
+
+		return true
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok {
+			return live == nil && len(d) == 0
+		}
+		if isNamedList(d) {
+			return containsNamedItems(l, d)
+		}
+		if len(l) != len(d) {
+			return false
+		}
+
+		for i := range d {
+			if !containsFields(l[i], d[i]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if reflect.DeepEqual(live, desired) {
+		return true
+	}
+
+	return equalScalars(live, desired)
+}
+
+// isNamedList returns true when all items of the list are objects with a
+// name.
+func isNamedList(list []interface{}) bool {
+	if len(list) == 0 {
+		return false
+	}
+
+	for _, item := range list {
+		if _, ok := itemName(item); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// containsNamedItems returns true when every desired item is contained in a
+// live item with the same name. Live items without a desired item of the
+// same name are ignored.
+func containsNamedItems(live, desired []interface{}) bool {
+	for _, d := range desired {
+		name, _ := itemName(d)
+
+		found := false
+		for _, l := range live {
+			if n, ok := itemName(l); ok && n == name && containsFields(l, d) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func itemName(item interface{}) (string, bool) {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+
+	name, ok := m["name"].(string)
+
+	return name, ok && name != ""
+}
+
+// equalScalars compares numbers by value and strings as quantities, e.g.
+// `0.5` and `500m`.
+func equalScalars(live, desired interface{}) bool {
+	ls, lok := scalarString(live)
+	ds, dok := scalarString(desired)
+	if !lok || !dok {
+		return false
+	}
+	if ls == ds {
+		return true
+	}
+
+	lq, err := resource.ParseQuantity(ls)
+	if err != nil {
+		return false
+	}
+	dq, err := resource.ParseQuantity(ds)
+	if err != nil {
+		return false
+	}
+
+	return lq.Cmp(dq) == 0
+}
+
+func scalarString(v interface{}) (string, bool) {
+	switch s := v.(type) {
+	case string:
+		return s, true
+	case int64:
+		return strconv.FormatInt(s, 10), true
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64), true
+	}
+
+	return "", false
+}
+
+func isZero(v interface{}) bool {
+	switch z := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(z) == 0
+	case []interface{}:
+		return len(z) == 0
+	case string:
+		return z == ""
+	case bool:
+		return !z
+	case int64:
+		return z == 0
+	case float64:
+		return z == 0
+	}
+
+	return false
+}
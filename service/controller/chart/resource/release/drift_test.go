@@ -0,0 +1,391 @@
+package release
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta/testrestmapper"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
+	"github.com/giantswarm/chart-operator/v4/service/internal/releasemetrics"
+)
+
+const driftManifest = `---
+# Source: hello-world/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: hello-world
+  labels:
+    app: hello-world
+data:
+  greeting: hello
+---
+# Source: hello-world/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: hello-world
+spec:
+  replicas: 2
+  template:
+    spec:
+      hostNetwork: false
+      containers:
+      - name: hello-world
+        image: hello-world:1.0.0
+        resources:
+          requests:
+            cpu: 0.5
+`
+
+func Test_driftedObjects(t *testing.T) {
+	newConfigMap := func(greeting string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "hello-world",
+				Namespace: "giantswarm",
+				Labels: map[string]string{
+					"app":                          "hello-world",
+					"app.kubernetes.io/managed-by": "Helm",
+				},
+			},
+			Data: map[string]string{
+				"greeting": greeting,
+			},
+		}
+	}
+	newDeployment := func(replicas int32, image string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "hello-world",
+				Namespace: "giantswarm",
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:            "hello-world",
+								Image:           image,
+								ImagePullPolicy: corev1.PullIfNotPresent,
+								Resources: corev1.ResourceRequirements{
+									Requests: corev1.ResourceList{
+										corev1.ResourceCPU: resource.MustParse("500m"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	withSidecar := func(d *appsv1.Deployment) *appsv1.Deployment {
+		d.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{
+			{
+				Name:  "PROXY",
+				Value: "localhost:15001",
+			},
+		}
+		d.Spec.Template.Spec.Containers = append([]corev1.Container{
+			{
+				Name:  "istio-proxy",
+				Image: "istio/proxyv2:1.20.0",
+			},
+		}, d.Spec.Template.Spec.Containers...)
+		d.Spec.Template.Spec.Volumes = []corev1.Volume{
+			{
+				Name: "istio-envoy",
+			},
+		}
+
+		return d
+	}
+	newHPA := func(target string) *autoscalingv2.HorizontalPodAutoscaler {
+		return &autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "hello-world",
+				Namespace: "giantswarm",
+			},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+					APIVersion: "apps/v1",
+					Kind:       "Deployment",
+					Name:       target,
+				},
+				MaxReplicas: 5,
+			},
+		}
+	}
+
+	testCases := []struct {
+		name            string
+		objects         []client.Object
+		expectedDrifted []driftedObject
+	}{
+		{
+			name: "case 0: no drift",
+			objects: []client.Object{
+				newConfigMap("hello"),
+				newDeployment(2, "hello-world:1.0.0"),
+			},
+		},
+		{
+			name: "case 1: modified config map",
+			objects: []client.Object{
+				newConfigMap("goodbye"),
+				newDeployment(2, "hello-world:1.0.0"),
+			},
+			expectedDrifted: []driftedObject{
+				{ID: "ConfigMap/giantswarm/hello-world"},
+			},
+		},
+		{
+			name: "case 2: scaled deployment and deleted config map",
+			objects: []client.Object{
+				newDeployment(0, "hello-world:1.0.0"),
+			},
+			expectedDrifted: []driftedObject{
+				{ID: "ConfigMap/giantswarm/hello-world", Deleted: true},
+				{ID: "Deployment/giantswarm/hello-world"},
+			},
+		},
+		{
+			name: "case 3: changed image",
+			objects: []client.Object{
+				newConfigMap("hello"),
+				newDeployment(2, "hello-world:1.1.0"),
+			},
+			expectedDrifted: []driftedObject{
+				{ID: "Deployment/giantswarm/hello-world"},
+			},
+		},
+		{
+			name: "case 4: injected sidecar, volume and env var",
+			objects: []client.Object{
+				newConfigMap("hello"),
+				withSidecar(newDeployment(2, "hello-world:1.0.0")),
+			},
+		},
+		{
+			name: "case 5: changed image with injected sidecar",
+			objects: []client.Object{
+				newConfigMap("hello"),
+				withSidecar(newDeployment(2, "hello-world:1.1.0")),
+			},
+			expectedDrifted: []driftedObject{
+				{ID: "Deployment/giantswarm/hello-world"},
+			},
+		},
+		{
+			name: "case 6: deployment scaled by autoscaler",
+			objects: []client.Object{
+				newConfigMap("hello"),
+				newDeployment(4, "hello-world:1.0.0"),
+				newHPA("hello-world"),
+			},
+		},
+		{
+			name: "case 7: scaled deployment with autoscaler of other deployment",
+			objects: []client.Object{
+				newConfigMap("hello"),
+				newDeployment(4, "hello-world:1.0.0"),
+				newHPA("goodbye-world"),
+			},
+			expectedDrifted: []driftedObject{
+				{ID: "Deployment/giantswarm/hello-world"},
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			r := &Resource{
+				ctrlClient: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithRESTMapper(testrestmapper.TestOnlyStaticRESTMapper(scheme.Scheme)).
+					WithObjects(tc.objects...).
+					Build(),
+				logger: microloggertest.New(),
+			}
+
+			cr := v1alpha1.Chart{
+				Spec: v1alpha1.ChartSpec{
+					Name:      "hello-world",
+					Namespace: "giantswarm",
+				},
+			}
+
+			drifted, err := r.driftedObjects(context.Background(), cr, driftManifest)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if !reflect.DeepEqual(drifted, tc.expectedDrifted) {
+				t.Fatalf("drifted == %v, want %v", drifted, tc.expectedDrifted)
+			}
+		})
+	}
+}
+
+func Test_isDrifted_selfHealAttempts(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		attempts              string
+		modified              bool
+		expectedDrifted       bool
+		expectedAttempts      string
+		expectedReleaseStatus string
+	}{
+		{
+			name:            "case 0: drifted release is self-healed",
+			modified:        true,
+			expectedDrifted: true,
+		},
+		{
+			name:             "case 1: drifted release is self-healed again",
+			attempts:         "2",
+			modified:         true,
+			expectedDrifted:  true,
+			expectedAttempts: "2",
+		},
+		{
+			name:                  "case 2: self-healing gives up after max attempts",
+			attempts:              "3",
+			modified:              true,
+			expectedAttempts:      "3",
+			expectedReleaseStatus: releasestatus.Drifted,
+		},
+		{
+			name:     "case 3: attempts are reset once the release has not drifted",
+			attempts: "3",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			ctx := context.Background()
+
+			cr := &v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "hello-world",
+					Namespace: "giantswarm",
+					Annotations: map[string]string{
+						annotation.SelfHeal: "true",
+					},
+				},
+				Spec: v1alpha1.ChartSpec{
+					Name:      "hello-world",
+					Namespace: "giantswarm",
+				},
+			}
+			if tc.attempts != "" {
+				cr.Annotations[annotation.SelfHealAttempts] = tc.attempts
+			}
+
+			greeting := "hello"
+			if tc.modified {
+				greeting = "goodbye"
+			}
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "hello-world",
+					Namespace: "giantswarm",
+				},
+				Data: map[string]string{
+					"greeting": greeting,
+				},
+			}
+
+			s := runtime.NewScheme()
+			err := scheme.AddToScheme(s)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			err = v1alpha1.AddToScheme(s)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			ctrlClient := fake.NewClientBuilder().
+				WithScheme(s).
+				WithRESTMapper(testrestmapper.TestOnlyStaticRESTMapper(s)).
+				WithObjects(cr, cm).
+				Build()
+
+			k8sClient := k8sfake.NewClientset()
+			err = storage.Init(driver.NewSecrets(k8sClient.CoreV1().Secrets("giantswarm"))).Create(&release.Release{
+				Name:      "hello-world",
+				Namespace: "giantswarm",
+				Version:   1,
+				Info: &release.Info{
+					Status: release.StatusDeployed,
+				},
+				Manifest: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: hello-world\ndata:\n  greeting: hello\n",
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			r := &Resource{
+				ctrlClient:     ctrlClient,
+				event:          record.NewFakeRecorder(10),
+				k8sClient:      k8sClient,
+				logger:         microloggertest.New(),
+				releaseMetrics: releasemetrics.New(),
+			}
+
+			cc := &controllercontext.Context{}
+
+			drifted, err := r.isDrifted(ctx, *cr, cc)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if drifted != tc.expectedDrifted {
+				t.Fatalf("drifted == %t, want %t", drifted, tc.expectedDrifted)
+			}
+			if cc.Status.Release.Status != tc.expectedReleaseStatus {
+				t.Fatalf("status == %#q, want %#q", cc.Status.Release.Status, tc.expectedReleaseStatus)
+			}
+
+			var current v1alpha1.Chart
+
+			err = ctrlClient.Get(ctx, client.ObjectKeyFromObject(cr), &current)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if current.Annotations[annotation.SelfHealAttempts] != tc.expectedAttempts {
+				t.Fatalf("attempts == %#q, want %#q", current.Annotations[annotation.SelfHealAttempts], tc.expectedAttempts)
+			}
+		})
+	}
+}
@@ -37,3 +37,102 @@ var wrongTypeError = &microerror.Error{
 func IsWrongType(err error) bool {
 	return microerror.Cause(err) == wrongTypeError
 }
+
+var invalidValuesSourcesError = &microerror.Error{
+	Kind: "invalidValuesSourcesError",
+}
+
+// IsInvalidValuesSources asserts invalidValuesSourcesError.
+func IsInvalidValuesSources(err error) bool {
+	return microerror.Cause(err) == invalidValuesSourcesError
+}
+
+var valuesSourceNotFoundError = &microerror.Error{
+	Kind: "valuesSourceNotFoundError",
+}
+
+// IsValuesSourceNotFound asserts valuesSourceNotFoundError.
+func IsValuesSourceNotFound(err error) bool {
+	return microerror.Cause(err) == valuesSourceNotFoundError
+}
+
+var ociPullFailedError = &microerror.Error{
+	Kind: "ociPullFailedError",
+}
+
+// IsOCIPullFailed asserts ociPullFailedError.
+func IsOCIPullFailed(err error) bool {
+	return microerror.Cause(err) == ociPullFailedError
+}
+
+var provenancePullFailedError = &microerror.Error{
+	Kind: "provenancePullFailedError",
+}
+
+// IsProvenancePullFailed asserts provenancePullFailedError.
+func IsProvenancePullFailed(err error) bool {
+	return microerror.Cause(err) == provenancePullFailedError
+}
+
+var crdNotEstablishedError = &microerror.Error{
+	Kind: "crdNotEstablishedError",
+}
+
+// IsCRDNotEstablished asserts crdNotEstablishedError.
+func IsCRDNotEstablished(err error) bool {
+	return microerror.Cause(err) == crdNotEstablishedError
+}
+
+var crdStoredVersionRemovedError = &microerror.Error{
+	Kind: "crdStoredVersionRemovedError",
+}
+
+// IsCRDStoredVersionRemoved asserts crdStoredVersionRemovedError.
+func IsCRDStoredVersionRemoved(err error) bool {
+	return microerror.Cause(err) == crdStoredVersionRemovedError
+}
+
+var invalidCRDPolicyError = &microerror.Error{
+	Kind: "invalidCRDPolicyError",
+}
+
+// IsInvalidCRDPolicy asserts invalidCRDPolicyError.
+func IsInvalidCRDPolicy(err error) bool {
+	return microerror.Cause(err) == invalidCRDPolicyError
+}
+
+var invalidDependenciesError = &microerror.Error{
+	Kind: "invalidDependenciesError",
+}
+
+// IsInvalidDependencies asserts invalidDependenciesError.
+func IsInvalidDependencies(err error) bool {
+	return microerror.Cause(err) == invalidDependenciesError
+}
+
+var invalidMaintenanceWindowError = &microerror.Error{
+	Kind: "invalidMaintenanceWindowError",
+}
+
+// IsInvalidMaintenanceWindow asserts invalidMaintenanceWindowError.
+func IsInvalidMaintenanceWindow(err error) bool {
+	return microerror.Cause(err) == invalidMaintenanceWindowError
+}
+
+var postRenderFailedError = &microerror.Error{
+	Kind: "postRenderFailedError",
+}
+
+// IsPostRenderFailed asserts postRenderFailedError.
+func IsPostRenderFailed(err error) bool {
+	return microerror.Cause(err) == postRenderFailedError
+}
+
+var helmV2MigrationFailedError = &microerror.Error{
+	Kind: "helmV2MigrationFailedError",
+}
+
+// IsHelmV2MigrationFailed asserts helmV2MigrationFailedError.
+func IsHelmV2MigrationFailed(err error) bool {
+	return microerror.Cause(err) == helmV2MigrationFailedError
+}
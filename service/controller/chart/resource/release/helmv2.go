@@ -0,0 +1,90 @@
+package release
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/label"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+	"github.com/giantswarm/chart-operator/v4/service/internal/helmv2"
+)
+
+// findHelmV2ConfigMaps returns the Tiller config maps of the Helm 2 release
+// which have not been archived after migrating them to Helm 3.
+func (r *Resource) findHelmV2ConfigMaps(ctx context.Context, releaseName string) ([]corev1.ConfigMap, error) {
+	lo := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s,%s=%s,!%s", "NAME", releaseName, "OWNER", "TILLER", label.HelmV2Archived),
+	}
+
+	// Check whether there are still helm2 release configmaps.
+	cms, err := r.k8sClient.CoreV1().ConfigMaps(r.tillerNamespace).List(ctx, lo)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return cms.Items, nil
+}
+
+// migrateHelmV2Release converts the Tiller config maps of the Helm 2 release
+// into Helm 3 release secrets in the namespace of the chart CR and archives
+// the config maps once all revisions are stored. Revisions stored by an
+// earlier attempt are skipped so failed migrations are resumed. It returns
+// the number of migrated revisions.
+func (r *Resource) migrateHelmV2Release(ctx context.Context, cr v1alpha1.Chart, cms []corev1.ConfigMap) (int, error) {
+	versions := map[string]int{}
+	for _, cm := range cms {
+		v, err := helmv2.Version(cm)
+		if err != nil {
+			return 0, microerror.Maskf(helmV2MigrationFailedError, "%s", err.Error())
+		}
+		versions[cm.Name] = v
+	}
+
+	sort.Slice(cms, func(i, j int) bool {
+		return versions[cms[i].Name] < versions[cms[j].Name]
+	})
+
+	s := driver.NewSecrets(r.k8sClient.CoreV1().Secrets(key.Namespace(cr)))
+	store := storage.Init(s)
+
+	var migrated int
+	for _, cm := range cms {
+		rel, err := helmv2.ConvertConfigMap(cm)
+		if err != nil {
+			return migrated, microerror.Maskf(helmV2MigrationFailedError, "revision %d: %s", versions[cm.Name], err.Error())
+		}
+		if rel.Namespace != key.Namespace(cr) {
+			return migrated, microerror.Maskf(helmV2MigrationFailedError, "revision %d is installed in namespace %#q instead of %#q", rel.Version, rel.Namespace, key.Namespace(cr))
+		}
+
+		err = store.Create(rel)
+		if errors.Is(err, driver.ErrReleaseExists) {
+			r.logger.Debugf(ctx, "revision %d of release %#q has already been migrated from helm 2", rel.Version, rel.Name)
+		} else if err != nil {
+			return migrated, microerror.Maskf(helmV2MigrationFailedError, "revision %d: %s", rel.Version, err.Error())
+		}
+
+		migrated++
+	}
+
+	for _, cm := range cms {
+		patch := []byte(fmt.Sprintf(`{"metadata":{"labels":{%q:"true"}}}`, label.HelmV2Archived))
+
+		_, err := r.k8sClient.CoreV1().ConfigMaps(cm.Namespace).Patch(ctx, cm.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		if err != nil {
+			return migrated, microerror.Maskf(helmV2MigrationFailedError, "archiving config map %#q: %s", cm.Name, err.Error())
+		}
+	}
+
+	return migrated, nil
+}
@@ -0,0 +1,196 @@
+package release
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"google.golang.org/protobuf/encoding/protowire"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/label"
+)
+
+// newHelmV2ConfigMap returns a Tiller config map of a Helm 2 release with
+// the minimal fields of its protobuf encoded release.
+func newHelmV2ConfigMap(name, namespace string, version int) *corev1.ConfigMap {
+	appendString := func(b []byte, num protowire.Number, s string) []byte {
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendString(b, s)
+	}
+	appendBytes := func(b []byte, num protowire.Number, v []byte) []byte {
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, v)
+	}
+	appendVarint := func(b []byte, num protowire.Number, v uint64) []byte {
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, v)
+	}
+
+	metadata := appendString(nil, 1, name)
+	metadata = appendString(metadata, 4, "1.0.0")
+
+	status := appendVarint(nil, 1, 1)
+
+	rel := appendString(nil, 1, name)
+	rel = appendBytes(rel, 2, appendBytes(nil, 1, status))
+	rel = appendBytes(rel, 3, appendBytes(nil, 1, metadata))
+	rel = appendVarint(rel, 7, uint64(version))
+	rel = appendString(rel, 8, namespace)
+
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.v%d", name, version),
+			Namespace: "kube-system",
+			Labels: map[string]string{
+				"NAME":    name,
+				"OWNER":   "TILLER",
+				"VERSION": strconv.Itoa(version),
+			},
+		},
+		Data: map[string]string{
+			"release": base64.StdEncoding.EncodeToString(rel),
+		},
+	}
+}
+
+func Test_migrateHelmV2Release(t *testing.T) {
+	ctx := context.Background()
+
+	cr := v1alpha1.Chart{
+		Spec: v1alpha1.ChartSpec{
+			Name:      "prometheus",
+			Namespace: "monitoring",
+		},
+	}
+
+	testCases := []struct {
+		name             string
+		objects          []runtime.Object
+		expectedMigrated int
+		expectedArchived bool
+		errorMatcher     func(error) bool
+	}{
+		{
+			name: "case 0: all revisions are migrated",
+			objects: []runtime.Object{
+				newHelmV2ConfigMap("prometheus", "monitoring", 2),
+				newHelmV2ConfigMap("prometheus", "monitoring", 1),
+			},
+			expectedMigrated: 2,
+			expectedArchived: true,
+		},
+		{
+			name: "case 1: revision migrated by an earlier attempt is skipped",
+			objects: []runtime.Object{
+				newHelmV2ConfigMap("prometheus", "monitoring", 1),
+				newHelmV2ConfigMap("prometheus", "monitoring", 2),
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "sh.helm.release.v1.prometheus.v1",
+						Namespace: "monitoring",
+						Labels: map[string]string{
+							"name":  "prometheus",
+							"owner": "helm",
+						},
+					},
+				},
+			},
+			expectedMigrated: 2,
+			expectedArchived: true,
+		},
+		{
+			name: "case 2: release is installed in another namespace",
+			objects: []runtime.Object{
+				newHelmV2ConfigMap("prometheus", "default", 1),
+			},
+			expectedMigrated: 0,
+			errorMatcher:     IsHelmV2MigrationFailed,
+		},
+		{
+			name: "case 3: revision cannot be decoded",
+			objects: []runtime.Object{
+				newHelmV2ConfigMap("prometheus", "monitoring", 1),
+				func() runtime.Object {
+					cm := newHelmV2ConfigMap("prometheus", "monitoring", 2)
+					cm.Data["release"] = "invalid"
+					return cm
+				}(),
+			},
+			expectedMigrated: 1,
+			errorMatcher:     IsHelmV2MigrationFailed,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			k8sClient := k8sfake.NewClientset(tc.objects...)
+
+			r := &Resource{
+				k8sClient: k8sClient,
+				logger:    microloggertest.New(),
+
+				tillerNamespace: "kube-system",
+			}
+
+			cms, err := r.findHelmV2ConfigMaps(ctx, "prometheus")
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			migrated, err := r.migrateHelmV2Release(ctx, cr, cms)
+			switch {
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case err != nil && !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if migrated != tc.expectedMigrated {
+				t.Fatalf("migrated == %d, want %d", migrated, tc.expectedMigrated)
+			}
+
+			secrets, err := k8sClient.CoreV1().Secrets("monitoring").List(ctx, metav1.ListOptions{
+				LabelSelector: "owner=helm,name=prometheus",
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if len(secrets.Items) != tc.expectedMigrated {
+				t.Fatalf("len(secrets) == %d, want %d", len(secrets.Items), tc.expectedMigrated)
+			}
+
+			cms, err = r.findHelmV2ConfigMaps(ctx, "prometheus")
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if tc.expectedArchived && len(cms) != 0 {
+				t.Fatalf("len(config maps) == %d, want 0", len(cms))
+			}
+			if !tc.expectedArchived && len(cms) != len(tc.objects) {
+				t.Fatalf("len(config maps) == %d, want %d", len(cms), len(tc.objects))
+			}
+
+			archived, err := k8sClient.CoreV1().ConfigMaps("kube-system").List(ctx, metav1.ListOptions{
+				LabelSelector: label.HelmV2Archived,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if tc.expectedArchived && len(archived.Items) != 2 {
+				t.Fatalf("len(archived config maps) == %d, want 2", len(archived.Items))
+			}
+		})
+	}
+}
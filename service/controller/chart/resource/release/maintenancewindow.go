@@ -0,0 +1,76 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
+	"github.com/giantswarm/chart-operator/v4/service/internal/maintenancewindow"
+)
+
+// isOutsideMaintenanceWindow returns true when the upgrade of the chart CR
+// has to be deferred because its maintenance window is closed. The time the
+// window opens next is added to the controller context so the status
+// resource reports it. Chart CRs with invalid maintenance window annotations
+// are not upgraded until the annotations are fixed.
+func (r *Resource) isOutsideMaintenanceWindow(ctx context.Context, cr v1alpha1.Chart, cc *controllercontext.Context, now time.Time) bool {
+	if key.HasIgnoreMaintenanceWindowAnnotation(cr) {
+		r.logger.Debugf(ctx, "ignoring maintenance window of release %#q", key.ReleaseName(cr))
+		return false
+	}
+
+	window, err := r.chartMaintenanceWindow(cr)
+	if err != nil {
+		reason := fmt.Sprintf("maintenance window of release %#q is invalid: %s", key.ReleaseName(cr), err.Error())
+		addStatusToContext(cc, reason, releasestatus.InvalidMaintenanceWindow)
+		r.event.Event(&cr, corev1.EventTypeWarning, invalidMaintenanceWindowEventReason, reason)
+
+		r.logger.LogCtx(ctx, "level", "warning", "message", reason, "stack", microerror.JSON(err))
+		return true
+	}
+
+	if window.Contains(now) {
+		return false
+	}
+
+	reason := fmt.Sprintf("upgrade pending until %s", window.Next(now).Format(time.RFC3339))
+	addStatusToContext(cc, reason, releasestatus.UpgradePending)
+
+	r.logger.Debugf(ctx, "release %#q is outside its maintenance window, %s", key.ReleaseName(cr), reason)
+
+	return true
+}
+
+// chartMaintenanceWindow returns the maintenance window of the chart CR. The
+// maintenance window annotations override the configured window.
+func (r *Resource) chartMaintenanceWindow(cr v1alpha1.Chart) (maintenancewindow.Window, error) {
+	schedule := key.MaintenanceWindowAnnotation(cr)
+	if schedule == "" {
+		return r.maintenanceWindow, nil
+	}
+
+	v := key.MaintenanceWindowDurationAnnotation(cr)
+	if v == "" {
+		return maintenancewindow.Window{}, microerror.Maskf(invalidMaintenanceWindowError, "annotation %#q must be set", annotation.MaintenanceWindowDuration)
+	}
+
+	duration, err := time.ParseDuration(v)
+	if err != nil {
+		return maintenancewindow.Window{}, microerror.Maskf(invalidMaintenanceWindowError, "annotation %#q must be a duration, got %#q", annotation.MaintenanceWindowDuration, v)
+	}
+
+	window, err := maintenancewindow.New(schedule, duration)
+	if err != nil {
+		return maintenancewindow.Window{}, microerror.Mask(err)
+	}
+
+	return window, nil
+}
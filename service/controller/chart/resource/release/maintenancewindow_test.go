@@ -0,0 +1,127 @@
+package release
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/micrologger/microloggertest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
+
+	"github.com/giantswarm/chart-operator/v4/service/internal/maintenancewindow"
+)
+
+func Test_isOutsideMaintenanceWindow(t *testing.T) {
+	// Tuesday 12:00 UTC.
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	nightly, err := maintenancewindow.New("0 22 * * *", 4*time.Hour)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	testCases := []struct {
+		name            string
+		annotations     map[string]string
+		window          maintenancewindow.Window
+		expectedOutside bool
+		expectedStatus  string
+		expectedReason  string
+	}{
+		{
+			name: "case 0: no maintenance window",
+		},
+		{
+			name:            "case 1: outside configured window",
+			window:          nightly,
+			expectedOutside: true,
+			expectedStatus:  releasestatus.UpgradePending,
+			expectedReason:  "upgrade pending until 2026-03-10T22:00:00Z",
+		},
+		{
+			name: "case 2: within window of chart CR",
+			annotations: map[string]string{
+				annotation.MaintenanceWindow:         "0 11 * * 2",
+				annotation.MaintenanceWindowDuration: "2h",
+			},
+			window: nightly,
+		},
+		{
+			name: "case 3: outside window of chart CR",
+			annotations: map[string]string{
+				annotation.MaintenanceWindow:         "0 2 * * 6",
+				annotation.MaintenanceWindowDuration: "2h",
+			},
+			expectedOutside: true,
+			expectedStatus:  releasestatus.UpgradePending,
+			expectedReason:  "upgrade pending until 2026-03-14T02:00:00Z",
+		},
+		{
+			name: "case 4: outside window with ignore annotation",
+			annotations: map[string]string{
+				annotation.IgnoreMaintenanceWindow: "true",
+			},
+			window: nightly,
+		},
+		{
+			name: "case 5: missing duration annotation",
+			annotations: map[string]string{
+				annotation.MaintenanceWindow: "0 2 * * 6",
+			},
+			expectedOutside: true,
+			expectedStatus:  releasestatus.InvalidMaintenanceWindow,
+		},
+		{
+			name: "case 6: invalid schedule annotation",
+			annotations: map[string]string{
+				annotation.MaintenanceWindow:         "at night",
+				annotation.MaintenanceWindowDuration: "2h",
+			},
+			expectedOutside: true,
+			expectedStatus:  releasestatus.InvalidMaintenanceWindow,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			ctx := controllercontext.NewContext(context.Background(), controllercontext.Context{})
+
+			cc, err := controllercontext.FromContext(ctx)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			r := &Resource{
+				event:             &record.FakeRecorder{},
+				logger:            microloggertest.New(),
+				maintenanceWindow: tc.window,
+			}
+
+			cr := v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: tc.annotations,
+				},
+			}
+
+			outside := r.isOutsideMaintenanceWindow(ctx, cr, cc, now)
+			if outside != tc.expectedOutside {
+				t.Fatalf("outside == %t, want %t", outside, tc.expectedOutside)
+			}
+			if cc.Status.Release.Status != tc.expectedStatus {
+				t.Fatalf("status == %#q, want %#q", cc.Status.Release.Status, tc.expectedStatus)
+			}
+			if tc.expectedReason != "" && cc.Status.Reason != tc.expectedReason {
+				t.Fatalf("reason == %#q, want %#q", cc.Status.Reason, tc.expectedReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package release
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
+)
+
+const (
+	deleteOperation   = "delete"
+	installOperation  = "install"
+	pullOperation     = "pull"
+	rollbackOperation = "rollback"
+	upgradeOperation  = "upgrade"
+)
+
+const (
+	alreadyExistsOutcome    = "already-exists"
+	pullFailedOutcome       = "pull-failed"
+	schemaViolationOutcome  = "schema-violation"
+	successOutcome          = "success"
+	timeoutOutcome          = "timeout"
+	unknownOutcome          = "unknown"
+	validationFailedOutcome = "validation-failed"
+)
+
+// observeOperation records the duration and outcome of the operation of the
+// chart CR started at the given time.
+func (r *Resource) observeOperation(cr v1alpha1.Chart, operation string, start time.Time, err error) {
+	r.releaseMetrics.ObserveOperation(cr.Name, cr.Namespace, operation, operationOutcome(err), time.Since(start))
+}
+
+// operationOutcome returns the outcome label of the operation error.
+func operationOutcome(err error) string {
+	switch {
+	case err == nil:
+		return successOutcome
+	case isTimeoutError(err):
+		return timeoutOutcome
+	case IsOCIPullFailed(err) || helmclient.IsPullChartFailedError(err) || helmclient.IsPullChartNotFound(err):
+		return pullFailedOutcome
+	case helmclient.IsValidationFailedError(err):
+		return validationFailedOutcome
+	case isSchemaValidationError(err):
+		return schemaViolationOutcome
+	case helmclient.IsResourceAlreadyExists(err) || helmclient.IsReleaseAlreadyExists(err):
+		return alreadyExistsOutcome
+	}
+
+	return unknownOutcome
+}
+
+// isTimeoutError returns true for chart pull timeouts and Helm operations
+// which timed out waiting for resources.
+func isTimeoutError(err error) bool {
+	if helmclient.IsPullChartTimeout(err) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	return strings.Contains(err.Error(), "timed out waiting for the condition") ||
+		strings.Contains(err.Error(), context.DeadlineExceeded.Error())
+}
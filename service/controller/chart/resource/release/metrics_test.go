@@ -0,0 +1,70 @@
+package release
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/giantswarm/microerror"
+)
+
+func Test_operationOutcome(t *testing.T) {
+	testCases := []struct {
+		name            string
+		err             error
+		expectedOutcome string
+	}{
+		{
+			name:            "case 0: success",
+			expectedOutcome: successOutcome,
+		},
+		{
+			name:            "case 1: Helm wait timeout",
+			err:             errors.New("timed out waiting for the condition"),
+			expectedOutcome: timeoutOutcome,
+		},
+		{
+			name:            "case 2: context deadline exceeded",
+			err:             microerror.Mask(fmt.Errorf("upgrade failed: %w", context.DeadlineExceeded)),
+			expectedOutcome: timeoutOutcome,
+		},
+		{
+			name:            "case 3: OCI pull failed",
+			err:             microerror.Maskf(ociPullFailedError, "pulling chart failed"),
+			expectedOutcome: pullFailedOutcome,
+		},
+		{
+			name:            "case 4: validation failed",
+			err:             errors.New("error validating data: unknown field"),
+			expectedOutcome: validationFailedOutcome,
+		},
+		{
+			name:            "case 5: values schema violation",
+			err:             fmt.Errorf("%s:\nhello-world:\n- replicas: Invalid type", helmSchemaValidationErrorMsg),
+			expectedOutcome: schemaViolationOutcome,
+		},
+		{
+			name:            "case 6: resource already exists",
+			err:             errors.New("rendered manifests contain a resource that already exists"),
+			expectedOutcome: alreadyExistsOutcome,
+		},
+		{
+			name:            "case 7: unknown error",
+			err:             errors.New("something went wrong"),
+			expectedOutcome: unknownOutcome,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			outcome := operationOutcome(tc.err)
+			if outcome != tc.expectedOutcome {
+				t.Fatalf("outcome == %#q, want %#q", outcome, tc.expectedOutcome)
+			}
+		})
+	}
+}
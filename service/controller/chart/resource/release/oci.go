@@ -0,0 +1,192 @@
+package release
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/afero"
+	"helm.sh/helm/v3/pkg/registry"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+)
+
+// dockerConfig is the format of docker config secrets. Secrets of type
+// kubernetes.io/dockercfg contain the auths map only.
+type dockerConfig struct {
+	Auths map[string]dockerConfigEntry `json:"auths"`
+}
+
+type dockerConfigEntry struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Auth     string `json:"auth,omitempty"`
+}
+
+// pullOCIChart pulls the oci:// chart of the chart CR using the credentials
+// of the docker config secret referenced by its pull-secret annotation and
+// returns the path of the chart tarball. Failures are returned as
+// ociPullFailedError.
+func (r *Resource) pullOCIChart(ctx context.Context, cr v1alpha1.Chart) (string, error) {
+	ref := key.TarballURL(cr)
+
+	client, err := r.newRegistryClient(ctx, cr)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	result, err := client.Pull(ref, registry.PullOptWithChart(true))
+	if err != nil {
+		return "", microerror.Maskf(ociPullFailedError, "pulling chart %#q failed: %s", ref, err.Error())
+	}
+
+	tmpFile, err := afero.TempFile(r.fs, "", "chart-tarball")
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	defer func() { _ = tmpFile.Close() }()
+
+	_, err = tmpFile.Write(result.Chart.Data)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return tmpFile.Name(), nil
+}
+
+// pullOCIProvenance pulls the provenance layer of the oci:// chart of the
+// chart CR. Failures are returned as ociPullFailedError.
+func (r *Resource) pullOCIProvenance(ctx context.Context, cr v1alpha1.Chart) ([]byte, error) {
+	ref := key.TarballURL(cr)
+
+	client, err := r.newRegistryClient(ctx, cr)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	result, err := client.Pull(ref, registry.PullOptWithChart(false), registry.PullOptWithProv(true))
+	if err != nil {
+		return nil, microerror.Maskf(ociPullFailedError, "pulling provenance of chart %#q failed: %s", ref, err.Error())
+	}
+
+	return result.Prov.Data, nil
+}
+
+// newRegistryClient returns a registry client for the oci:// chart of the
+// chart CR. It authenticates with the credentials of the docker config
+// secret referenced by the pull-secret annotation when it is set.
+func (r *Resource) newRegistryClient(ctx context.Context, cr v1alpha1.Chart) (*registry.Client, error) {
+	if key.PullSecretAnnotation(cr) == "" {
+		client, err := registry.NewClient()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return client, nil
+	}
+
+	secretNamespace, secretName := pullSecret(cr)
+
+	secret, err := r.k8sClient.CoreV1().Secrets(secretNamespace).Get(ctx, secretName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, microerror.Maskf(ociPullFailedError, "pull secret %#q in namespace %#q not found", secretName, secretNamespace)
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	username, password, err := registryCredentials(secret, registryHost(key.TarballURL(cr)))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	client, err := registry.NewClient(registry.ClientOptBasicAuth(username, password))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	r.logger.Debugf(ctx, "using credentials of pull secret %#q in namespace %#q for %#q", secretName, secretNamespace, key.TarballURL(cr))
+
+	return client, nil
+}
+
+// pullSecret returns the namespace and name of the pull secret of the chart
+// CR. Secrets without namespace are looked up in the namespace of the chart
+// CR.
+func pullSecret(cr v1alpha1.Chart) (string, string) {
+	value := key.PullSecretAnnotation(cr)
+
+	namespace, name, ok := strings.Cut(value, "/")
+	if !ok {
+		return cr.Namespace, value
+	}
+
+	return namespace, name
+}
+
+// registryCredentials returns the username and password for the registry
+// host from the docker config secret.
+func registryCredentials(secret *corev1.Secret, host string) (string, string, error) {
+	var auths map[string]dockerConfigEntry
+
+	switch secret.Type {
+	case corev1.SecretTypeDockerConfigJson:
+		var c dockerConfig
+
+		err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &c)
+		if err != nil {
+			return "", "", microerror.Maskf(ociPullFailedError, "parsing %#q of pull secret %#q in namespace %#q failed: %s", corev1.DockerConfigJsonKey, secret.Name, secret.Namespace, err.Error())
+		}
+
+		auths = c.Auths
+	case corev1.SecretTypeDockercfg:
+		err := json.Unmarshal(secret.Data[corev1.DockerConfigKey], &auths)
+		if err != nil {
+			return "", "", microerror.Maskf(ociPullFailedError, "parsing %#q of pull secret %#q in namespace %#q failed: %s", corev1.DockerConfigKey, secret.Name, secret.Namespace, err.Error())
+		}
+	default:
+		return "", "", microerror.Maskf(ociPullFailedError, "pull secret %#q in namespace %#q has type %#q, want %#q or %#q", secret.Name, secret.Namespace, secret.Type, corev1.SecretTypeDockerConfigJson, corev1.SecretTypeDockercfg)
+	}
+
+	for server, entry := range auths {
+		if registryHost(server) != host {
+			continue
+		}
+
+		if entry.Username != "" || entry.Password != "" {
+			return entry.Username, entry.Password, nil
+		}
+
+		b, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			return "", "", microerror.Maskf(ociPullFailedError, "decoding auth of registry %#q in pull secret %#q in namespace %#q failed: %s", host, secret.Name, secret.Namespace, err.Error())
+		}
+
+		username, password, ok := strings.Cut(string(b), ":")
+		if !ok {
+			return "", "", microerror.Maskf(ociPullFailedError, "auth of registry %#q in pull secret %#q in namespace %#q is not in username:password format", host, secret.Name, secret.Namespace)
+		}
+
+		return username, password, nil
+	}
+
+	return "", "", microerror.Maskf(ociPullFailedError, "pull secret %#q in namespace %#q has no credentials for registry %#q", secret.Name, secret.Namespace, host)
+}
+
+// registryHost returns the host of an oci:// chart reference or of a docker
+// config server, e.g. registry.example.com for
+// oci://registry.example.com/charts/chart:1.0.0.
+func registryHost(ref string) string {
+	for _, scheme := range []string{registry.OCIScheme + "://", "https://", "http://"} {
+		ref = strings.TrimPrefix(ref, scheme)
+	}
+
+	host, _, _ := strings.Cut(ref, "/")
+
+	return host
+}
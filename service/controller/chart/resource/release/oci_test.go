@@ -0,0 +1,201 @@
+package release
+
+import (
+	"encoding/base64"
+	"strconv"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
+)
+
+func Test_pullSecret(t *testing.T) {
+	testCases := []struct {
+		name              string
+		pullSecret        string
+		expectedNamespace string
+		expectedName      string
+	}{
+		{
+			name:              "case 0: secret in chart CR namespace",
+			pullSecret:        "registry-credentials",
+			expectedNamespace: "giantswarm",
+			expectedName:      "registry-credentials",
+		},
+		{
+			name:              "case 1: secret in other namespace",
+			pullSecret:        "org-acme/registry-credentials",
+			expectedNamespace: "org-acme",
+			expectedName:      "registry-credentials",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			cr := v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						annotation.PullSecret: tc.pullSecret,
+					},
+					Namespace: "giantswarm",
+				},
+			}
+
+			namespace, name := pullSecret(cr)
+			if namespace != tc.expectedNamespace {
+				t.Fatalf("namespace == %#q, want %#q", namespace, tc.expectedNamespace)
+			}
+			if name != tc.expectedName {
+				t.Fatalf("name == %#q, want %#q", name, tc.expectedName)
+			}
+		})
+	}
+}
+
+func Test_registryCredentials(t *testing.T) {
+	auth := base64.StdEncoding.EncodeToString([]byte("robot:s3cr3t"))
+
+	testCases := []struct {
+		name             string
+		secret           *corev1.Secret
+		host             string
+		expectedUsername string
+		expectedPassword string
+		errorMatcher     func(error) bool
+	}{
+		{
+			name: "case 0: docker config json with username and password",
+			secret: &corev1.Secret{
+				Type: corev1.SecretTypeDockerConfigJson,
+				Data: map[string][]byte{
+					corev1.DockerConfigJsonKey: []byte(`{"auths":{"registry.example.com":{"username":"robot","password":"s3cr3t"}}}`),
+				},
+			},
+			host:             "registry.example.com",
+			expectedUsername: "robot",
+			expectedPassword: "s3cr3t",
+		},
+		{
+			name: "case 1: docker config json with auth and server url",
+			secret: &corev1.Secret{
+				Type: corev1.SecretTypeDockerConfigJson,
+				Data: map[string][]byte{
+					corev1.DockerConfigJsonKey: []byte(`{"auths":{"https://registry.example.com/v2/":{"auth":"` + auth + `"}}}`),
+				},
+			},
+			host:             "registry.example.com",
+			expectedUsername: "robot",
+			expectedPassword: "s3cr3t",
+		},
+		{
+			name: "case 2: docker cfg",
+			secret: &corev1.Secret{
+				Type: corev1.SecretTypeDockercfg,
+				Data: map[string][]byte{
+					corev1.DockerConfigKey: []byte(`{"registry.example.com":{"auth":"` + auth + `"}}`),
+				},
+			},
+			host:             "registry.example.com",
+			expectedUsername: "robot",
+			expectedPassword: "s3cr3t",
+		},
+		{
+			name: "case 3: no credentials for registry",
+			secret: &corev1.Secret{
+				Type: corev1.SecretTypeDockerConfigJson,
+				Data: map[string][]byte{
+					corev1.DockerConfigJsonKey: []byte(`{"auths":{"other.example.com":{"auth":"` + auth + `"}}}`),
+				},
+			},
+			host:         "registry.example.com",
+			errorMatcher: IsOCIPullFailed,
+		},
+		{
+			name: "case 4: opaque secret",
+			secret: &corev1.Secret{
+				Type: corev1.SecretTypeOpaque,
+			},
+			host:         "registry.example.com",
+			errorMatcher: IsOCIPullFailed,
+		},
+		{
+			name: "case 5: invalid auth",
+			secret: &corev1.Secret{
+				Type: corev1.SecretTypeDockerConfigJson,
+				Data: map[string][]byte{
+					corev1.DockerConfigJsonKey: []byte(`{"auths":{"registry.example.com":{"auth":"` + base64.StdEncoding.EncodeToString([]byte("robot")) + `"}}}`),
+				},
+			},
+			host:         "registry.example.com",
+			errorMatcher: IsOCIPullFailed,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			username, password, err := registryCredentials(tc.secret, tc.host)
+			switch {
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case err != nil && !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if username != tc.expectedUsername {
+				t.Fatalf("username == %#q, want %#q", username, tc.expectedUsername)
+			}
+			if password != tc.expectedPassword {
+				t.Fatalf("password == %#q, want %#q", password, tc.expectedPassword)
+			}
+		})
+	}
+}
+
+func Test_registryHost(t *testing.T) {
+	testCases := []struct {
+		name         string
+		ref          string
+		expectedHost string
+	}{
+		{
+			name:         "case 0: oci reference with tag",
+			ref:          "oci://registry.example.com/charts/hello-world:1.0.0",
+			expectedHost: "registry.example.com",
+		},
+		{
+			name:         "case 1: oci reference with digest and port",
+			ref:          "oci://registry.example.com:5000/hello-world@sha256:4b1e",
+			expectedHost: "registry.example.com:5000",
+		},
+		{
+			name:         "case 2: docker config server url",
+			ref:          "https://registry.example.com/v1/",
+			expectedHost: "registry.example.com",
+		},
+		{
+			name:         "case 3: docker config host",
+			ref:          "registry.example.com",
+			expectedHost: "registry.example.com",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			host := registryHost(tc.ref)
+			if host != tc.expectedHost {
+				t.Fatalf("host == %#q, want %#q", host, tc.expectedHost)
+			}
+		})
+	}
+}
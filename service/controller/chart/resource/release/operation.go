@@ -0,0 +1,83 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
+	"github.com/giantswarm/operatorkit/v7/pkg/controller/context/finalizerskeptcontext"
+	"github.com/giantswarm/operatorkit/v7/pkg/controller/context/resourcecanceledcontext"
+
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
+	"github.com/giantswarm/chart-operator/v4/service/internal/operation"
+)
+
+// checkOperation checks the last Helm operation of the chart CR. When it is
+// still running in the background the resource is canceled so no second
+// operation is started and the pending release is not rolled back or
+// recovered, and true is returned. When it failed for the desired version
+// and values of the chart CR the failure is added to the controller context
+// so the status resource reports it.
+func (r *Resource) checkOperation(ctx context.Context, cr v1alpha1.Chart, cc *controllercontext.Context) bool {
+	o, ok := r.operations.Get(cr.UID)
+	if !ok {
+		return false
+	}
+
+	if o.Running() {
+		reason := fmt.Sprintf("%s of release %#q to version %#q is running since %s", o.Kind, o.Release, o.Version, o.StartedAt.UTC().Format(time.RFC3339))
+
+		status := helmclient.StatusPendingUpgrade
+		if o.Kind == operation.Install {
+			status = helmclient.StatusPendingInstall
+		}
+		addStatusToContext(cc, reason, status)
+
+		r.logger.Debugf(ctx, "%s", reason)
+
+		if key.IsDeleted(cr) {
+			// The release is deleted once the operation finished.
+			r.logger.Debugf(ctx, "keeping finalizers")
+			finalizerskeptcontext.SetKept(ctx)
+		}
+
+		r.logger.Debugf(ctx, "canceling resource")
+		resourcecanceledcontext.SetCanceled(ctx)
+
+		return true
+	}
+
+	if o.Err == nil || o.Version != key.Version(cr) || o.ValuesChecksum != key.ValuesChecksumAnnotation(cr) {
+		return false
+	}
+
+	reason, status := operationFailure(o)
+	addStatusToContext(cc, reason, status)
+
+	r.logger.Debugf(ctx, "%s of release %#q to version %#q failed in the background at %s: %s", o.Kind, o.Release, o.Version, o.FinishedAt.UTC().Format(time.RFC3339), o.Err.Error())
+
+	return false
+}
+
+// operationFailure returns the reason and status of the failed operation
+// matching the ones reported when the operation fails in the foreground.
+func operationFailure(o operation.Operation) (string, string) {
+	err := o.Err
+
+	switch {
+	case helmclient.IsResourceAlreadyExists(err):
+		return fmt.Sprintf("object already exists: (%s)", err.Error()), releasestatus.AlreadyExists
+	case helmclient.IsValidationFailedError(err):
+		return fmt.Sprintf("helm validation error: (%s)", err.Error()), releasestatus.ValidationFailed
+	case helmclient.IsInvalidManifest(err):
+		return fmt.Sprintf("invalid manifest error: (%s)", err.Error()), releasestatus.InvalidManifest
+	case isSchemaValidationError(err):
+		return err.Error(), releasestatus.ValuesSchemaViolation
+	default:
+		return fmt.Sprintf("%s of version %#q failed: (%s)", o.Kind, o.Version, err.Error()), releasestatus.UnknownError
+	}
+}
@@ -0,0 +1,158 @@
+package release
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/giantswarm/operatorkit/v7/pkg/controller/context/finalizerskeptcontext"
+	"github.com/giantswarm/operatorkit/v7/pkg/controller/context/resourcecanceledcontext"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
+
+	"github.com/giantswarm/chart-operator/v4/service/internal/operation"
+	"github.com/giantswarm/chart-operator/v4/service/internal/releasemetrics"
+)
+
+func Test_checkOperation(t *testing.T) {
+	uid := types.UID("b6f4e8a2-5b0c-4c1e-9d4f-3f0e2c6a7d11")
+
+	testCases := []struct {
+		name             string
+		operation        *operation.Operation
+		finishErr        error
+		deleted          bool
+		expectedRunning  bool
+		expectedStatus   string
+		expectedCanceled bool
+		expectedKept     bool
+	}{
+		{
+			name: "case 0: no operation",
+		},
+		{
+			name: "case 1: install still running",
+			operation: &operation.Operation{
+				Kind:           operation.Install,
+				Release:        "hello-world",
+				Version:        "1.0.0",
+				ValuesChecksum: "4b1e",
+			},
+			expectedRunning:  true,
+			expectedStatus:   helmclient.StatusPendingInstall,
+			expectedCanceled: true,
+		},
+		{
+			name: "case 2: upgrade still running for deleted chart CR",
+			operation: &operation.Operation{
+				Kind:           operation.Upgrade,
+				Release:        "hello-world",
+				Version:        "1.0.0",
+				ValuesChecksum: "4b1e",
+			},
+			deleted:          true,
+			expectedRunning:  true,
+			expectedStatus:   helmclient.StatusPendingUpgrade,
+			expectedCanceled: true,
+			expectedKept:     true,
+		},
+		{
+			name: "case 3: upgrade failed in the background",
+			operation: &operation.Operation{
+				Kind:           operation.Upgrade,
+				Release:        "hello-world",
+				Version:        "1.0.0",
+				ValuesChecksum: "4b1e",
+			},
+			finishErr:      errors.New("timed out waiting for the condition"),
+			expectedStatus: releasestatus.UnknownError,
+		},
+		{
+			name: "case 4: upgrade failed in the background for other version",
+			operation: &operation.Operation{
+				Kind:           operation.Upgrade,
+				Release:        "hello-world",
+				Version:        "0.9.0",
+				ValuesChecksum: "4b1e",
+			},
+			finishErr: errors.New("timed out waiting for the condition"),
+		},
+		{
+			name: "case 5: upgrade succeeded in the background",
+			operation: &operation.Operation{
+				Kind:           operation.Upgrade,
+				Release:        "hello-world",
+				Version:        "1.0.0",
+				ValuesChecksum: "4b1e",
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			ctx := controllercontext.NewContext(context.Background(), controllercontext.Context{})
+			ctx = resourcecanceledcontext.NewContext(ctx, make(chan struct{}))
+			ctx = finalizerskeptcontext.NewContext(ctx, make(chan struct{}))
+
+			cc, err := controllercontext.FromContext(ctx)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			r := &Resource{
+				logger:         microloggertest.New(),
+				operations:     operation.New(),
+				releaseMetrics: releasemetrics.New(),
+			}
+
+			if tc.operation != nil {
+				r.operations.Start(uid, *tc.operation)
+
+				// Operations without error in the test case are running
+				// unless they are expected to have succeeded.
+				if tc.finishErr != nil || !tc.expectedRunning {
+					r.operations.Finish(uid, tc.finishErr)
+				}
+			}
+
+			cr := v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						annotation.ValuesChecksum: "4b1e",
+					},
+					UID: uid,
+				},
+				Spec: v1alpha1.ChartSpec{
+					Version: "1.0.0",
+				},
+			}
+			if tc.deleted {
+				cr.DeletionTimestamp = &metav1.Time{}
+			}
+
+			running := r.checkOperation(ctx, cr, cc)
+			if running != tc.expectedRunning {
+				t.Fatalf("running == %t, want %t", running, tc.expectedRunning)
+			}
+			if cc.Status.Release.Status != tc.expectedStatus {
+				t.Fatalf("status == %#q, want %#q", cc.Status.Release.Status, tc.expectedStatus)
+			}
+			if resourcecanceledcontext.IsCanceled(ctx) != tc.expectedCanceled {
+				t.Fatalf("canceled == %t, want %t", resourcecanceledcontext.IsCanceled(ctx), tc.expectedCanceled)
+			}
+			if finalizerskeptcontext.IsKept(ctx) != tc.expectedKept {
+				t.Fatalf("kept == %t, want %t", finalizerskeptcontext.IsKept(ctx), tc.expectedKept)
+			}
+		})
+	}
+}
@@ -0,0 +1,417 @@
+package release
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/postrender"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+	"github.com/giantswarm/chart-operator/v4/service/internal/imagerewrite"
+)
+
+const (
+	// postRenderedTemplate is the only template of the charts installed
+	// instead of charts with post-render patches. It outputs the
+	// post-rendered manifests stored as files of the chart without
+	// rendering them again.
+	postRenderedTemplate = `{{- range $path, $_ := .Files.Glob "manifests/*" }}
+---
+{{ $.Files.Get $path }}
+{{- end }}
+`
+
+	// kustomizationDir is the directory of the in-memory file system the
+	// kustomization of the post-renderer is built in.
+	kustomizationDir = "/post-render"
+)
+
+// lookupActionRegexp matches template actions calling the lookup function.
+var lookupActionRegexp = regexp.MustCompile(`{{[^}]*\blookup\b`)
+
+// kustomizePostRenderer is a Helm post-renderer applying Kustomize patches
+// to the rendered manifests.
+type kustomizePostRenderer struct {
+	patches []types.Patch
+}
+
+func (p kustomizePostRenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	kustomization := types.Kustomization{
+		Patches:   p.patches,
+		Resources: []string{"manifests.yaml"},
+	}
+
+	b, err := yaml.Marshal(kustomization)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	fs := filesys.MakeFsInMemory()
+
+	err = fs.WriteFile(filepath.Join(kustomizationDir, "kustomization.yaml"), b)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	err = fs.WriteFile(filepath.Join(kustomizationDir, "manifests.yaml"), renderedManifests.Bytes())
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	resources, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fs, kustomizationDir)
+	if err != nil {
+		return nil, microerror.Maskf(postRenderFailedError, "applying patches failed: %s", err.Error())
+	}
+
+	modified, err := resources.AsYaml()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return bytes.NewBuffer(modified), nil
+}
+
+// postRenderers is a Helm post-renderer running the post-renderers it
+// consists of in order.
+type postRenderers []postrender.PostRenderer
+
+func (p postRenderers) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	modified := renderedManifests
+
+	for _, pr := range p {
+		var err error
+
+		modified, err = pr.Run(modified)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return modified, nil
+}
+
+// getPostRenderPatches returns the data of the config map referenced by the
+// post-render-patches annotation of the chart CR. It is empty when the
+// annotation is not set.
+func (r *Resource) getPostRenderPatches(ctx context.Context, cr v1alpha1.Chart) (map[string]string, error) {
+	if key.PostRenderPatchesAnnotation(cr) == "" || key.IsDeleted(cr) {
+		return nil, nil
+	}
+
+	configMapNamespace, configMapName := postRenderPatchesConfigMap(cr)
+
+	configMap, err := r.k8sClient.CoreV1().ConfigMaps(configMapNamespace).Get(ctx, configMapName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, microerror.Maskf(postRenderFailedError, "post-render patches config map %#q in namespace %#q not found", configMapName, configMapNamespace)
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	_, err = parsePostRenderPatches(configMap.Data)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return configMap.Data, nil
+}
+
+// postRenderPatchesConfigMap returns the namespace and name of the
+// post-render patches config map of the chart CR. Config maps without
+// namespace are looked up in the namespace of the chart CR.
+func postRenderPatchesConfigMap(cr v1alpha1.Chart) (string, string) {
+	value := key.PostRenderPatchesAnnotation(cr)
+
+	namespace, name, ok := strings.Cut(value, "/")
+	if !ok {
+		return cr.Namespace, value
+	}
+
+	return namespace, name
+}
+
+// parsePostRenderPatches parses the patches of the post-render patches config
+// map. Every key holds a YAML list of Kustomize patches with an inline patch
+// and an optional target. Keys are applied in alphabetical order.
+func parsePostRenderPatches(data map[string]string) ([]types.Patch, error) {
+	var patches []types.Patch
+
+	for _, k := range sortedStringKeys(data) {
+		var p []types.Patch
+
+		err := yaml.UnmarshalStrict([]byte(data[k]), &p)
+		if err != nil {
+			return nil, microerror.Maskf(postRenderFailedError, "parsing post-render patches of key %#q failed: %s", k, err.Error())
+		}
+
+		for i, patch := range p {
+			if patch.Path != "" {
+				return nil, microerror.Maskf(postRenderFailedError, "post-render patch %d of key %#q must be inline, got path %#q", i, k, patch.Path)
+			}
+			if patch.Patch == "" {
+				return nil, microerror.Maskf(postRenderFailedError, "post-render patch %d of key %#q must not be empty", i, k)
+			}
+		}
+
+		patches = append(patches, p...)
+	}
+
+	return patches, nil
+}
+
+// chartImageRewriter returns the image rewriter of the chart CR. It is nil
+// when image rewriting is disabled or the chart CR opted out of it.
+func (r *Resource) chartImageRewriter(cr v1alpha1.Chart) *imagerewrite.Rewriter {
+	if r.imageRewriter == nil || key.HasSkipImageRewriteAnnotation(cr) {
+		return nil
+	}
+
+	return r.imageRewriter
+}
+
+// checksumImageRewriter returns the image rewriter of the chart CR when it
+// applies to the deployed release, i.e. the release has images which are or
+// were rewritten. Only these releases are upgraded when image rewriting is
+// enabled or its configuration changes. Releases without deployed revision
+// are installed with images rewritten, so the rewriter is returned for them.
+func (r *Resource) checksumImageRewriter(ctx context.Context, cr v1alpha1.Chart) (*imagerewrite.Rewriter, error) {
+	rewriter := r.chartImageRewriter(cr)
+	if rewriter == nil {
+		return nil, nil
+	}
+
+	rel, err := r.deployedRelease(ctx, cr)
+	if errors.Is(err, driver.ErrNoDeployedReleases) || errors.Is(err, driver.ErrReleaseNotFound) {
+		return rewriter, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	manifests := []string{rel.Manifest}
+	for _, h := range rel.Hooks {
+		manifests = append(manifests, h.Manifest)
+	}
+
+	applies, err := rewriter.Applies(strings.Join(manifests, "\n---\n"))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if !applies {
+		return nil, nil
+	}
+
+	return rewriter, nil
+}
+
+// postRenderer returns the post-renderer applying the post-render patches of
+// the release state and rewriting the images of the chart CR afterwards. It
+// is nil when the release has neither patches nor images to rewrite.
+func (r *Resource) postRenderer(cr v1alpha1.Chart, releaseState ReleaseState) (postrender.PostRenderer, error) {
+	var prs postRenderers
+
+	if len(releaseState.PostRenderPatches) > 0 {
+		patches, err := parsePostRenderPatches(releaseState.PostRenderPatches)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		prs = append(prs, kustomizePostRenderer{patches: patches})
+	}
+
+	if rewriter := r.chartImageRewriter(cr); rewriter != nil {
+		prs = append(prs, rewriter)
+	}
+
+	if len(prs) == 0 {
+		return nil, nil
+	}
+
+	return prs, nil
+}
+
+// postRender returns the path of the chart tarball to install or upgrade the
+// release with. The Helm client does not support post-renderers, so charts
+// with post-render patches or images to rewrite are rendered with the
+// post-renderer and packaged as a chart of the post-rendered manifests, hooks
+// and CRDs. The original tarball path is returned when the release has no
+// post-renderer. Otherwise the returned tarball must be removed with
+// removePostRenderedChart.
+//
+// Rendering happens before Helm installs or upgrades the release, which has
+// the following limits:
+//
+//   - Templates are rendered without access to the cluster, so charts using
+//     the lookup function are rejected when post-render patches are set.
+//   - .Release.Revision is always 1, since the revision is only known to
+//     Helm.
+//   - Helm stores the post-rendered chart in the release, so e.g. `helm get
+//     values` works but `helm pull` of the release shows a single template.
+//   - Hooks are stored as files of the post-rendered chart. Helm still runs
+//     them as hooks because of their annotations, but they are not patched,
+//     only their images are rewritten.
+func (r *Resource) postRender(ctx context.Context, cr v1alpha1.Chart, tarballPath string, releaseState ReleaseState, isUpgrade bool) (string, error) {
+	pr, err := r.postRenderer(cr, releaseState)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	if pr == nil {
+		return tarballPath, nil
+	}
+
+	r.logger.Debugf(ctx, "post-rendering release %#q", releaseState.Name)
+
+	original, err := loader.Load(tarballPath)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	if len(releaseState.PostRenderPatches) > 0 {
+		if name, ok := lookupTemplate(original); ok {
+			return "", microerror.Maskf(postRenderFailedError, "template %#q uses the lookup function, which is not supported with post-render patches", name)
+		}
+	}
+
+	rel, err := r.renderRelease(ctx, cr, tarballPath, releaseState.Values, isUpgrade, pr)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	metadata := *original.Metadata
+	metadata.Dependencies = nil
+
+	rendered := &chart.Chart{
+		Metadata: &metadata,
+		Templates: []*chart.File{
+			{
+				Name: "templates/post-rendered.yaml",
+				Data: []byte(postRenderedTemplate),
+			},
+		},
+	}
+
+	manifests := sortedManifests(rel.Manifest)
+	for _, h := range rel.Hooks {
+		hook := h.Manifest
+
+		// Helm does not post-render hooks, but their images are
+		// rewritten as well so they can be pulled from the mirror.
+		if rewriter := r.chartImageRewriter(cr); rewriter != nil {
+			hook, err = rewriter.RewriteManifest(hook)
+			if err != nil {
+				return "", microerror.Mask(err)
+			}
+		}
+
+		manifests = append(manifests, hook)
+	}
+	for i, m := range manifests {
+		rendered.Files = append(rendered.Files, &chart.File{
+			Name: fmt.Sprintf("manifests/%04d.yaml", i),
+			Data: []byte(m),
+		})
+	}
+
+	for i, crd := range original.CRDObjects() {
+		rendered.Files = append(rendered.Files, &chart.File{
+			Name: fmt.Sprintf("crds/%04d-%s", i, filepath.Base(crd.Name)),
+			Data: crd.File.Data,
+		})
+	}
+
+	dir, err := os.MkdirTemp("", "chart-operator-post-render-")
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	path, err := chartutil.Save(rendered, dir)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return "", microerror.Mask(err)
+	}
+
+	r.logger.Debugf(ctx, "post-rendered release %#q", releaseState.Name)
+
+	return path, nil
+}
+
+// lookupTemplate returns the name of the first template of the chart or its
+// dependencies calling the lookup function.
+func lookupTemplate(c *chart.Chart) (string, bool) {
+	for _, t := range c.Templates {
+		if lookupActionRegexp.Match(t.Data) {
+			return filepath.Join(c.ChartFullPath(), t.Name), true
+		}
+	}
+
+	for _, d := range c.Dependencies() {
+		if name, ok := lookupTemplate(d); ok {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// removePostRenderedChart removes the chart tarball created by postRender.
+func (r *Resource) removePostRenderedChart(ctx context.Context, path string) {
+	err := os.RemoveAll(filepath.Dir(path))
+	if err != nil {
+		r.logger.Errorf(ctx, err, "deletion of %#q failed", path)
+	}
+}
+
+// postRenderChecksumData returns the data the values checksums are computed
+// of. Post-render patches and the configuration of the image rewriter
+// returned by checksumImageRewriter are included so changing them upgrades
+// the release. Values of releases without
+// post-renderer are returned unchanged so their checksums do not change.
+func postRenderChecksumData(values map[string]interface{}, patches map[string]string, rewriter *imagerewrite.Rewriter) map[string]interface{} {
+	if len(patches) == 0 && rewriter == nil {
+		return values
+	}
+
+	data := map[string]interface{}{
+		"values": values,
+	}
+
+	if len(patches) > 0 {
+		p := map[string]interface{}{}
+		for k, v := range patches {
+			p[k] = v
+		}
+
+		data["patches"] = p
+	}
+	if rewriter != nil {
+		data["imageRewrite"] = rewriter.String()
+	}
+
+	return data
+}
+
+func sortedStringKeys(data map[string]string) []string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,513 @@
+package release
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
+	"github.com/giantswarm/chart-operator/v4/service/internal/imagerewrite"
+)
+
+const postRenderDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: hello-world
+spec:
+  template:
+    spec:
+      containers:
+      - name: hello-world
+        image: {{ .Values.image }}
+`
+
+const postRenderHook = `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: hello-world-hook
+  annotations:
+    helm.sh/hook: pre-upgrade
+spec:
+  template:
+    spec:
+      containers:
+      - name: hook
+        image: {{ .Values.image }}
+`
+
+const postRenderLookupSecret = `{{- $secret := (lookup "v1" "Secret" .Release.Namespace "hello-world") }}
+apiVersion: v1
+kind: Secret
+metadata:
+  name: hello-world
+data:
+  password: {{ if $secret }}{{ index $secret.data "password" }}{{ else }}{{ randAlphaNum 16 | b64enc }}{{ end }}
+`
+
+const postRenderPatches = `- target:
+    kind: Deployment
+  patch: |
+    - op: add
+      path: /spec/template/spec/tolerations
+      value: [{"operator": "Exists"}]
+- patch: |
+    apiVersion: apps/v1
+    kind: Deployment
+    metadata:
+      name: hello-world
+      labels:
+        patched: "true"
+`
+
+func Test_parsePostRenderPatches(t *testing.T) {
+	testCases := []struct {
+		name            string
+		data            map[string]string
+		expectedPatches int
+		errorMatcher    func(error) bool
+	}{
+		{
+			name: "case 0: no patches",
+		},
+		{
+			name: "case 1: patches of multiple keys",
+			data: map[string]string{
+				"a": postRenderPatches,
+				"b": "- patch: '[]'\n",
+			},
+			expectedPatches: 3,
+		},
+		{
+			name: "case 2: patch from path",
+			data: map[string]string{
+				"a": "- path: patch.yaml\n",
+			},
+			errorMatcher: IsPostRenderFailed,
+		},
+		{
+			name: "case 3: empty patch",
+			data: map[string]string{
+				"a": "- target:\n    kind: Deployment\n",
+			},
+			errorMatcher: IsPostRenderFailed,
+		},
+		{
+			name: "case 4: unknown field",
+			data: map[string]string{
+				"a": "- patches: []\n",
+			},
+			errorMatcher: IsPostRenderFailed,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			patches, err := parsePostRenderPatches(tc.data)
+			switch {
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case err != nil && !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if len(patches) != tc.expectedPatches {
+				t.Fatalf("len(patches) == %d, want %d", len(patches), tc.expectedPatches)
+			}
+		})
+	}
+}
+
+func Test_kustomizePostRenderer(t *testing.T) {
+	patches, err := parsePostRenderPatches(map[string]string{"patches": postRenderPatches})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	manifest := strings.ReplaceAll(postRenderDeployment, "{{ .Values.image }}", "hello-world:1.0.0")
+
+	modified, err := kustomizePostRenderer{patches: patches}.Run(bytes.NewBufferString(manifest))
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	for _, s := range []string{"patched: \"true\"", "operator: Exists", "image: hello-world:1.0.0"} {
+		if !strings.Contains(modified.String(), s) {
+			t.Fatalf("modified manifest does not contain %#q\n%s", s, modified.String())
+		}
+	}
+
+	// Strategic merge patches without target must match an object.
+	patches, err = parsePostRenderPatches(map[string]string{"patches": "- patch: |\n    apiVersion: v1\n    kind: ConfigMap\n    metadata:\n      name: missing\n"})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	_, err = kustomizePostRenderer{patches: patches}.Run(bytes.NewBufferString(manifest))
+	if !IsPostRenderFailed(err) {
+		t.Fatalf("error == %#v, want matching", err)
+	}
+}
+
+func Test_postRender(t *testing.T) {
+	ctx := context.Background()
+
+	chartDir := filepath.Join(t.TempDir(), "hello-world")
+
+	files := map[string]string{
+		"Chart.yaml":               "apiVersion: v2\nname: hello-world\nversion: 1.0.0\n",
+		"values.yaml":              "image: hello-world:0.1.0\n",
+		"crds/greetings.yaml":      greetingsCRD,
+		"templates/deployment.yml": postRenderDeployment,
+		"templates/hook.yaml":      postRenderHook,
+	}
+	for name, content := range files {
+		err := os.MkdirAll(filepath.Dir(filepath.Join(chartDir, name)), 0755)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		err = os.WriteFile(filepath.Join(chartDir, name), []byte(content), 0600)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+	}
+
+	c, err := loader.Load(chartDir)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	tarballPath, err := chartutil.Save(c, t.TempDir())
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	k8sClient := fake.NewSimpleClientset()
+	k8sClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{
+		GitVersion: "v1.30.0",
+		Major:      "1",
+		Minor:      "30",
+	}
+
+	r := &Resource{
+		k8sClient: k8sClient,
+		logger:    microloggertest.New(),
+	}
+
+	cr := v1alpha1.Chart{
+		Spec: v1alpha1.ChartSpec{
+			Name:      "hello-world",
+			Namespace: "giantswarm",
+		},
+	}
+
+	releaseState := ReleaseState{
+		Name: "hello-world",
+		Values: map[string]interface{}{
+			"image": "hello-world:1.0.0",
+		},
+	}
+
+	// Releases without patches use the original chart.
+	path, err := r.postRender(ctx, cr, tarballPath, releaseState, false)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if path != tarballPath {
+		t.Fatalf("path == %#q, want %#q", path, tarballPath)
+	}
+
+	releaseState.PostRenderPatches = map[string]string{
+		"patches": postRenderPatches,
+	}
+
+	path, err = r.postRender(ctx, cr, tarballPath, releaseState, false)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	defer r.removePostRenderedChart(ctx, path)
+
+	rendered, err := loader.Load(path)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if rendered.Metadata.Version != "1.0.0" {
+		t.Fatalf("version == %#q, want %#q", rendered.Metadata.Version, "1.0.0")
+	}
+	if len(rendered.CRDObjects()) != 1 {
+		t.Fatalf("len(crds) == %d, want 1", len(rendered.CRDObjects()))
+	}
+
+	// Rendering the post-rendered chart without values results in the
+	// patched manifest and the hook.
+	rel, err := r.renderRelease(ctx, cr, path, nil, false, nil)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	for _, s := range []string{"patched: \"true\"", "operator: Exists", "image: hello-world:1.0.0"} {
+		if !strings.Contains(rel.Manifest, s) {
+			t.Fatalf("manifest does not contain %#q\n%s", s, rel.Manifest)
+		}
+	}
+	if len(rel.Hooks) != 1 {
+		t.Fatalf("len(hooks) == %d, want 1", len(rel.Hooks))
+	}
+	if !strings.Contains(rel.Hooks[0].Manifest, "image: hello-world:1.0.0") {
+		t.Fatalf("hook does not contain the rendered image\n%s", rel.Hooks[0].Manifest)
+	}
+
+	// Patches which cannot be applied fail the post-rendering.
+	releaseState.PostRenderPatches = map[string]string{
+		"patches": "- patch: |\n    apiVersion: v1\n    kind: ConfigMap\n    metadata:\n      name: missing\n",
+	}
+
+	_, err = r.postRender(ctx, cr, tarballPath, releaseState, false)
+	if !IsPostRenderFailed(err) {
+		t.Fatalf("error == %#v, want matching", err)
+	}
+
+	// Charts using the lookup function cannot be post-rendered with
+	// patches, since templates are rendered without access to the cluster.
+	{
+		lookupDir := filepath.Join(t.TempDir(), "lookup")
+
+		err = os.MkdirAll(filepath.Join(lookupDir, "templates"), 0755)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		err = os.WriteFile(filepath.Join(lookupDir, "Chart.yaml"), []byte("apiVersion: v2\nname: lookup\nversion: 1.0.0\n"), 0600)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		err = os.WriteFile(filepath.Join(lookupDir, "templates", "secret.yaml"), []byte(postRenderLookupSecret), 0600)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+
+		c, err := loader.Load(lookupDir)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		lookupTarballPath, err := chartutil.Save(c, t.TempDir())
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+
+		releaseState.PostRenderPatches = map[string]string{
+			"patches": postRenderPatches,
+		}
+
+		_, err = r.postRender(ctx, cr, lookupTarballPath, releaseState, false)
+		if !IsPostRenderFailed(err) {
+			t.Fatalf("error == %#v, want matching", err)
+		}
+	}
+
+	// Images of the manifest and hooks are rewritten after applying the
+	// patches.
+	r.imageRewriter, err = imagerewrite.New(imagerewrite.Config{
+		Registry:         "registry.example.com",
+		SourceRegistries: []string{"docker.io"},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	releaseState.PostRenderPatches = map[string]string{
+		"patches": postRenderPatches,
+	}
+
+	path, err = r.postRender(ctx, cr, tarballPath, releaseState, false)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	defer r.removePostRenderedChart(ctx, path)
+
+	rel, err = r.renderRelease(ctx, cr, path, nil, false, nil)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	for _, s := range []string{"patched: \"true\"", "image: registry.example.com/library/hello-world:1.0.0"} {
+		if !strings.Contains(rel.Manifest, s) {
+			t.Fatalf("manifest does not contain %#q\n%s", s, rel.Manifest)
+		}
+	}
+	if len(rel.Hooks) != 1 {
+		t.Fatalf("len(hooks) == %d, want 1", len(rel.Hooks))
+	}
+	if !strings.Contains(rel.Hooks[0].Manifest, "image: registry.example.com/library/hello-world:1.0.0") {
+		t.Fatalf("hook does not contain the rewritten image\n%s", rel.Hooks[0].Manifest)
+	}
+
+	// Chart CRs opting out of image rewriting without patches use the
+	// original chart.
+	cr.Annotations = map[string]string{
+		annotation.SkipImageRewrite: "true",
+	}
+	releaseState.PostRenderPatches = nil
+
+	path, err = r.postRender(ctx, cr, tarballPath, releaseState, false)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if path != tarballPath {
+		t.Fatalf("path == %#q, want %#q", path, tarballPath)
+	}
+}
+
+func Test_checksumImageRewriter(t *testing.T) {
+	rewriter, err := imagerewrite.New(imagerewrite.Config{
+		Registry:         "registry.example.com",
+		SourceRegistries: []string{"docker.io"},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	testCases := []struct {
+		name             string
+		annotations      map[string]string
+		deployedImage    string
+		expectedRewriter bool
+	}{
+		{
+			name:             "case 0: release without deployed revision",
+			expectedRewriter: true,
+		},
+		{
+			name:             "case 1: deployed release with image of source registry",
+			deployedImage:    "hello-world:1.0.0",
+			expectedRewriter: true,
+		},
+		{
+			name:             "case 2: deployed release with rewritten image",
+			deployedImage:    "registry.example.com/library/hello-world:1.0.0",
+			expectedRewriter: true,
+		},
+		{
+			name:          "case 3: deployed release with image of other registry",
+			deployedImage: "gsoci.azurecr.io/giantswarm/hello-world:1.0.0",
+		},
+		{
+			name: "case 4: chart CR opted out of image rewriting",
+			annotations: map[string]string{
+				annotation.SkipImageRewrite: "true",
+			},
+			deployedImage: "hello-world:1.0.0",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			k8sClient := fake.NewSimpleClientset()
+
+			if tc.deployedImage != "" {
+				manifest := strings.ReplaceAll(postRenderDeployment, "{{ .Values.image }}", tc.deployedImage)
+
+				err := storage.Init(driver.NewSecrets(k8sClient.CoreV1().Secrets("giantswarm"))).Create(&release.Release{
+					Name:      "hello-world",
+					Namespace: "giantswarm",
+					Version:   1,
+					Info: &release.Info{
+						Status: release.StatusDeployed,
+					},
+					Manifest: manifest,
+				})
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+			}
+
+			r := &Resource{
+				imageRewriter: rewriter,
+				k8sClient:     k8sClient,
+				logger:        microloggertest.New(),
+			}
+
+			cr := v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: tc.annotations,
+				},
+				Spec: v1alpha1.ChartSpec{
+					Name:      "hello-world",
+					Namespace: "giantswarm",
+				},
+			}
+
+			result, err := r.checksumImageRewriter(context.Background(), cr)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if (result != nil) != tc.expectedRewriter {
+				t.Fatalf("rewriter == %v, want %t", result, tc.expectedRewriter)
+			}
+		})
+	}
+}
+
+func Test_postRenderChecksumData(t *testing.T) {
+	values := map[string]interface{}{
+		"image": "hello-world:1.0.0",
+	}
+
+	checksum, err := valuesChecksum(values)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	// Checksums of releases without post-renderer do not change.
+	unpatched, err := valuesChecksum(postRenderChecksumData(values, nil, nil))
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if unpatched != checksum {
+		t.Fatalf("checksum == %#q, want %#q", unpatched, checksum)
+	}
+
+	patched, err := valuesChecksum(postRenderChecksumData(values, map[string]string{"patches": postRenderPatches}, nil))
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if patched == checksum {
+		t.Fatalf("checksum == %#q, want different checksum", patched)
+	}
+
+	rewriter, err := imagerewrite.New(imagerewrite.Config{
+		Registry:         "registry.example.com",
+		SourceRegistries: []string{"docker.io"},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	rewritten, err := valuesChecksum(postRenderChecksumData(values, nil, rewriter))
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if rewritten == checksum || rewritten == patched {
+		t.Fatalf("checksum == %#q, want different checksum", rewritten)
+	}
+}
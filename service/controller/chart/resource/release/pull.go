@@ -0,0 +1,217 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/operatorkit/v7/pkg/controller/context/resourcecanceledcontext"
+	"github.com/spf13/afero"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
+	"github.com/giantswarm/chart-operator/v4/service/internal/chartcache"
+	"github.com/giantswarm/chart-operator/v4/service/internal/chartverifier"
+)
+
+// pullChartTarball returns the path of the chart tarball of the chart CR.
+// The tarball is served from the chart cache when possible and pulled
+// otherwise. oci:// charts of chart CRs with the pull-secret annotation are
+// pulled with the credentials of the referenced docker config secret and are
+// never cached, all other charts are pulled by the Helm client. When the chart CR has the
+// chart-digest annotation the tarball must match it. When a keyring is
+// configured for the tarball URL the tarball must be verified by its
+// provenance file. When pulling or verifying fails for known reasons the
+// failure is added to the controller context, the resource is canceled and
+// an empty path is returned.
+func (r *Resource) pullChartTarball(ctx context.Context, cr v1alpha1.Chart, hc helmclient.Interface) (string, error) {
+	cc, err := controllercontext.FromContext(ctx)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	tarballURL := key.TarballURL(cr)
+	expectedDigest := key.ChartDigest(cr)
+
+	// The cache is keyed by tarball URL only so it would serve private
+	// charts to chart CRs without or with revoked credentials.
+	credentialed := key.TarballURLIsOCI(cr) && key.PullSecretAnnotation(cr) != ""
+
+	var tarballPath string
+	var cached bool
+	if !credentialed {
+		tarballPath, cached, err = r.chartCache.Get(ctx, tarballURL, expectedDigest)
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+	}
+	if cached {
+		r.logger.Debugf(ctx, "using cached chart tarball %#q", tarballURL)
+	} else {
+		start := time.Now()
+		if credentialed {
+			tarballPath, err = r.pullOCIChart(ctx, cr)
+		} else {
+			tarballPath, err = hc.PullChartTarball(ctx, tarballURL)
+		}
+		r.observeOperation(cr, pullOperation, start, err)
+		if err != nil {
+			var reason string
+			if IsOCIPullFailed(err) {
+				reason = err.Error()
+			} else if helmclient.IsPullChartFailedError(err) {
+				reason = fmt.Sprintf("pulling chart %#q failed", tarballURL)
+			} else if helmclient.IsPullChartNotFound(err) {
+				reason = fmt.Sprintf("chart %#q not found", tarballURL)
+			} else if helmclient.IsPullChartTimeout(err) {
+				reason = fmt.Sprintf("timeout pulling %#q", tarballURL)
+			} else {
+				return "", microerror.Mask(err)
+			}
+
+			addStatusToContext(cc, reason, releasestatus.ChartPullFailed)
+			r.event.Event(&cr, corev1.EventTypeWarning, chartPullFailedEventReason, reason)
+
+			r.logger.LogCtx(ctx, "level", "warning", "message", reason, "stack", microerror.JSON(err))
+			r.logger.Debugf(ctx, "canceling resource")
+			resourcecanceledcontext.SetCanceled(ctx)
+
+			return "", nil
+		}
+	}
+
+	var cachedDigest string
+	if cached {
+		cachedDigest, err = r.chartCache.Verify(tarballPath, "")
+		if err != nil {
+			r.removeTarball(ctx, tarballPath)
+			return "", microerror.Mask(err)
+		}
+	}
+
+	var verifiedDigest string
+	if r.chartVerifier.Enabled(tarballURL) {
+		// Cached tarballs already verified for the tarball URL and the chart
+		// CR are not verified again so the provenance file is not pulled on
+		// every reconciliation.
+		if cached && r.chartCache.IsVerified(tarballURL, cachedDigest) && chartcache.NormalizeDigest(key.VerifiedDigestAnnotation(cr)) == cachedDigest {
+			r.logger.Debugf(ctx, "cached chart tarball %#q already verified", tarballURL)
+		} else {
+			verifiedDigest, err = r.verifyChartTarball(ctx, cr, hc, tarballPath)
+			if chartverifier.IsVerificationFailed(err) || IsProvenancePullFailed(err) || IsOCIPullFailed(err) {
+				r.removeTarball(ctx, tarballPath)
+				if cached && chartverifier.IsVerificationFailed(err) {
+					r.chartCache.Remove(ctx, cachedDigest)
+				}
+				r.cancelOnVerificationFailed(ctx, cc, cr, err.Error())
+
+				return "", nil
+			} else if err != nil {
+				r.removeTarball(ctx, tarballPath)
+				return "", microerror.Mask(err)
+			}
+
+			if verifiedDigest != key.VerifiedDigestAnnotation(cr) {
+				err = r.addAnnotation(ctx, cr, annotation.VerifiedDigest, verifiedDigest)
+				if err != nil {
+					r.removeTarball(ctx, tarballPath)
+					return "", microerror.Mask(err)
+				}
+			}
+		}
+	}
+
+	if cached {
+		if verifiedDigest != "" {
+			r.chartCache.SetVerified(tarballURL, verifiedDigest)
+		}
+
+		return tarballPath, nil
+	}
+
+	if credentialed {
+		_, err = r.chartCache.Verify(tarballPath, expectedDigest)
+	} else {
+		_, err = r.chartCache.Put(ctx, tarballURL, tarballPath, expectedDigest)
+		if err == nil && verifiedDigest != "" {
+			r.chartCache.SetVerified(tarballURL, verifiedDigest)
+		}
+	}
+	if chartcache.IsDigestMismatch(err) {
+		r.removeTarball(ctx, tarballPath)
+		r.cancelOnVerificationFailed(ctx, cc, cr, fmt.Sprintf("verifying chart %#q failed: %s", tarballURL, err.Error()))
+
+		return "", nil
+	} else if err != nil {
+		r.removeTarball(ctx, tarballPath)
+		return "", microerror.Mask(err)
+	}
+
+	return tarballPath, nil
+}
+
+// verifyChartTarball verifies the chart tarball against its provenance file
+// and returns the verified digest. The provenance file of tarball URLs is
+// expected at the tarball URL with a .prov suffix and the provenance of
+// oci:// charts in their provenance layer.
+func (r *Resource) verifyChartTarball(ctx context.Context, cr v1alpha1.Chart, hc helmclient.Interface, tarballPath string) (string, error) {
+	tarballURL := key.TarballURL(cr)
+
+	var provenance []byte
+	if key.TarballURLIsOCI(cr) {
+		var err error
+		provenance, err = r.pullOCIProvenance(ctx, cr)
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+	} else {
+		provenanceURL := tarballURL + ".prov"
+
+		provenancePath, err := hc.PullChartTarball(ctx, provenanceURL)
+		if helmclient.IsPullChartFailedError(err) || helmclient.IsPullChartNotFound(err) || helmclient.IsPullChartTimeout(err) {
+			return "", microerror.Maskf(provenancePullFailedError, "pulling provenance %#q failed", provenanceURL)
+		} else if err != nil {
+			return "", microerror.Mask(err)
+		}
+		defer r.removeTarball(ctx, provenancePath)
+
+		provenance, err = afero.ReadFile(r.fs, provenancePath)
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+	}
+
+	tarball, err := afero.ReadFile(r.fs, tarballPath)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	digest, err := r.chartVerifier.Verify(ctx, tarballURL, tarball, provenance)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return digest, nil
+}
+
+func (r *Resource) cancelOnVerificationFailed(ctx context.Context, cc *controllercontext.Context, cr v1alpha1.Chart, reason string) {
+	addStatusToContext(cc, reason, releasestatus.ChartVerificationFailed)
+	r.event.Event(&cr, corev1.EventTypeWarning, chartVerificationFailedEventReason, reason)
+
+	r.logger.LogCtx(ctx, "level", "warning", "message", reason)
+	r.logger.Debugf(ctx, "canceling resource")
+	resourcecanceledcontext.SetCanceled(ctx)
+}
+
+func (r *Resource) removeTarball(ctx context.Context, tarballPath string) {
+	err := r.fs.Remove(tarballPath)
+	if err != nil {
+		r.logger.Errorf(ctx, err, "deletion of %#q failed", tarballPath)
+	}
+}
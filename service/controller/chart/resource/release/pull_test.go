@@ -0,0 +1,331 @@
+package release
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/helmclient/v4/pkg/helmclienttest"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/giantswarm/operatorkit/v7/pkg/controller/context/resourcecanceledcontext"
+	"github.com/spf13/afero"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake" //nolint:staticcheck
+
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
+	"github.com/giantswarm/chart-operator/v4/service/internal/chartcache"
+	"github.com/giantswarm/chart-operator/v4/service/internal/chartverifier"
+	"github.com/giantswarm/chart-operator/v4/service/internal/clientpair"
+	"github.com/giantswarm/chart-operator/v4/service/internal/operation"
+	"github.com/giantswarm/chart-operator/v4/service/internal/releasemetrics"
+)
+
+// testKeyring is the ASCII armored public key of a test catalog.
+const testKeyring = `-----BEGIN PGP PUBLIC KEY BLOCK-----
+
+xsBNBGrTclsBCADeTaamqQmjetb17kSeI+OFdWQ4Kw8TVoYw/8W8aIPqlQhcEGQR
+PfQ6VigdpaUU6jm0ZPTZaTkHnckLzdksgGG9sPxjPUcuORYy4CZIhp61l8wRadkM
+U3BPg3VD7AfjkHl9qttwMUMY6CBj9VS7srD5mU34u3UmR9PO15gVnK5Ueo92sG4r
+hBkjhUCUT1f/x4oiL5dWTbnhCme01bJP80emVPSf7BpIIwnGxf52ZFWq7VDZRYUS
+3MCtSm7cfpRL8AbXnONJy9BMGs4ElaIZoAB+cgp7LwIuNygVYu/oCAJgYRsKVdLZ
+eT3391t7XC9CIw25kY1O3G4oY1uxh57Oq99ZABEBAAHNHWNhdGFsb2cgPGNhdGFs
+b2dAZXhhbXBsZS5jb20+wsBiBBMBCAAWBQJq03JbCRBvv0kZjG2rtgIbAwIZAQAA
+KYkIACFcHVDZqAQfjz7mUK6mq8loBbW5bE3vCenJcIEzWjm5vf/r1JSrFu+DQq7L
+52d0RFdDhyGpell/+iNscf1wxuTyfYblbOZ79lrz7cDNPvOaUqVXsk4R7cy6PHQX
+R2pSRMqx4KCfJRgEeE8jNEEycPrES/bz08Z8eocjUScfE5SlMRsh7WfHYcMZGOa1
+FuLzjVcK6F6KDLPaD4W3zn2AZHl9Ry4iuP1GsaaCD9OWvHEEHUIYHXThJm0gmlO5
+Pgf9Czfdmsr1stddIYBZQnPo45KZxT38EiEEgDu/YAM8A8B1HS5qYGOPL+0VO2hv
+qcRlh/4Ld+gJAYA0VDT6nqKB2cLOwE0EatNyWwEIAKvpXxU9DvP6z48JOe13vrU8
+4JXzunmKX8YjhgGquq9swFhVOsRcmuRJNN+z5a+9VveZbCcMTIUfZtYUyPNDhBWw
+GGN1Et+OpVXhabRF6ivAS2NQG8ZcVhljpXzhXnSGV7xWEu97UPBmPxwlBrQvOtyW
+bZ/E+o2z1nfirxK7UL2Ii9M1bhKbcFybodbWonbj/vROzwhOUnrWOJjo5Dk9dYDa
+2+bbUjxE3n0wughOIddzZzIfJJwTjNWzjZQ0iFDEY2WHeKZiwnhBSbaoO9VMmX4i
+AAJTy9tVFPWM/YKq64i666GFdHNqh1zyLa5KMloBmVNftZbmb66LPc3E2LUN2FkA
+EQEAAcLAXwQYAQgAEwUCatNyWwkQb79JGYxtq7YCGwwAAFpHCAC9zDMBSNKrW6Vo
+5vkLme+o8bJGoP8gIt7w+LH+gRwdMXTTQVmUeeHet9c/Djhu9dSspbxKfH2MpiF9
+KHrAm/eBH1CS62j6ft8GHjGhly9mh8iwDKFoZTQu8BaiguaAqicZtcI7Zv0Vsj6X
+cuqXlBws+jOHWCvVYpIizpUUryTv8jqQkKuitBzHCsSpH8+Va/2/QW/m0OpBROoz
+YMW8XO5E6k2+xm+3n3FDhJTGX8Hx3vIObJzEI6MI6NWyOWeIOWbaXEoel10H16Ve
+JvBL00sgdzuerqZsA0HdifXtJ2ANo7nq5xsc6Dqyu3wCWj6QZF1JkLMTQvWylJ33
+oDWz/0qR
+=Y4T6
+-----END PGP PUBLIC KEY BLOCK-----
+`
+
+func Test_Resource_Release_pullChartTarball(t *testing.T) {
+	tarball := []byte("chart tarball")
+	digest := fmt.Sprintf("%x", sha256.Sum256(tarball))
+
+	testCases := []struct {
+		name             string
+		digest           string
+		cached           bool
+		pullSecret       string
+		pullError        error
+		verified         bool
+		cacheVerified    bool
+		verifiedDigest   string
+		expectedTarball  bool
+		expectedEvicted  bool
+		expectedCanceled bool
+		expectedStatus   string
+	}{
+		{
+			name:            "case 0: tarball not cached is pulled",
+			expectedTarball: true,
+		},
+		{
+			name:            "case 1: cached tarball is not pulled",
+			cached:          true,
+			pullError:       errors.New("unexpected pull"),
+			expectedTarball: true,
+		},
+		{
+			name:            "case 2: pulled tarball matches digest",
+			digest:          "sha256:" + digest,
+			expectedTarball: true,
+		},
+		{
+			name:             "case 3: pulled tarball does not match digest",
+			digest:           "sha256:0000",
+			expectedCanceled: true,
+			expectedStatus:   releasestatus.ChartVerificationFailed,
+		},
+		{
+			name:             "case 4: cached tarball does not match digest and pulled tarball neither",
+			digest:           "0000",
+			cached:           true,
+			expectedCanceled: true,
+			expectedStatus:   releasestatus.ChartVerificationFailed,
+		},
+		{
+			name:             "case 5: oci chart with missing pull secret",
+			pullSecret:       "registry-credentials",
+			expectedCanceled: true,
+			expectedStatus:   releasestatus.ChartPullFailed,
+		},
+		{
+			name:             "case 6: chart without signed provenance",
+			verified:         true,
+			expectedCanceled: true,
+			expectedStatus:   releasestatus.ChartVerificationFailed,
+		},
+		{
+			name:             "case 7: cached oci chart is not served to chart CR with missing pull secret",
+			cached:           true,
+			pullSecret:       "registry-credentials",
+			expectedCanceled: true,
+			expectedStatus:   releasestatus.ChartPullFailed,
+		},
+		{
+			name:            "case 8: cached tarball verified for the chart CR is not verified again",
+			cached:          true,
+			pullError:       errors.New("unexpected pull"),
+			verified:        true,
+			cacheVerified:   true,
+			verifiedDigest:  "sha256:" + digest,
+			expectedTarball: true,
+		},
+		{
+			name:             "case 9: cached tarball failing verification is evicted",
+			cached:           true,
+			verified:         true,
+			cacheVerified:    true,
+			expectedCanceled: true,
+			expectedEvicted:  true,
+			expectedStatus:   releasestatus.ChartVerificationFailed,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			cc := controllercontext.Context{}
+			ctx := controllercontext.NewContext(context.Background(), cc)
+			ctx = resourcecanceledcontext.NewContext(ctx, make(chan struct{}))
+
+			fs := afero.NewMemMapFs()
+
+			err := afero.WriteFile(fs, "/tmp/pulled.tgz", tarball, 0644)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			chartCache, err := chartcache.New(chartcache.Config{
+				Fs:     fs,
+				Logger: microloggertest.New(),
+
+				Directory: "/cache",
+				MaxSize:   1024,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			obj := v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-chart",
+					Namespace: "giantswarm",
+				},
+				Spec: v1alpha1.ChartSpec{
+					TarballURL: "https://giantswarm.github.io/app-catalog/test-chart-1.0.0.tgz",
+				},
+			}
+			obj.Annotations = map[string]string{}
+			if tc.digest != "" {
+				obj.Annotations[annotation.ChartDigest] = tc.digest
+			}
+			if tc.verifiedDigest != "" {
+				obj.Annotations[annotation.VerifiedDigest] = tc.verifiedDigest
+			}
+			if tc.pullSecret != "" {
+				obj.Annotations[annotation.PullSecret] = tc.pullSecret
+				obj.Spec.TarballURL = "oci://registry.example.com/app-catalog/test-chart:1.0.0"
+			}
+
+			if tc.cached {
+				_, err = chartCache.Put(ctx, obj.Spec.TarballURL, "/tmp/pulled.tgz", "")
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+				if tc.cacheVerified {
+					chartCache.SetVerified(obj.Spec.TarballURL, "sha256:"+digest)
+				}
+			}
+
+			helmClients, err := clientpair.NewClientPair(clientpair.ClientPairConfig{
+				Logger: microloggertest.New(),
+
+				PrvHelmClient: helmclienttest.New(helmclienttest.Config{}),
+				PubHelmClient: helmclienttest.New(helmclienttest.Config{}),
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			chartVerifier := newTestChartVerifier(t)
+			if tc.verified {
+				err = afero.WriteFile(fs, "/keyrings/catalog.asc", []byte(testKeyring), 0644)
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+
+				chartVerifier, err = chartverifier.New(chartverifier.Config{
+					Fs:     fs,
+					Logger: microloggertest.New(),
+
+					Keyrings: []chartverifier.Keyring{
+						{
+							URLPrefix: "https://giantswarm.github.io/app-catalog/",
+							Path:      "/keyrings/catalog.asc",
+						},
+					},
+				})
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+			}
+
+			c := Config{
+				ChartCache:     chartCache,
+				ChartVerifier:  chartVerifier,
+				Event:          &record.FakeRecorder{},
+				Fs:             fs,
+				CtrlClient:     fake.NewFakeClient(), //nolint:staticcheck
+				HelmClients:    helmClients,
+				K8sClient:      k8sfake.NewClientset(),
+				Logger:         microloggertest.New(),
+				Operations:     operation.New(),
+				ReleaseMetrics: releasemetrics.New(),
+
+				TillerNamespace: "giantswarm",
+			}
+			r, err := New(c)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			hc := helmclienttest.New(helmclienttest.Config{
+				PullChartTarballError: tc.pullError,
+				PullChartTarballPath:  "/tmp/pulled.tgz",
+			})
+
+			tarballPath, err := r.pullChartTarball(ctx, obj, hc)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if tc.expectedTarball {
+				b, err := afero.ReadFile(fs, tarballPath)
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+				if string(b) != string(tarball) {
+					t.Fatalf("tarball == %#q, want %#q", b, tarball)
+				}
+			} else if tarballPath != "" {
+				t.Fatalf("tarball path == %#q, want empty", tarballPath)
+			}
+
+			canceled := resourcecanceledcontext.IsCanceled(ctx)
+			if canceled != tc.expectedCanceled {
+				t.Fatalf("canceled == %t, want %t", canceled, tc.expectedCanceled)
+			}
+
+			resultCC, err := controllercontext.FromContext(ctx)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if resultCC.Status.Release.Status != tc.expectedStatus {
+				t.Fatalf("status == %#q, want %#q", resultCC.Status.Release.Status, tc.expectedStatus)
+			}
+
+			if tc.cached {
+				_, hit, err := chartCache.Get(ctx, obj.Spec.TarballURL, "")
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+				if hit == tc.expectedEvicted {
+					t.Fatalf("evicted == %t, want %t", !hit, tc.expectedEvicted)
+				}
+			}
+		})
+	}
+}
+
+func newTestChartCache(t *testing.T) *chartcache.Cache {
+	t.Helper()
+
+	chartCache, err := chartcache.New(chartcache.Config{
+		Fs:     afero.NewMemMapFs(),
+		Logger: microloggertest.New(),
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	return chartCache
+}
+
+func newTestChartVerifier(t *testing.T) *chartverifier.Verifier {
+	t.Helper()
+
+	chartVerifier, err := chartverifier.New(chartverifier.Config{
+		Fs:     afero.NewMemMapFs(),
+		Logger: microloggertest.New(),
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	return chartVerifier
+}
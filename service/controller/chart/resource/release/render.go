@@ -0,0 +1,104 @@
+package release
+
+import (
+	"context"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/postrender"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+)
+
+// deployedManifest returns the manifest of the currently deployed revision of
+// the Helm release. It is read from the Helm release secrets directly since
+// the Helm client does not expose release manifests.
+func (r *Resource) deployedManifest(ctx context.Context, cr v1alpha1.Chart) (string, error) {
+	rel, err := r.deployedRelease(ctx, cr)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return rel.Manifest, nil
+}
+
+// deployedRelease returns the currently deployed revision of the Helm
+// release read from the Helm release secrets. It returns
+// driver.ErrNoDeployedReleases when the release has no deployed revision.
+func (r *Resource) deployedRelease(ctx context.Context, cr v1alpha1.Chart) (*release.Release, error) {
+	s := driver.NewSecrets(r.k8sClient.CoreV1().Secrets(key.Namespace(cr)))
+	store := storage.Init(s)
+
+	rel, err := store.Deployed(key.ReleaseName(cr))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return rel, nil
+}
+
+// renderManifest renders the manifest of the chart packaged in the given
+// tarball without contacting the Kubernetes API for anything else than
+// discovering the server capabilities. It is the equivalent of running
+// `helm template --is-upgrade`.
+func (r *Resource) renderManifest(ctx context.Context, cr v1alpha1.Chart, tarballPath string, values map[string]interface{}) (string, error) {
+	rel, err := r.renderRelease(ctx, cr, tarballPath, values, true, nil)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return rel.Manifest, nil
+}
+
+// renderRelease renders the release of the chart packaged in the given
+// tarball like renderManifest, optionally for an install and with the given
+// post-renderer. Hooks are rendered but not post-rendered, like by Helm.
+func (r *Resource) renderRelease(ctx context.Context, cr v1alpha1.Chart, tarballPath string, values map[string]interface{}, isUpgrade bool, pr postrender.PostRenderer) (*release.Release, error) {
+	chart, err := loader.Load(tarballPath)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	serverVersion, err := r.k8sClient.Discovery().ServerVersion()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	apiVersions, err := action.GetVersionSet(r.k8sClient.Discovery())
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	cfg := &action.Configuration{
+		Log: func(format string, v ...interface{}) {
+			r.logger.Debugf(ctx, format, v...)
+		},
+	}
+
+	install := action.NewInstall(cfg)
+	install.APIVersions = apiVersions
+	install.ClientOnly = true
+	install.DryRun = true
+	install.IsUpgrade = isUpgrade
+	install.KubeVersion = &chartutil.KubeVersion{
+		Version: serverVersion.GitVersion,
+		Major:   serverVersion.Major,
+		Minor:   serverVersion.Minor,
+	}
+	install.Namespace = key.Namespace(cr)
+	install.PostRenderer = pr
+	install.ReleaseName = key.ReleaseName(cr)
+	install.Replace = true
+
+	rel, err := install.RunWithContext(ctx, chart, values)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return rel, nil
+}
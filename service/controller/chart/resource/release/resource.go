@@ -2,7 +2,6 @@ package release
 
 import (
 	"context"
-	"fmt"
 	"strings"
 	"time"
 
@@ -11,16 +10,23 @@ import (
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	"github.com/spf13/afero"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
 
+	"github.com/giantswarm/chart-operator/v4/service/internal/chartcache"
+	"github.com/giantswarm/chart-operator/v4/service/internal/chartverifier"
 	"github.com/giantswarm/chart-operator/v4/service/internal/clientpair"
+	"github.com/giantswarm/chart-operator/v4/service/internal/imagerewrite"
+	"github.com/giantswarm/chart-operator/v4/service/internal/maintenancewindow"
+	"github.com/giantswarm/chart-operator/v4/service/internal/operation"
+	"github.com/giantswarm/chart-operator/v4/service/internal/releasemetrics"
+	"github.com/giantswarm/chart-operator/v4/service/internal/retrybackoff"
 )
 
 const (
@@ -31,73 +37,106 @@ const (
 	// installing or updating a release before moving to process the next CR.
 	defaultK8sWaitTimeout = 10 * time.Second
 
-	// alreadyExistsStatus is set in the CR status when it failed to create
-	// a manifest object because it exists already.
-	alreadyExistsStatus = "already-exists"
-
-	// chartPullFailedStatus is set in the CR status when it failed to pull
-	// chart tarball for various reasons: network issues, tarball does not
-	// exists, connection timeout etc.
-	chartPullFailedStatus = "chart-pull-failed"
-
 	// helmSchemaViolationErrorMsg defines the error message returned by Helm on
 	// schema validation failure.
 	// See: https://github.com/helm/helm/blob/main/pkg/chartutil/values.go#L160
 	helmSchemaValidationErrorMsg = "values don't meet the specifications of the schema(s) in the following chart(s)"
+)
 
-	// invalidManifestStatus is set in the CR status when it failed to create
-	// manifest objects with helm resources.
-	invalidManifestStatus = "invalid-manifest"
-
-	// releaseNotInstalledStatus is set in the CR status when there is no Helm
-	// Release to check.
-	releaseNotInstalledStatus = "not-installed"
-
-	// unknownError when a release fails for unknown reasons.
-	unknownError = "unknown-error"
-
-	// validationFailedStatus is set in the CR status when it failed to pass
-	// OpenAPI validation on release manifest.
-	validationFailedStatus = "validation-failed"
-
-	// valuesSchemaViolation is set in the CR status when Chart has not passed
-	// values.yaml validation against schema.
-	valuesSchemaViolation = "values-schema-violation"
+// Reasons of the Kubernetes events emitted for the chart CR.
+const (
+	chartPullFailedEventReason              = "ChartPullFailed"
+	chartVerificationFailedEventReason      = "ChartVerificationFailed"
+	crdsAppliedEventReason                  = "CRDsApplied"
+	dependencyCycleEventReason              = "DependencyCycle"
+	driftDetectedEventReason                = "DriftDetected"
+	dryRunEventReason                       = "DryRun"
+	helmV2MigrationFailedEventReason        = "HelmV2MigrationFailed"
+	helmV2ReleaseMigratedEventReason        = "HelmV2ReleaseMigrated"
+	installFailedEventReason                = "InstallFailed"
+	installStartedEventReason               = "InstallStarted"
+	installSucceededEventReason             = "InstallSucceeded"
+	invalidCordonEventReason                = "InvalidCordon"
+	invalidMaintenanceWindowEventReason     = "InvalidMaintenanceWindow"
+	pendingInstallDeletedEventReason        = "PendingInstallDeleted"
+	pendingInstallDeletionFailedEventReason = "PendingInstallDeletionFailed"
+	pendingRecoveredEventReason             = "PendingRecovered"
+	rollbackFailedEventReason               = "RollbackFailed"
+	rollbackSucceededEventReason            = "RollbackSucceeded"
+	uninstalledEventReason                  = "Uninstalled"
+	upgradeFailedEventReason                = "UpgradeFailed"
+	upgradeStartedEventReason               = "UpgradeStarted"
+	upgradeSucceededEventReason             = "UpgradeSucceeded"
+	valuesSchemaViolationEventReason        = "ValuesSchemaViolation"
 )
 
 // Config represents the configuration used to create a new release resource.
 type Config struct {
 	// Dependencies.
-	Fs          afero.Fs
-	CtrlClient  client.Client
-	HelmClients *clientpair.ClientPair
-	K8sClient   kubernetes.Interface
-	Logger      micrologger.Logger
+	ChartCache     *chartcache.Cache
+	ChartVerifier  *chartverifier.Verifier
+	Event          record.EventRecorder
+	Fs             afero.Fs
+	CtrlClient     client.Client
+	HelmClients    *clientpair.ClientPair
+	K8sClient      kubernetes.Interface
+	Logger         micrologger.Logger
+	Operations     *operation.Registry
+	ReleaseMetrics *releasemetrics.Metrics
 
 	// Settings.
-	K8sWaitTimeout  time.Duration
-	MaxRollback     int
-	TillerNamespace string
+	// ImageRewriter rewrites the container images of rendered manifests.
+	// Images are not rewritten when it is nil.
+	ImageRewriter  *imagerewrite.Rewriter
+	K8sWaitTimeout time.Duration
+	// MaintenanceWindow defers upgrades of releases. The zero value does
+	// not defer upgrades.
+	MaintenanceWindow maintenancewindow.Window
+	MaxRollback       int
+	// MigrateHelmV2Releases enables migrating the Tiller config maps of
+	// Helm 2 releases to Helm 3. Chart CRs with Helm 2 releases are not
+	// reconciled when it is false.
+	MigrateHelmV2Releases bool
+	RetryPolicy           retrybackoff.Policy
+	TillerNamespace       string
 }
 
 // Resource implements the chart resource.
 type Resource struct {
 	// Dependencies.
-	fs          afero.Fs
-	ctrlClient  client.Client
-	helmClients *clientpair.ClientPair
-	k8sClient   kubernetes.Interface
-	logger      micrologger.Logger
+	chartCache     *chartcache.Cache
+	chartVerifier  *chartverifier.Verifier
+	event          record.EventRecorder
+	fs             afero.Fs
+	ctrlClient     client.Client
+	helmClients    *clientpair.ClientPair
+	k8sClient      kubernetes.Interface
+	logger         micrologger.Logger
+	operations     *operation.Registry
+	releaseMetrics *releasemetrics.Metrics
 
 	// Settings.
-	k8sWaitTimeout  time.Duration
-	maxRollback     int
-	tillerNamespace string
+	imageRewriter         *imagerewrite.Rewriter
+	k8sWaitTimeout        time.Duration
+	maintenanceWindow     maintenancewindow.Window
+	maxRollback           int
+	migrateHelmV2Releases bool
+	retryPolicy           retrybackoff.Policy
+	tillerNamespace       string
 }
 
 // New creates a new configured chart resource.
 func New(config Config) (*Resource, error) {
 	// Dependencies.
+	if config.ChartCache == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ChartCache must not be empty", config)
+	}
+	if config.ChartVerifier == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ChartVerifier must not be empty", config)
+	}
+	if config.Event == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Event must not be empty", config)
+	}
 	if config.Fs == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Fs must not be empty", config)
 	}
@@ -113,27 +152,49 @@ func New(config Config) (*Resource, error) {
 	if config.Logger == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
 	}
+	if config.Operations == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Operations must not be empty", config)
+	}
+	if config.ReleaseMetrics == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ReleaseMetrics must not be empty", config)
+	}
 
 	// Settings.
 	if config.K8sWaitTimeout == 0 {
 		config.K8sWaitTimeout = defaultK8sWaitTimeout
 	}
+	if config.RetryPolicy == (retrybackoff.Policy{}) {
+		config.RetryPolicy = retrybackoff.DefaultPolicy
+	}
+	err := config.RetryPolicy.Validate()
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.RetryPolicy is invalid: %s", config, err.Error())
+	}
 	if config.TillerNamespace == "" {
 		return nil, microerror.Maskf(invalidConfigError, "%T.TillerNamespace must not be empty", config)
 	}
 
 	r := &Resource{
 		// Dependencies.
-		fs:          config.Fs,
-		ctrlClient:  config.CtrlClient,
-		helmClients: config.HelmClients,
-		k8sClient:   config.K8sClient,
-		logger:      config.Logger,
+		chartCache:     config.ChartCache,
+		chartVerifier:  config.ChartVerifier,
+		event:          config.Event,
+		fs:             config.Fs,
+		ctrlClient:     config.CtrlClient,
+		helmClients:    config.HelmClients,
+		k8sClient:      config.K8sClient,
+		logger:         config.Logger,
+		operations:     config.Operations,
+		releaseMetrics: config.ReleaseMetrics,
 
 		// Settings.
-		k8sWaitTimeout:  config.K8sWaitTimeout,
-		maxRollback:     config.MaxRollback,
-		tillerNamespace: config.TillerNamespace,
+		imageRewriter:         config.ImageRewriter,
+		k8sWaitTimeout:        config.K8sWaitTimeout,
+		maintenanceWindow:     config.MaintenanceWindow,
+		maxRollback:           config.MaxRollback,
+		migrateHelmV2Releases: config.MigrateHelmV2Releases,
+		retryPolicy:           config.RetryPolicy,
+		tillerNamespace:       config.TillerNamespace,
 	}
 
 	return r, nil
@@ -143,20 +204,6 @@ func (r *Resource) Name() string {
 	return Name
 }
 
-func (r *Resource) findHelmV2ConfigMaps(ctx context.Context, releaseName string) (bool, error) {
-	lo := metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("%s=%s,%s=%s", "NAME", releaseName, "OWNER", "TILLER"),
-	}
-
-	// Check whether there are still helm2 release configmaps.
-	cms, err := r.k8sClient.CoreV1().ConfigMaps(r.tillerNamespace).List(ctx, lo)
-	if err != nil {
-		return false, microerror.Mask(err)
-	}
-
-	return len(cms.Items) > 0, nil
-}
-
 func (r *Resource) addAnnotation(ctx context.Context, cr v1alpha1.Chart, key, value string) error {
 	modifiedChart := cr.DeepCopy()
 
@@ -192,7 +239,8 @@ func (r *Resource) removeAnnotation(ctx context.Context, cr v1alpha1.Chart, key
 
 // addHashAnnotation updates the chart CR annotations if they have changed.
 // A patch operation is used because app-operator also sets annotations for
-// chart CRs.
+// chart CRs. The legacy MD5 checksum annotation is removed once the SHA-256
+// checksum is set.
 func (r *Resource) addHashAnnotation(ctx context.Context, cr v1alpha1.Chart, releaseState ReleaseState) error {
 	r.logger.Debugf(ctx, "patching annotations for chart CR %#q in namespace %#q", cr.Name, cr.Namespace)
 
@@ -209,10 +257,19 @@ func (r *Resource) addHashAnnotation(ctx context.Context, cr v1alpha1.Chart, rel
 		return microerror.Mask(err)
 	}
 
-	currentChecksum := key.ValuesMD5ChecksumAnnotation(currentCR)
+	if releaseState.ValuesChecksum != key.ValuesChecksumAnnotation(currentCR) ||
+		key.ValuesChecksumAlgorithmAnnotation(currentCR) != valuesChecksumAlgorithm ||
+		hasLegacyValuesChecksum(currentCR) {
+		modifiedCR := currentCR.DeepCopy()
+
+		if len(modifiedCR.Annotations) == 0 {
+			modifiedCR.Annotations = map[string]string{}
+		}
+		modifiedCR.Annotations[annotation.ValuesChecksum] = releaseState.ValuesChecksum
+		modifiedCR.Annotations[annotation.ValuesChecksumAlgorithm] = valuesChecksumAlgorithm
+		delete(modifiedCR.Annotations, annotation.ValuesMD5Checksum)
 
-	if releaseState.ValuesMD5Checksum != currentChecksum {
-		err := r.addAnnotation(ctx, currentCR, annotation.ValuesMD5Checksum, releaseState.ValuesMD5Checksum)
+		err = r.ctrlClient.Patch(ctx, modifiedCR, client.MergeFrom(&currentCR))
 		if err != nil {
 			return microerror.Mask(err)
 		}
@@ -245,6 +302,9 @@ func equals(a, b ReleaseState) bool {
 	if a.Status != b.Status {
 		return false
 	}
+	if a.ValuesChecksum != b.ValuesChecksum {
+		return false
+	}
 	if a.ValuesMD5Checksum != b.ValuesMD5Checksum {
 		return false
 	}
@@ -267,7 +327,7 @@ func isReleaseModified(a, b ReleaseState) bool {
 	result := false
 
 	if !isEmpty(a) {
-		if a.ValuesMD5Checksum != b.ValuesMD5Checksum {
+		if isValuesModified(a, b) {
 			result = true
 		}
 
@@ -283,6 +343,24 @@ func isReleaseModified(a, b ReleaseState) bool {
 	return result
 }
 
+// isValuesModified compares the values checksums of the current and desired
+// release states. When the current state only has the legacy MD5 checksum
+// it is compared instead so migrating does not upgrade the release.
+func isValuesModified(current, desired ReleaseState) bool {
+	if current.ValuesChecksum == "" && current.ValuesMD5Checksum != "" {
+		return current.ValuesMD5Checksum != desired.ValuesMD5Checksum
+	}
+
+	return current.ValuesChecksum != desired.ValuesChecksum
+}
+
+// hasLegacyValuesChecksum checks whether the chart CR still has the legacy
+// MD5 values checksum annotation.
+func hasLegacyValuesChecksum(cr v1alpha1.Chart) bool {
+	_, ok := cr.GetAnnotations()[annotation.ValuesMD5Checksum]
+	return ok
+}
+
 func isSchemaValidationError(err error) bool {
 	return strings.Split(err.Error(), ":")[0] == helmSchemaValidationErrorMsg
 }
@@ -0,0 +1,135 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
+	"github.com/giantswarm/chart-operator/v4/service/internal/retrybackoff"
+)
+
+// isBackingOff returns true when the last installs or upgrades of the chart
+// CR failed and the time of the next retry has not been reached yet. The
+// failed attempts and the next retry time are then added to the controller
+// context so the status resource reports them.
+func (r *Resource) isBackingOff(ctx context.Context, cr v1alpha1.Chart, cc *controllercontext.Context) bool {
+	attempts := key.FailedAttempts(cr)
+	if attempts == 0 {
+		return false
+	}
+
+	nextRetry := key.NextRetry(cr)
+	if !time.Now().Before(nextRetry) {
+		return false
+	}
+
+	reason := fmt.Sprintf("release %#q failed %d times in a row, next retry at %s", key.ReleaseName(cr), attempts, nextRetry.UTC().Format(time.RFC3339))
+	addStatusToContext(cc, reason, releasestatus.RetryBackoff)
+	cc.Status.Release.FailedAttempts = attempts
+	cc.Status.Release.NextRetry = nextRetry
+
+	r.logger.Debugf(ctx, "%s", reason)
+
+	return true
+}
+
+// updateFailedAttempts counts a failed install or upgrade of the chart CR in
+// the failed-attempts annotation and sets the next-retry annotation according
+// to the retry policy of the chart CR. A successful install or upgrade
+// removes both annotations. A patch operation is used because app-operator
+// also sets annotations for chart CRs.
+func (r *Resource) updateFailedAttempts(ctx context.Context, cr v1alpha1.Chart, failed bool) error {
+	// Get chart CR again to ensure the annotations are correct. The
+	// operation may have run in the background.
+	var currentCR v1alpha1.Chart
+
+	err := r.ctrlClient.Get(
+		ctx,
+		types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace},
+		&currentCR,
+	)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	modifiedCR := currentCR.DeepCopy()
+
+	if failed {
+		attempts := key.FailedAttempts(currentCR) + 1
+		nextRetry := time.Now().Add(r.chartRetryPolicy(ctx, currentCR).Delay(attempts))
+
+		if len(modifiedCR.Annotations) == 0 {
+			modifiedCR.Annotations = map[string]string{}
+		}
+		modifiedCR.Annotations[annotation.FailedAttempts] = strconv.Itoa(attempts)
+		modifiedCR.Annotations[annotation.NextRetry] = nextRetry.UTC().Format(time.RFC3339)
+
+		r.logger.Debugf(ctx, "release %#q failed %d times in a row, retrying at %s", key.ReleaseName(cr), attempts, nextRetry.UTC().Format(time.RFC3339))
+	} else {
+		_, hasAttempts := currentCR.Annotations[annotation.FailedAttempts]
+		_, hasNextRetry := currentCR.Annotations[annotation.NextRetry]
+		if !hasAttempts && !hasNextRetry {
+			return nil
+		}
+
+		delete(modifiedCR.Annotations, annotation.FailedAttempts)
+		delete(modifiedCR.Annotations, annotation.NextRetry)
+	}
+
+	err = r.ctrlClient.Patch(ctx, modifiedCR, client.MergeFrom(&currentCR))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// chartRetryPolicy returns the retry policy of the chart CR. The settings of
+// the configured policy are overridden by the retry annotations of the chart
+// CR. Invalid overrides are ignored.
+func (r *Resource) chartRetryPolicy(ctx context.Context, cr v1alpha1.Chart) retrybackoff.Policy {
+	policy := r.retryPolicy
+
+	if v := key.RetryBaseDelayAnnotation(cr); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			r.logger.Debugf(ctx, "ignoring annotation %#q, %#q is not a duration", annotation.RetryBaseDelay, v)
+		} else {
+			policy.BaseDelay = d
+		}
+	}
+	if v := key.RetryMaxDelayAnnotation(cr); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			r.logger.Debugf(ctx, "ignoring annotation %#q, %#q is not a duration", annotation.RetryMaxDelay, v)
+		} else {
+			policy.MaxDelay = d
+		}
+	}
+	if v := key.RetryJitterAnnotation(cr); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			r.logger.Debugf(ctx, "ignoring annotation %#q, %#q is not a number", annotation.RetryJitter, v)
+		} else {
+			policy.Jitter = f
+		}
+	}
+
+	err := policy.Validate()
+	if err != nil {
+		r.logger.Debugf(ctx, "ignoring retry annotations of chart CR %#q, %s", cr.Name, err.Error())
+		return r.retryPolicy
+	}
+
+	return policy
+}
@@ -0,0 +1,158 @@
+package release
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/micrologger/microloggertest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+
+	"github.com/giantswarm/chart-operator/v4/service/internal/retrybackoff"
+)
+
+func Test_chartRetryPolicy(t *testing.T) {
+	testCases := []struct {
+		name           string
+		annotations    map[string]string
+		expectedPolicy retrybackoff.Policy
+	}{
+		{
+			name:           "case 0: no overrides",
+			expectedPolicy: retrybackoff.DefaultPolicy,
+		},
+		{
+			name: "case 1: all overrides",
+			annotations: map[string]string{
+				annotation.RetryBaseDelay: "1m",
+				annotation.RetryJitter:    "0",
+				annotation.RetryMaxDelay:  "2h",
+			},
+			expectedPolicy: retrybackoff.Policy{
+				BaseDelay: time.Minute,
+				MaxDelay:  2 * time.Hour,
+				Jitter:    0,
+			},
+		},
+		{
+			name: "case 2: invalid override is ignored",
+			annotations: map[string]string{
+				annotation.RetryBaseDelay: "1m",
+				annotation.RetryMaxDelay:  "forever",
+			},
+			expectedPolicy: retrybackoff.Policy{
+				BaseDelay: time.Minute,
+				MaxDelay:  retrybackoff.DefaultPolicy.MaxDelay,
+				Jitter:    retrybackoff.DefaultPolicy.Jitter,
+			},
+		},
+		{
+			name: "case 3: invalid policy falls back to configured policy",
+			annotations: map[string]string{
+				annotation.RetryBaseDelay: "2h",
+			},
+			expectedPolicy: retrybackoff.DefaultPolicy,
+		},
+	}
+
+	r := &Resource{
+		logger:      microloggertest.New(),
+		retryPolicy: retrybackoff.DefaultPolicy,
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			cr := v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: tc.annotations,
+				},
+			}
+
+			result := r.chartRetryPolicy(context.Background(), cr)
+			if result != tc.expectedPolicy {
+				t.Fatalf("policy == %#v, want %#v", result, tc.expectedPolicy)
+			}
+		})
+	}
+}
+
+func Test_updateFailedAttempts(t *testing.T) {
+	ctx := context.Background()
+
+	s := runtime.NewScheme()
+	err := v1alpha1.AddToScheme(s)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	cr := v1alpha1.Chart{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "hello-world",
+			Namespace: "giantswarm",
+		},
+	}
+
+	ctrlClient := fake.NewClientBuilder().WithScheme(s).WithObjects(cr.DeepCopy()).Build()
+
+	r := &Resource{
+		ctrlClient: ctrlClient,
+		logger:     microloggertest.New(),
+		retryPolicy: retrybackoff.Policy{
+			BaseDelay: time.Minute,
+			MaxDelay:  time.Hour,
+		},
+	}
+
+	getCR := func() v1alpha1.Chart {
+		var current v1alpha1.Chart
+
+		err := ctrlClient.Get(ctx, types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace}, &current)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+
+		return current
+	}
+
+	for i := 1; i <= 3; i++ {
+		err = r.updateFailedAttempts(ctx, cr, true)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+
+		current := getCR()
+		if key.FailedAttempts(current) != i {
+			t.Fatalf("failed attempts == %d, want %d", key.FailedAttempts(current), i)
+		}
+
+		// The delay is doubled with every failed attempt.
+		delay := time.Until(key.NextRetry(current))
+		expected := time.Duration(1<<(i-1)) * time.Minute
+		if delay > expected || delay < expected-5*time.Second {
+			t.Fatalf("next retry in %s, want %s", delay, expected)
+		}
+	}
+
+	err = r.updateFailedAttempts(ctx, cr, false)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	current := getCR()
+	if _, ok := current.Annotations[annotation.FailedAttempts]; ok {
+		t.Fatalf("annotation %#q found, want removed", annotation.FailedAttempts)
+	}
+	if _, ok := current.Annotations[annotation.NextRetry]; ok {
+		t.Fatalf("annotation %#q found, want removed", annotation.NextRetry)
+	}
+}
@@ -6,6 +6,20 @@ type Patch struct {
 	Value interface{} `json:"value"`
 }
 
+// Dependency references a chart CR which must be deployed before the release
+// of the depending chart CR is installed or upgraded. The dependencies of a
+// chart CR are set in the depends-on annotation.
+type Dependency struct {
+	// Name is the name of the chart CR.
+	Name string `json:"name"`
+	// Namespace is the namespace of the chart CR. It defaults to the
+	// namespace of the depending chart CR.
+	Namespace string `json:"namespace,omitempty"`
+	// Version is a semver constraint the deployed version of the chart CR
+	// must satisfy, e.g. >=1.2.0. Any version satisfies an empty constraint.
+	Version string `json:"version,omitempty"`
+}
+
 // ReleaseState holds the state of the Helm release to be reconciled.
 type ReleaseState struct {
 	// Name is the name of the Helm release when the chart is deployed.
@@ -14,10 +28,18 @@ type ReleaseState struct {
 	// Status is the status of the Helm release when the chart is deployed.
 	// e.g. DEPLOYED
 	Status string
-	// ValuesMD5Checksum is the MD5 checksum of the values YAML. It is used for
-	// comparison since it is more reliable than using the values returned by
-	// helmclient.GetReleaseContent.
+	// ValuesChecksum is the SHA-256 checksum of the canonical values JSON.
+	// It is used for comparison since it is more reliable than using the
+	// values returned by helmclient.GetReleaseContent.
+	ValuesChecksum string
+	// ValuesMD5Checksum is the legacy MD5 checksum of the values. It is
+	// compared when the chart CR has not been migrated to the SHA-256
+	// checksum yet.
 	ValuesMD5Checksum string
+	// PostRenderPatches are the Kustomize patches of the config map
+	// referenced by the post-render-patches annotation keyed by their config
+	// map key.
+	PostRenderPatches map[string]string
 	// Values are any values that have been set when the Helm Chart was
 	// installed.
 	Values map[string]interface{}
@@ -25,3 +47,18 @@ type ReleaseState struct {
 	// e.g. 0.1.2
 	Version string
 }
+
+// ValuesSource references a config map or secret holding Helm values. The
+// values sources of a chart CR are set in the values-sources annotation.
+type ValuesSource struct {
+	// Kind is the kind of the values source, either ConfigMap or Secret.
+	Kind string `json:"kind"`
+	// Name is the name of the config map or secret.
+	Name string `json:"name"`
+	// Namespace is the namespace of the config map or secret. It defaults
+	// to the namespace of the chart CR.
+	Namespace string `json:"namespace,omitempty"`
+	// Key selects a single key of the config map or secret. When empty all
+	// keys are merged in alphabetical order.
+	Key string `json:"key,omitempty"`
+}
@@ -2,6 +2,8 @@ package release
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
@@ -15,12 +17,14 @@ import (
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/storage"
 	"helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
-	"github.com/giantswarm/chart-operator/v4/pkg/project"
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
+	"github.com/giantswarm/chart-operator/v4/service/internal/operation"
 )
 
 func (r *Resource) ApplyUpdateChange(ctx context.Context, obj, updateChange interface{}) error {
@@ -46,42 +50,34 @@ func (r *Resource) ApplyUpdateChange(ctx context.Context, obj, updateChange inte
 		return nil
 	}
 
-	r.logger.Debugf(ctx, "updating release %#q in namespace %#q", releaseState.Name, key.Namespace(cr))
-
-	tarballURL := key.TarballURL(cr)
-	tarballPath, err := hc.PullChartTarball(ctx, tarballURL)
-	if helmclient.IsPullChartFailedError(err) {
-		reason := fmt.Sprintf("pulling chart %#q failed", tarballURL)
-		addStatusToContext(cc, reason, chartPullFailedStatus)
+	if key.HasDryRunAnnotation(cr) {
+		err = r.dryRunUpdate(ctx, cr, hc, releaseState)
+		if err != nil {
+			return microerror.Mask(err)
+		}
 
-		r.logger.LogCtx(ctx, "level", "warning", "message", reason, "stack", microerror.JSON(err))
 		r.logger.Debugf(ctx, "canceling resource")
 		resourcecanceledcontext.SetCanceled(ctx)
 		return nil
-	} else if helmclient.IsPullChartNotFound(err) {
-		reason := fmt.Sprintf("chart %#q not found", tarballURL)
-		addStatusToContext(cc, reason, chartPullFailedStatus)
+	}
 
-		r.logger.LogCtx(ctx, "level", "warning", "message", reason, "stack", microerror.JSON(err))
-		r.logger.Debugf(ctx, "canceling resource")
-		resourcecanceledcontext.SetCanceled(ctx)
-		return nil
-	} else if helmclient.IsPullChartTimeout(err) {
-		reason := fmt.Sprintf("timeout pulling %#q", tarballURL)
-		addStatusToContext(cc, reason, chartPullFailedStatus)
+	r.logger.Debugf(ctx, "updating release %#q in namespace %#q", releaseState.Name, key.Namespace(cr))
 
-		r.logger.LogCtx(ctx, "level", "warning", "message", reason, "stack", microerror.JSON(err))
-		r.logger.Debugf(ctx, "canceling resource")
-		resourcecanceledcontext.SetCanceled(ctx)
-		return nil
-	} else if err != nil {
+	tarballPath, err := r.pullChartTarball(ctx, cr, hc)
+	if err != nil {
 		return microerror.Mask(err)
 	}
+	if tarballPath == "" {
+		// Pulling the chart failed and the resource was canceled.
+		return nil
+	}
 
+	// The tarball is owned by the background operation once it is started,
+	// as the operation may outlive this function.
+	tarballOwned := false
 	defer func() {
-		err := r.fs.Remove(tarballPath)
-		if err != nil {
-			r.logger.Errorf(ctx, err, "deletion of %#q failed", tarballPath)
+		if !tarballOwned {
+			r.removeTarball(ctx, tarballPath)
 		}
 	}()
 
@@ -97,14 +93,66 @@ func (r *Resource) ApplyUpdateChange(ctx context.Context, obj, updateChange inte
 
 	timeout := key.UpgradeTimeout(cr)
 
-	ch := make(chan error)
+	crdTimeout := defaultCRDEstablishedTimeout
+	if timeout != nil {
+		crdTimeout = (*timeout).Duration
+	}
+
+	if r.cancelOnInvalidCRDPolicy(ctx, cr, cc) {
+		return nil
+	}
+
+	o, started := r.operations.Start(cr.UID, operation.Operation{
+		Kind:           operation.Upgrade,
+		Release:        releaseState.Name,
+		Version:        releaseState.Version,
+		ValuesChecksum: releaseState.ValuesChecksum,
+	})
+	if !started {
+		r.logger.Debugf(ctx, "not updating release %#q, %s to version %#q is still running", releaseState.Name, o.Kind, o.Version)
+		r.logger.Debugf(ctx, "canceling resource")
+		resourcecanceledcontext.SetCanceled(ctx)
+		return nil
+	}
+
+	r.event.Eventf(&cr, corev1.EventTypeNormal, upgradeStartedEventReason, "upgrading release %#q to version %#q", releaseState.Name, releaseState.Version)
+
+	ch := make(chan error, 1)
+	start := time.Now()
+	tarballOwned = true
 
 	// We update the helm release but with a wait timeout so we don't
 	// block reconciling other CRs.
 	//
 	// If we do timeout the update will continue in the background.
-	// We will check the progress in the next reconciliation loop.
+	// Its result is recorded in the operation registry and checked in the
+	// next reconciliation loop.
 	go func() {
+		var changes crdChanges
+		var e error
+
+		defer func() {
+			r.operations.Finish(cr.UID, e)
+			r.observeOperation(cr, upgradeOperation, start, e)
+
+			// The result is sent before patching the Chart CR so the API
+			// calls below do not count against the k8s wait timeout. CRD
+			// changes are only reported once the operation has finished,
+			// also when it continued in the background.
+			ch <- e
+
+			err := r.reportCRDChanges(ctx, cr, changes)
+			if err != nil {
+				r.logger.Errorf(ctx, err, "reporting CRD changes of release %#q failed", releaseState.Name)
+			}
+
+			err = r.updateFailedAttempts(ctx, cr, e != nil)
+			if err != nil {
+				r.logger.Errorf(ctx, err, "updating failed attempts of release %#q failed", releaseState.Name)
+			}
+		}()
+		defer r.removeTarball(ctx, tarballPath)
+
 		opts := helmclient.UpdateOptions{
 			Force: false,
 		}
@@ -114,25 +162,48 @@ func (r *Resource) ApplyUpdateChange(ctx context.Context, obj, updateChange inte
 			opts.Timeout = (*timeout).Duration
 		}
 
+		changes, e = r.applyCRDPolicy(ctx, cr, tarballPath, crdTimeout)
+		if e != nil {
+			return
+		}
+
+		// Charts subject to the two-step installation may introduce new CRDs
+		// together with CRs of these kinds. Helm does not create CRDs on
+		// upgrades and cannot create CRs of kinds it does not know yet, so
+		// the new CRDs are created first.
+		chart, e := hc.LoadChart(ctx, tarballPath)
+		if e != nil {
+			return
+		}
+		if _, ok := chart.Annotations[subjectToTwoStepInstall]; ok {
+			e = r.applyNewCRDs(ctx, cr, tarballPath, releaseState.Values, crdTimeout)
+			if e != nil {
+				return
+			}
+		}
+
+		// The Helm client does not support post-renderers, so releases with
+		// post-render patches are upgraded from a post-rendered chart.
+		chartPath, e := r.postRender(ctx, cr, tarballPath, releaseState, true)
+		if e != nil {
+			return
+		}
+		if chartPath != tarballPath {
+			defer r.removePostRenderedChart(ctx, chartPath)
+		}
+
 		// We need to pass the ValueOverrides option to make the update process
 		// use the default values and prevent errors on nested values.
-		err = hc.UpdateReleaseFromTarball(ctx,
-			tarballPath,
+		e = hc.UpdateReleaseFromTarball(ctx,
+			chartPath,
 			key.Namespace(cr),
 			releaseState.Name,
 			releaseState.Values,
 			opts)
-
-		close(ch)
-		/*
-			The two-step installation is not supported on upgrades, yet it could be
-			useful in times when app is already installed in version A, and is being
-			upgraded to version B that introduces new CRDs, and CRs of these kinds.
-		*/
 	}()
 
 	select {
-	case <-ch:
+	case err = <-ch:
 		// Fall through.
 	case <-time.After(r.k8sWaitTimeout):
 		r.logger.Debugf(ctx, "waited for %d secs. release still being updated", int64(r.k8sWaitTimeout.Seconds()))
@@ -153,7 +224,8 @@ func (r *Resource) ApplyUpdateChange(ctx context.Context, obj, updateChange inte
 		reason := err.Error()
 		reason = fmt.Sprintf("resource already exists: (%s)", reason)
 		r.logger.Debugf(ctx, "helm release %#q failed, %s", releaseState.Name, reason)
-		addStatusToContext(cc, reason, alreadyExistsStatus)
+		addStatusToContext(cc, reason, releasestatus.AlreadyExists)
+		r.event.Event(&cr, corev1.EventTypeWarning, upgradeFailedEventReason, reason)
 
 		r.logger.Debugf(ctx, "canceling resource")
 		resourcecanceledcontext.SetCanceled(ctx)
@@ -162,7 +234,8 @@ func (r *Resource) ApplyUpdateChange(ctx context.Context, obj, updateChange inte
 		reason := err.Error()
 		reason = fmt.Sprintf("helm validation error: (%s)", reason)
 		r.logger.Debugf(ctx, "helm release %#q failed, %s", releaseState.Name, reason)
-		addStatusToContext(cc, reason, validationFailedStatus)
+		addStatusToContext(cc, reason, releasestatus.ValidationFailed)
+		r.event.Event(&cr, corev1.EventTypeWarning, upgradeFailedEventReason, reason)
 
 		r.logger.Debugf(ctx, "canceling resource")
 		resourcecanceledcontext.SetCanceled(ctx)
@@ -171,7 +244,26 @@ func (r *Resource) ApplyUpdateChange(ctx context.Context, obj, updateChange inte
 		reason := err.Error()
 		reason = fmt.Sprintf("invalid manifest error: (%s)", reason)
 		r.logger.Debugf(ctx, "helm release %#q failed, %s", releaseState.Name, reason)
-		addStatusToContext(cc, reason, invalidManifestStatus)
+		addStatusToContext(cc, reason, releasestatus.InvalidManifest)
+		r.event.Event(&cr, corev1.EventTypeWarning, upgradeFailedEventReason, reason)
+
+		r.logger.Debugf(ctx, "canceling resource")
+		resourcecanceledcontext.SetCanceled(ctx)
+		return nil
+	} else if IsPostRenderFailed(err) {
+		reason := fmt.Sprintf("post-render error: (%s)", err.Error())
+		r.logger.Debugf(ctx, "helm release %#q failed, %s", releaseState.Name, reason)
+		addStatusToContext(cc, reason, releasestatus.PostRenderFailed)
+		r.event.Event(&cr, corev1.EventTypeWarning, upgradeFailedEventReason, reason)
+
+		r.logger.Debugf(ctx, "canceling resource")
+		resourcecanceledcontext.SetCanceled(ctx)
+		return nil
+	} else if IsCRDStoredVersionRemoved(err) {
+		reason := fmt.Sprintf("CRD policy error: (%s)", err.Error())
+		r.logger.Debugf(ctx, "helm release %#q failed, %s", releaseState.Name, reason)
+		addStatusToContext(cc, reason, releasestatus.CRDPolicyFailed)
+		r.event.Event(&cr, corev1.EventTypeWarning, upgradeFailedEventReason, reason)
 
 		r.logger.Debugf(ctx, "canceling resource")
 		resourcecanceledcontext.SetCanceled(ctx)
@@ -180,7 +272,8 @@ func (r *Resource) ApplyUpdateChange(ctx context.Context, obj, updateChange inte
 		releaseContent, relErr := hc.GetReleaseContent(ctx, key.Namespace(cr), releaseState.Name)
 		if helmclient.IsReleaseNotFound(relErr) {
 			reason := fmt.Sprintf("release %#q not found", releaseState.Name)
-			addStatusToContext(cc, reason, releaseNotInstalledStatus)
+			addStatusToContext(cc, reason, releasestatus.NotInstalled)
+			r.event.Event(&cr, corev1.EventTypeWarning, upgradeFailedEventReason, reason)
 
 			r.logger.Debugf(ctx, "canceling resource")
 			resourcecanceledcontext.SetCanceled(ctx)
@@ -192,7 +285,15 @@ func (r *Resource) ApplyUpdateChange(ctx context.Context, obj, updateChange inte
 		// Release is failed so the status resource will check the Helm release.
 		if releaseContent.Status == helmclient.StatusFailed {
 			addStatusToContext(cc, releaseContent.Description, helmclient.StatusFailed)
+			r.event.Event(&cr, corev1.EventTypeWarning, upgradeFailedEventReason, releaseContent.Description)
 			r.logger.Debugf(ctx, "failed to update release %#q", releaseContent.Name)
+
+			if key.HasAtomicUpgradeAnnotation(cr) {
+				_, err = r.atomicRollback(ctx, cr, hc, releaseState.Version, releaseContent.Description)
+				if err != nil {
+					return microerror.Mask(err)
+				}
+			}
 		} else if releaseContent.Status == helmclient.StatusPendingUpgrade {
 			// (ljakimczuk): this is a cosmetic change and is not really needed. Without it,
 			// we will get the `unknown` error in the logs indicating operation is in progress,
@@ -207,10 +308,12 @@ func (r *Resource) ApplyUpdateChange(ctx context.Context, obj, updateChange inte
 			r.logger.Debugf(ctx, "updating release %#q is already on-going", releaseContent.Name)
 		} else if isSchemaValidationError(err) {
 			r.logger.Errorf(ctx, err, "values schema validation for %#q failed", releaseState.Name)
-			addStatusToContext(cc, err.Error(), valuesSchemaViolation)
+			addStatusToContext(cc, err.Error(), releasestatus.ValuesSchemaViolation)
+			r.event.Event(&cr, corev1.EventTypeWarning, valuesSchemaViolationEventReason, err.Error())
 		} else {
 			r.logger.Errorf(ctx, err, "helm release %#q failed", releaseState.Name)
-			addStatusToContext(cc, err.Error(), unknownError)
+			addStatusToContext(cc, err.Error(), releasestatus.UnknownError)
+			r.event.Event(&cr, corev1.EventTypeWarning, upgradeFailedEventReason, err.Error())
 		}
 
 		r.logger.Debugf(ctx, "canceling resource")
@@ -219,6 +322,7 @@ func (r *Resource) ApplyUpdateChange(ctx context.Context, obj, updateChange inte
 	}
 
 	r.logger.Debugf(ctx, "updated release %#q in namespace %#q", releaseState.Name, key.Namespace(cr))
+	r.event.Eventf(&cr, corev1.EventTypeNormal, upgradeSucceededEventReason, "upgraded release %#q to version %#q", releaseState.Name, releaseState.Version)
 
 	// We set the checksum annotation so the update state calculation
 	// is accurate when we check in the next reconciliation loop.
@@ -227,9 +331,104 @@ func (r *Resource) ApplyUpdateChange(ctx context.Context, obj, updateChange inte
 		return microerror.Mask(err)
 	}
 
+	err = r.removeAnnotation(ctx, cr, annotation.AtomicRollbackCount)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
 	return nil
 }
 
+// dryRunUpdate renders the chart that would be upgraded to and diffs it with
+// the manifest of the deployed release. The summary is reported in the CR
+// status and the release is not upgraded. The chart is only pulled and
+// diffed again when the chart, the values or the deployed revision changed
+// since the last diff, and the event is only emitted when the summary
+// changed.
+func (r *Resource) dryRunUpdate(ctx context.Context, cr v1alpha1.Chart, hc helmclient.Interface, releaseState ReleaseState) error {
+	cc, err := controllercontext.FromContext(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.logger.Debugf(ctx, "release %#q has dry-run enabled, diffing instead of updating", releaseState.Name)
+
+	var currentManifest string
+	var currentRevision int
+	{
+		rel, err := r.deployedRelease(ctx, cr)
+		if errors.Is(err, driver.ErrNoDeployedReleases) {
+			// There is no deployed revision to compare with, e.g. because
+			// the release failed. So everything would be added.
+		} else if err != nil {
+			return microerror.Mask(err)
+		} else {
+			currentManifest = rel.Manifest
+			currentRevision = rel.Version
+		}
+	}
+
+	checksum := dryRunChecksum(cr, releaseState, currentRevision)
+	if checksum == key.DryRunChecksumAnnotation(cr) && cr.Status.Release.Status == releasestatus.DryRun {
+		r.logger.Debugf(ctx, "release %#q has not changed since the last dry-run", releaseState.Name)
+		addStatusToContext(cc, cr.Status.Reason, releasestatus.DryRun)
+		return nil
+	}
+
+	tarballPath, err := r.pullChartTarball(ctx, cr, hc)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if tarballPath == "" {
+		// Pulling the chart failed and the resource was canceled.
+		return nil
+	}
+	defer r.removeTarball(ctx, tarballPath)
+
+	pr, err := r.postRenderer(cr, releaseState)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	rel, err := r.renderRelease(ctx, cr, tarballPath, releaseState.Values, true, pr)
+	if err != nil {
+		reason := fmt.Sprintf("dry-run: rendering chart %#q failed: (%s)", key.TarballURL(cr), err.Error())
+		addStatusToContext(cc, reason, releasestatus.DryRun)
+
+		r.logger.LogCtx(ctx, "level", "warning", "message", reason, "stack", microerror.JSON(err))
+		return nil
+	}
+
+	d, err := diffManifests(currentManifest, rel.Manifest)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	reason := fmt.Sprintf("dry-run: upgrade to version %#q would result in %s", releaseState.Version, d.String())
+	addStatusToContext(cc, reason, releasestatus.DryRun)
+
+	if reason != cr.Status.Reason {
+		r.event.Event(&cr, corev1.EventTypeNormal, dryRunEventReason, reason)
+	}
+
+	err = r.addAnnotation(ctx, cr, annotation.DryRunChecksum, checksum)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.logger.Debugf(ctx, "diffed release %#q, %s", releaseState.Name, d.String())
+
+	return nil
+}
+
+// dryRunChecksum returns the checksum of the chart, the values and the
+// deployed revision a dry-run diff is computed for.
+func dryRunChecksum(cr v1alpha1.Chart, releaseState ReleaseState, revision int) string {
+	data := fmt.Sprintf("%s\n%s\n%s\n%d", key.TarballURL(cr), releaseState.Version, releaseState.ValuesChecksum, revision)
+
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(data)))
+}
+
 func (r *Resource) NewUpdatePatch(ctx context.Context, obj, currentState, desiredState interface{}) (*crud.Patch, error) {
 	create, err := r.newCreateChange(ctx, obj, currentState, desiredState)
 	if err != nil {
@@ -292,19 +491,49 @@ func (r *Resource) newUpdateChange(ctx context.Context, obj, currentState, desir
 		r.tryRecoverFromPending(ctx, cr, &currentReleaseState)
 	}
 
-	// We check the controller context and if the release has failed more
-	// than the max number of attempts we stop updating. Otherwise too many
-	// release secrets will be created.
-	//
-	// The releasemaxhistory resource handles deleting the oldest secret if
-	// it is over 1 minute old. So we still retry but at a slower rate.
-	if currentReleaseState.Status == helmclient.StatusFailed && cc.Status.Release.FailedMaxAttempts {
-		r.logger.Debugf(ctx, "the %#q release is in status %#q and has failed %d times", desiredReleaseState.Name, currentReleaseState.Status, project.ReleaseFailedMaxAttempts)
-		return nil, nil
+	// An upgrade which continued in the background may have failed since
+	// the last reconciliation loop. Atomic releases are rolled back to the
+	// last deployed revision before upgrading again.
+	if currentReleaseState.Status == helmclient.StatusFailed && key.HasAtomicUpgradeAnnotation(cr) {
+		hc := r.helmClients.Get(ctx, cr, false)
+
+		rolledBack, err := r.atomicRollback(ctx, cr, hc, currentReleaseState.Version, "release is in failed status")
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if rolledBack {
+			// no-op after rollback
+			return nil, nil
+		}
 	}
 
 	if isReleaseModified(currentReleaseState, desiredReleaseState) {
-		// Ignoring `Values` in diff since it could contain secret data and we use MD5 hash for comparison.
+		// Failed installs and upgrades are retried with an exponential
+		// back-off so failing releases do not create too many release
+		// secrets.
+		if r.isBackingOff(ctx, cr, cc) {
+			r.logger.Debugf(ctx, "the %#q release does not need to be updated yet", desiredReleaseState.Name)
+			return nil, nil
+		}
+
+		waiting, err := r.isWaitingForDependencies(ctx, cr, cc)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if waiting {
+			r.logger.Debugf(ctx, "the %#q release does not need to be updated yet", desiredReleaseState.Name)
+			return nil, nil
+		}
+
+		// Version and values changes are deferred until the maintenance
+		// window of the chart CR opens. Installs and deletions are not
+		// affected.
+		if r.isOutsideMaintenanceWindow(ctx, cr, cc, time.Now()) {
+			r.logger.Debugf(ctx, "the %#q release does not need to be updated yet", desiredReleaseState.Name)
+			return nil, nil
+		}
+
+		// Ignoring `Values` in diff since it could contain secret data and we use a checksum for comparison.
 		opt := cmp.FilterPath(func(p cmp.Path) bool {
 			return p.String() == "Values"
 		}, cmp.Ignore())
@@ -316,10 +545,42 @@ func (r *Resource) newUpdateChange(ctx context.Context, obj, currentState, desir
 		return &desiredReleaseState, nil
 	}
 
+	// The release is up to date. Chart CRs which still use the legacy MD5
+	// checksum are migrated to the SHA-256 checksum.
+	if hasLegacyValuesChecksum(cr) && !isEmpty(currentReleaseState) {
+		err = r.addHashAnnotation(ctx, cr, desiredReleaseState)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	err = r.removeAnnotation(ctx, cr, annotation.RollbackCount)
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
+	err = r.removeAnnotation(ctx, cr, annotation.AtomicRollbackCount)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// Objects of the deployed release may have been modified or deleted in
+	// the cluster. Chart CRs with self-healing upgrade the release to the same
+	// version and values so Helm restores them.
+	if currentReleaseState.Status == helmclient.StatusDeployed {
+		drifted, err := r.isDrifted(ctx, cr, cc)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if drifted && !r.isBackingOff(ctx, cr, cc) {
+			err = r.addSelfHealAttempt(ctx, cr)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+
+			r.logger.Debugf(ctx, "release %#q has to be updated to restore drifted objects", desiredReleaseState.Name)
+			return &desiredReleaseState, nil
+		}
+	}
 
 	return nil, nil
 }
@@ -345,7 +606,7 @@ func (r *Resource) rollback(ctx context.Context, obj interface{}, currentStatus
 		}
 	}
 
-	if rollbackCount > r.maxRollback {
+	if rollbackCount >= r.maxRollback {
 		r.logger.Debugf(ctx, "the %#q release is in status %#q and has reached max %d rollbacks", key.ReleaseName(cr), currentStatus, r.maxRollback)
 		return nil
 	}
@@ -360,12 +621,16 @@ func (r *Resource) rollback(ctx context.Context, obj interface{}, currentStatus
 			opts.Timeout = (*timeout).Duration
 		}
 
+		start := time.Now()
 		err = hc.DeleteRelease(ctx, key.Namespace(cr), key.ReleaseName(cr), opts)
+		r.observeOperation(cr, deleteOperation, start, err)
 		if err != nil {
+			r.event.Eventf(&cr, corev1.EventTypeWarning, pendingInstallDeletionFailedEventReason, "deleting release %#q in %#q status failed: %s", key.ReleaseName(cr), currentStatus, err.Error())
 			return microerror.Mask(err)
 		}
 
 		r.logger.Debugf(ctx, "deleted release %#q", key.ReleaseName(cr))
+		r.event.Eventf(&cr, corev1.EventTypeNormal, pendingInstallDeletedEventReason, "deleted release %#q in %#q status", key.ReleaseName(cr), currentStatus)
 	} else {
 		r.logger.Debugf(ctx, "rollback release %#q in %#q status", key.ReleaseName(cr), currentStatus)
 
@@ -378,12 +643,16 @@ func (r *Resource) rollback(ctx context.Context, obj interface{}, currentStatus
 		}
 
 		// Rollback to revision 0 restore a release to the previous revision.
+		start := time.Now()
 		err = hc.Rollback(ctx, key.Namespace(cr), key.ReleaseName(cr), 0, opts)
+		r.observeOperation(cr, rollbackOperation, start, err)
 		if err != nil {
+			r.event.Eventf(&cr, corev1.EventTypeWarning, rollbackFailedEventReason, "rollback of release %#q in %#q status failed: %s", key.ReleaseName(cr), currentStatus, err.Error())
 			return microerror.Mask(err)
 		}
 
 		r.logger.Debugf(ctx, "rollbacked release %#q", key.ReleaseName(cr))
+		r.event.Eventf(&cr, corev1.EventTypeNormal, rollbackSucceededEventReason, "rolled back release %#q from %#q status", key.ReleaseName(cr), currentStatus)
 	}
 
 	err = r.addAnnotation(ctx, cr, annotation.RollbackCount, fmt.Sprintf("%d", rollbackCount+1))
@@ -425,6 +694,7 @@ func (r *Resource) tryRecoverFromPending(ctx context.Context, cr v1alpha1.Chart,
 	rs.Status = string(release.StatusUnknown)
 
 	r.logger.Debugf(ctx, "recovered from `pending` status for release %#q", rs.Name)
+	r.event.Eventf(&cr, corev1.EventTypeWarning, pendingRecoveredEventReason, "recovered release %#q revision %d from pending status after its timeout elapsed", rs.Name, rel.Version)
 }
 
 func getTimeout(cr v1alpha1.Chart, rev int) *metav1.Duration {
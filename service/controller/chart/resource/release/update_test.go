@@ -2,19 +2,40 @@ package release
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
 	"github.com/giantswarm/helmclient/v4/pkg/helmclienttest"
 	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/giantswarm/operatorkit/v7/pkg/controller/context/resourcecanceledcontext"
 	"github.com/spf13/afero"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake" //nolint:staticcheck
 
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
-
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
+	"github.com/giantswarm/chart-operator/v4/service/internal/chartcache"
 	"github.com/giantswarm/chart-operator/v4/service/internal/clientpair"
+	"github.com/giantswarm/chart-operator/v4/service/internal/operation"
+	"github.com/giantswarm/chart-operator/v4/service/internal/releasemetrics"
 )
 
 func Test_Resource_Release_newUpdateChange(t *testing.T) {
@@ -146,6 +167,41 @@ func Test_Resource_Release_newUpdateChange(t *testing.T) {
 				Version: "release-version",
 			},
 		},
+		{
+			name: "case 8: current state has different checksum, expected desired state",
+			currentState: &ReleaseState{
+				Name:           "release-name",
+				ValuesChecksum: "old-checksum",
+				Version:        "release-version",
+			},
+			desiredState: &ReleaseState{
+				Name:              "release-name",
+				ValuesChecksum:    "new-checksum",
+				ValuesMD5Checksum: "md5-checksum",
+				Version:           "release-version",
+			},
+			expectedUpdateState: &ReleaseState{
+				Name:              "release-name",
+				ValuesChecksum:    "new-checksum",
+				ValuesMD5Checksum: "md5-checksum",
+				Version:           "release-version",
+			},
+		},
+		{
+			name: "case 9: current state only has equal legacy checksum, empty update change",
+			currentState: &ReleaseState{
+				Name:              "release-name",
+				ValuesMD5Checksum: "md5-checksum",
+				Version:           "release-version",
+			},
+			desiredState: &ReleaseState{
+				Name:              "release-name",
+				ValuesChecksum:    "checksum",
+				ValuesMD5Checksum: "md5-checksum",
+				Version:           "release-version",
+			},
+			expectedUpdateState: nil,
+		},
 	}
 	var newResource *Resource
 	{
@@ -160,11 +216,16 @@ func Test_Resource_Release_newUpdateChange(t *testing.T) {
 		}
 
 		c := Config{
-			Fs:          afero.NewMemMapFs(),
-			CtrlClient:  fake.NewFakeClient(), //nolint:staticcheck
-			HelmClients: helmClients,
-			K8sClient:   k8sfake.NewClientset(),
-			Logger:      microloggertest.New(),
+			ChartCache:     newTestChartCache(t),
+			ChartVerifier:  newTestChartVerifier(t),
+			Event:          &record.FakeRecorder{},
+			Fs:             afero.NewMemMapFs(),
+			CtrlClient:     fake.NewFakeClient(), //nolint:staticcheck
+			HelmClients:    helmClients,
+			K8sClient:      k8sfake.NewClientset(),
+			Logger:         microloggertest.New(),
+			Operations:     operation.New(),
+			ReleaseMetrics: releasemetrics.New(),
 
 			TillerNamespace: "giantswarm",
 		}
@@ -205,3 +266,446 @@ func Test_Resource_Release_newUpdateChange(t *testing.T) {
 		})
 	}
 }
+
+// backgroundHelmClient reports whether the chart tarball still exists when
+// the release is upgraded.
+type backgroundHelmClient struct {
+	helmclient.Interface
+
+	updated chan error
+}
+
+func (c *backgroundHelmClient) UpdateReleaseFromTarball(ctx context.Context, chartPath, namespace, releaseName string, values map[string]interface{}, options helmclient.UpdateOptions) error {
+	_, err := os.Stat(chartPath)
+	c.updated <- err
+	return nil
+}
+
+func Test_Resource_Release_ApplyUpdateChange_slowCRDWait(t *testing.T) {
+	ctx := controllercontext.NewContext(context.Background(), controllercontext.Context{})
+	ctx = resourcecanceledcontext.NewContext(ctx, make(chan struct{}))
+
+	// The chart has a CRD in its crds directory which is created according
+	// to the CRD policy before the release is upgraded.
+	var tarballPath string
+	{
+		chartDir := filepath.Join(t.TempDir(), "hello-world")
+
+		err := os.MkdirAll(filepath.Join(chartDir, "crds"), 0755)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		err = os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("apiVersion: v2\nname: hello-world\nversion: 1.0.0\n"), 0600)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		err = os.WriteFile(filepath.Join(chartDir, "crds", "greetings.yaml"), []byte(greetingsCRD), 0600)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+
+		chart, err := loader.Load(chartDir)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		tarballPath, err = chartutil.Save(chart, t.TempDir())
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+	}
+
+	cr := &v1alpha1.Chart{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "hello-world",
+			Namespace: "giantswarm",
+			Annotations: map[string]string{
+				annotation.CRDPolicy: crdPolicyCreate,
+			},
+		},
+		Spec: v1alpha1.ChartSpec{
+			Name:       "hello-world",
+			Namespace:  "default",
+			TarballURL: "https://giantswarm.github.io/app-catalog/hello-world-1.0.0.tgz",
+			Upgrade: v1alpha1.ChartSpecUpgrade{
+				Timeout: &metav1.Duration{Duration: 10 * time.Second},
+			},
+			Version: "1.0.0",
+		},
+	}
+
+	s := runtime.NewScheme()
+	err := apiextensionsv1.AddToScheme(s)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	err = v1alpha1.AddToScheme(s)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	ctrlClient := fake.NewClientBuilder().WithScheme(s).WithObjects(cr).Build()
+
+	hc := &backgroundHelmClient{
+		Interface: helmclienttest.New(helmclienttest.Config{
+			PullChartTarballPath: tarballPath,
+		}),
+		updated: make(chan error, 1),
+	}
+	helmClients, err := clientpair.NewClientPair(clientpair.ClientPairConfig{
+		Logger: microloggertest.New(),
+
+		PrvHelmClient: hc,
+		PubHelmClient: hc,
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	chartCache, err := chartcache.New(chartcache.Config{
+		Fs:     afero.NewOsFs(),
+		Logger: microloggertest.New(),
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	r, err := New(Config{
+		ChartCache:     chartCache,
+		ChartVerifier:  newTestChartVerifier(t),
+		Event:          record.NewFakeRecorder(10),
+		Fs:             afero.NewOsFs(),
+		CtrlClient:     ctrlClient,
+		HelmClients:    helmClients,
+		K8sClient:      k8sfake.NewClientset(),
+		Logger:         microloggertest.New(),
+		Operations:     operation.New(),
+		ReleaseMetrics: releasemetrics.New(),
+
+		K8sWaitTimeout:  10 * time.Millisecond,
+		TillerNamespace: "giantswarm",
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	// The created CRD only becomes established after the reconciliation
+	// stopped waiting for the upgrade.
+	go func() {
+		for {
+			var crd apiextensionsv1.CustomResourceDefinition
+
+			err := ctrlClient.Get(ctx, client.ObjectKey{Name: "greetings.example.com"}, &crd)
+			if err != nil {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+
+			crd.Status.Conditions = []apiextensionsv1.CustomResourceDefinitionCondition{
+				{
+					Type:   apiextensionsv1.Established,
+					Status: apiextensionsv1.ConditionTrue,
+				},
+			}
+
+			err = ctrlClient.Status().Update(ctx, &crd)
+			if err != nil {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+
+			return
+		}
+	}()
+
+	err = r.ApplyUpdateChange(ctx, cr, &ReleaseState{
+		Name:    "hello-world",
+		Version: "1.0.0",
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	if o, ok := r.operations.Get(cr.UID); !ok || !o.Running() {
+		t.Fatalf("upgrade finished before the wait timeout, want running")
+	}
+
+	crdChanges := func() string {
+		var current v1alpha1.Chart
+
+		err := ctrlClient.Get(ctx, client.ObjectKeyFromObject(cr), &current)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+
+		return current.Annotations[annotation.CRDChanges]
+	}
+
+	if crdChanges() != "" {
+		t.Fatalf("CRD changes == %#q, want none before the upgrade finished", crdChanges())
+	}
+
+	select {
+	case err = <-hc.updated:
+		if err != nil {
+			t.Fatalf("chart tarball missing on upgrade: %#v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("release not upgraded")
+	}
+
+	// The tarball is removed once the background upgrade finished.
+	for i := 0; ; i++ {
+		_, err = os.Stat(tarballPath)
+		if os.IsNotExist(err) {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("chart tarball %#q not removed", tarballPath)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The CRD changes are reported once the background upgrade finished.
+	for i := 0; ; i++ {
+		if crdChanges() == "created: greetings.example.com" {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("CRD changes == %#q, want %#q", crdChanges(), "created: greetings.example.com")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if o, _ := r.operations.Get(cr.UID); o.Running() {
+		t.Fatalf("upgrade still running, want finished")
+	}
+}
+
+// pullCountingHelmClient counts the chart tarballs pulled.
+type pullCountingHelmClient struct {
+	helmclient.Interface
+
+	pulls int
+}
+
+func (c *pullCountingHelmClient) PullChartTarball(ctx context.Context, tarballURL string) (string, error) {
+	c.pulls++
+	return c.Interface.PullChartTarball(ctx, tarballURL)
+}
+
+func Test_Resource_Release_dryRunUpdate(t *testing.T) {
+	const deployedManifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: hello-world
+data:
+  greeting: hello
+`
+
+	releaseState := ReleaseState{
+		Name:           "hello-world",
+		ValuesChecksum: "checksum",
+		Values: map[string]interface{}{
+			"greeting": "goodbye",
+		},
+		Version: "1.1.0",
+	}
+
+	testCases := []struct {
+		name           string
+		template       string
+		checksum       bool
+		status         v1alpha1.ChartStatus
+		expectedPulls  int
+		expectedEvents int
+		expectedReason string
+		expectedStored bool
+	}{
+		{
+			name:           "case 0: changed manifest is diffed",
+			template:       "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: hello-world\ndata:\n  greeting: {{ .Values.greeting }}\n",
+			expectedPulls:  1,
+			expectedEvents: 1,
+			expectedReason: "dry-run: upgrade to version `1.1.0` would result in 0 added, 1 changed, 0 removed (changed: ConfigMap/hello-world)",
+			expectedStored: true,
+		},
+		{
+			name:     "case 1: unchanged chart, values and revision are not diffed again",
+			template: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: hello-world\ndata:\n  greeting: {{ .Values.greeting }}\n",
+			checksum: true,
+			status: v1alpha1.ChartStatus{
+				Reason: "dry-run: previous diff",
+				Release: v1alpha1.ChartStatusRelease{
+					Status: releasestatus.DryRun,
+				},
+			},
+			expectedReason: "dry-run: previous diff",
+			expectedStored: true,
+		},
+		{
+			name:           "case 2: render error is reported without event",
+			template:       "greeting: {{ .Values.greeting | missingFunction }}\n",
+			expectedPulls:  1,
+			expectedReason: "dry-run: rendering chart `https://giantswarm.github.io/app-catalog/hello-world-1.1.0.tgz` failed: (parse error at (hello-world/templates/configmap.yaml:1): function \"missingFunction\" not defined)",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			ctx := controllercontext.NewContext(context.Background(), controllercontext.Context{})
+			ctx = resourcecanceledcontext.NewContext(ctx, make(chan struct{}))
+
+			var tarballPath string
+			{
+				chartDir := filepath.Join(t.TempDir(), "hello-world")
+
+				err := os.MkdirAll(filepath.Join(chartDir, "templates"), 0755)
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+				err = os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("apiVersion: v2\nname: hello-world\nversion: 1.1.0\n"), 0600)
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+				err = os.WriteFile(filepath.Join(chartDir, "templates", "configmap.yaml"), []byte(tc.template), 0600)
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+
+				chart, err := loader.Load(chartDir)
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+				tarballPath, err = chartutil.Save(chart, t.TempDir())
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+			}
+
+			cr := &v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "hello-world",
+					Namespace: "giantswarm",
+					Annotations: map[string]string{
+						annotation.DryRun: "true",
+					},
+				},
+				Spec: v1alpha1.ChartSpec{
+					Name:       "hello-world",
+					Namespace:  "default",
+					TarballURL: "https://giantswarm.github.io/app-catalog/hello-world-1.1.0.tgz",
+					Version:    "1.1.0",
+				},
+				Status: tc.status,
+			}
+			if tc.checksum {
+				cr.Annotations[annotation.DryRunChecksum] = dryRunChecksum(*cr, releaseState, 1)
+			}
+
+			s := runtime.NewScheme()
+			err := v1alpha1.AddToScheme(s)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			ctrlClient := fake.NewClientBuilder().WithScheme(s).WithObjects(cr).Build()
+
+			k8sClient := k8sfake.NewClientset()
+			k8sClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{
+				GitVersion: "v1.30.0",
+				Major:      "1",
+				Minor:      "30",
+			}
+			err = storage.Init(driver.NewSecrets(k8sClient.CoreV1().Secrets("default"))).Create(&release.Release{
+				Name:      "hello-world",
+				Namespace: "default",
+				Version:   1,
+				Info: &release.Info{
+					Status: release.StatusDeployed,
+				},
+				Manifest: deployedManifest,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			hc := &pullCountingHelmClient{
+				Interface: helmclienttest.New(helmclienttest.Config{
+					PullChartTarballPath: tarballPath,
+				}),
+			}
+			helmClients, err := clientpair.NewClientPair(clientpair.ClientPairConfig{
+				Logger: microloggertest.New(),
+
+				PrvHelmClient: hc,
+				PubHelmClient: hc,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			chartCache, err := chartcache.New(chartcache.Config{
+				Fs:     afero.NewOsFs(),
+				Logger: microloggertest.New(),
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			recorder := record.NewFakeRecorder(10)
+
+			r, err := New(Config{
+				ChartCache:     chartCache,
+				ChartVerifier:  newTestChartVerifier(t),
+				Event:          recorder,
+				Fs:             afero.NewOsFs(),
+				CtrlClient:     ctrlClient,
+				HelmClients:    helmClients,
+				K8sClient:      k8sClient,
+				Logger:         microloggertest.New(),
+				Operations:     operation.New(),
+				ReleaseMetrics: releasemetrics.New(),
+
+				TillerNamespace: "giantswarm",
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			err = r.dryRunUpdate(ctx, *cr, hc, releaseState)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			cc, err := controllercontext.FromContext(ctx)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if hc.pulls != tc.expectedPulls {
+				t.Fatalf("pulls == %d, want %d", hc.pulls, tc.expectedPulls)
+			}
+			if len(recorder.Events) != tc.expectedEvents {
+				t.Fatalf("events == %d, want %d", len(recorder.Events), tc.expectedEvents)
+			}
+			if cc.Status.Release.Status != releasestatus.DryRun {
+				t.Fatalf("status == %#q, want %#q", cc.Status.Release.Status, releasestatus.DryRun)
+			}
+			if cc.Status.Reason != tc.expectedReason {
+				t.Fatalf("reason == %#q, want %#q", cc.Status.Reason, tc.expectedReason)
+			}
+
+			var current v1alpha1.Chart
+
+			err = ctrlClient.Get(ctx, client.ObjectKeyFromObject(cr), &current)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			stored := current.Annotations[annotation.DryRunChecksum] == dryRunChecksum(*cr, releaseState, 1)
+			if stored != tc.expectedStored {
+				t.Fatalf("checksum stored == %t, want %t", stored, tc.expectedStored)
+			}
+		})
+	}
+}
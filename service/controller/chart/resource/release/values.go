@@ -0,0 +1,160 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/operatorkit/v7/pkg/controller/context/resourcecanceledcontext"
+	"github.com/imdario/mergo"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+)
+
+const (
+	valuesSourceKindConfigMap = "ConfigMap"
+	valuesSourceKindSecret    = "Secret"
+)
+
+// valuesSources returns the values sources set in the values-sources
+// annotation of the chart CR in their declared order.
+func valuesSources(cr v1alpha1.Chart) ([]ValuesSource, error) {
+	value := key.ValuesSourcesAnnotation(cr)
+	if value == "" {
+		return nil, nil
+	}
+
+	var sources []ValuesSource
+
+	err := json.Unmarshal([]byte(value), &sources)
+	if err != nil {
+		return nil, microerror.Maskf(invalidValuesSourcesError, "parsing annotation failed: %s", err.Error())
+	}
+
+	for i, s := range sources {
+		if s.Kind != valuesSourceKindConfigMap && s.Kind != valuesSourceKindSecret {
+			return nil, microerror.Maskf(invalidValuesSourcesError, "source %d has kind %#q, want %#q or %#q", i, s.Kind, valuesSourceKindConfigMap, valuesSourceKindSecret)
+		}
+		if s.Name == "" {
+			return nil, microerror.Maskf(invalidValuesSourcesError, "source %d has no name", i)
+		}
+		if s.Namespace == "" {
+			sources[i].Namespace = cr.Namespace
+		}
+	}
+
+	return sources, nil
+}
+
+// getValuesSourcesData merges the values of all sources of the chart CR in
+// their declared order. Values of later sources override values of earlier
+// sources.
+func (r *Resource) getValuesSourcesData(ctx context.Context, cr v1alpha1.Chart) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+
+	// TODO: Improve desired state generation by removing call to key.IsDeleted.
+	//
+	//	See https://github.com/giantswarm/giantswarm/issues/5719
+	//
+	if key.IsDeleted(cr) {
+		// Return early as the sources may have already been deleted and
+		// an invalid annotation must not block uninstalling the release.
+		return values, nil
+	}
+
+	sources, err := valuesSources(cr)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	for _, s := range sources {
+		data, err := r.getValuesSourceData(ctx, s)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, k := range sortedKeys(data) {
+			var v map[string]interface{}
+
+			err = yaml.Unmarshal(data[k], &v)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+
+			err = mergo.Merge(&values, v, mergo.WithOverride)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// getValuesSourceData returns the data of the config map or secret referenced
+// by the values source. When the source selects a key only that key is
+// returned.
+func (r *Resource) getValuesSourceData(ctx context.Context, s ValuesSource) (map[string][]byte, error) {
+	data := map[string][]byte{}
+
+	switch s.Kind {
+	case valuesSourceKindConfigMap:
+		configMap, err := r.k8sClient.CoreV1().ConfigMaps(s.Namespace).Get(ctx, s.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil, microerror.Maskf(valuesSourceNotFoundError, "config map %#q in namespace %#q not found", s.Name, s.Namespace)
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for k, v := range configMap.Data {
+			data[k] = []byte(v)
+		}
+	case valuesSourceKindSecret:
+		secret, err := r.k8sClient.CoreV1().Secrets(s.Namespace).Get(ctx, s.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil, microerror.Maskf(valuesSourceNotFoundError, "secret %#q in namespace %#q not found", s.Name, s.Namespace)
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		data = secret.Data
+	}
+
+	if s.Key == "" {
+		return data, nil
+	}
+
+	v, ok := data[s.Key]
+	if !ok {
+		return nil, microerror.Maskf(valuesSourceNotFoundError, "key %#q not found in %s %#q in namespace %#q", s.Key, s.Kind, s.Name, s.Namespace)
+	}
+
+	return map[string][]byte{s.Key: v}, nil
+}
+
+// cancelOnValuesSourcesError adds the values sources failure to the
+// controller context and cancels the resource. The status resource then
+// reports it in the CR status.
+func (r *Resource) cancelOnValuesSourcesError(ctx context.Context, cc *controllercontext.Context, err error, status string) {
+	addStatusToContext(cc, err.Error(), status)
+
+	r.logger.LogCtx(ctx, "level", "warning", "message", err.Error())
+	r.logger.Debugf(ctx, "canceling resource")
+	resourcecanceledcontext.SetCanceled(ctx)
+}
+
+func sortedKeys(data map[string][]byte) []string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
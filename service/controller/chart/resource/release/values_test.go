@@ -0,0 +1,283 @@
+package release
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/helmclient/v4/pkg/helmclienttest"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/giantswarm/operatorkit/v7/pkg/controller/context/resourcecanceledcontext"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake" //nolint:staticcheck
+
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
+	"github.com/giantswarm/chart-operator/v4/service/internal/clientpair"
+	"github.com/giantswarm/chart-operator/v4/service/internal/operation"
+	"github.com/giantswarm/chart-operator/v4/service/internal/releasemetrics"
+)
+
+func Test_DesiredState_valuesSources(t *testing.T) {
+	catalogValues := &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "catalog-values",
+			Namespace: "giantswarm",
+		},
+		Data: map[string]string{
+			"values": `replicas: 1
+image:
+  registry: quay.io
+  tag: 1.0.0`,
+		},
+	}
+	clusterValues := &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster-values",
+			Namespace: "org-acme",
+		},
+		Data: map[string]string{
+			"a-values": `replicas: 2`,
+			"b-values": `image:
+  registry: docker.io`,
+		},
+	}
+	userSecrets := &apiv1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "user-secrets",
+			Namespace: "giantswarm",
+		},
+		Data: map[string][]byte{
+			"ignored": []byte(`replicas: 5`),
+			"values":  []byte(`password: secret`),
+		},
+	}
+
+	testCases := []struct {
+		name             string
+		obj              *v1alpha1.Chart
+		objs             []runtime.Object
+		expectedValues   map[string]interface{}
+		expectedStatus   controllercontext.Status
+		expectedCanceled bool
+	}{
+		{
+			name: "case 0: sources merged in declared order",
+			obj: &v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "giantswarm",
+					Annotations: map[string]string{
+						annotation.ValuesSources: `[
+{"kind":"ConfigMap","name":"catalog-values"},
+{"kind":"ConfigMap","name":"cluster-values","namespace":"org-acme"},
+{"kind":"Secret","name":"user-secrets","key":"values"}]`,
+					},
+				},
+				Spec: v1alpha1.ChartSpec{
+					Name:    "test-app",
+					Version: "1.0.0",
+				},
+			},
+			objs: []runtime.Object{catalogValues, clusterValues, userSecrets},
+			expectedValues: map[string]interface{}{
+				"image": map[string]interface{}{
+					"registry": "docker.io",
+					"tag":      "1.0.0",
+				},
+				"password": "secret",
+				"replicas": 2,
+			},
+		},
+		{
+			name: "case 1: sources override the config map of the spec",
+			obj: &v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "giantswarm",
+					Annotations: map[string]string{
+						annotation.ValuesSources: `[{"kind":"ConfigMap","name":"cluster-values","namespace":"org-acme","key":"a-values"}]`,
+					},
+				},
+				Spec: v1alpha1.ChartSpec{
+					Name: "test-app",
+					Config: v1alpha1.ChartSpecConfig{
+						ConfigMap: v1alpha1.ChartSpecConfigConfigMap{
+							Name:      "catalog-values",
+							Namespace: "giantswarm",
+						},
+					},
+					Version: "1.0.0",
+				},
+			},
+			objs: []runtime.Object{catalogValues, clusterValues},
+			expectedValues: map[string]interface{}{
+				"image": map[string]interface{}{
+					"registry": "quay.io",
+					"tag":      "1.0.0",
+				},
+				"replicas": 2,
+			},
+		},
+		{
+			name: "case 2: missing source",
+			obj: &v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "giantswarm",
+					Annotations: map[string]string{
+						annotation.ValuesSources: `[{"kind":"Secret","name":"missing"}]`,
+					},
+				},
+				Spec: v1alpha1.ChartSpec{
+					Name:    "test-app",
+					Version: "1.0.0",
+				},
+			},
+			expectedStatus: controllercontext.Status{
+				Reason: "values source not found error: secret `missing` in namespace `giantswarm` not found",
+				Release: controllercontext.Release{
+					Status: releasestatus.ValuesSourceNotFound,
+				},
+			},
+			expectedCanceled: true,
+		},
+		{
+			name: "case 3: missing key",
+			obj: &v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "giantswarm",
+					Annotations: map[string]string{
+						annotation.ValuesSources: `[{"kind":"ConfigMap","name":"catalog-values","key":"missing"}]`,
+					},
+				},
+				Spec: v1alpha1.ChartSpec{
+					Name:    "test-app",
+					Version: "1.0.0",
+				},
+			},
+			objs: []runtime.Object{catalogValues},
+			expectedStatus: controllercontext.Status{
+				Reason: "values source not found error: key `missing` not found in ConfigMap `catalog-values` in namespace `giantswarm`",
+				Release: controllercontext.Release{
+					Status: releasestatus.ValuesSourceNotFound,
+				},
+			},
+			expectedCanceled: true,
+		},
+		{
+			name: "case 4: invalid kind",
+			obj: &v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "giantswarm",
+					Annotations: map[string]string{
+						annotation.ValuesSources: `[{"kind":"Deployment","name":"test"}]`,
+					},
+				},
+				Spec: v1alpha1.ChartSpec{
+					Name:    "test-app",
+					Version: "1.0.0",
+				},
+			},
+			expectedStatus: controllercontext.Status{
+				Reason: "invalid values sources error: source 0 has kind `Deployment`, want `ConfigMap` or `Secret`",
+				Release: controllercontext.Release{
+					Status: releasestatus.InvalidValuesSources,
+				},
+			},
+			expectedCanceled: true,
+		},
+		{
+			name: "case 5: deleted chart with malformed sources",
+			obj: &v1alpha1.Chart{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:         "giantswarm",
+					DeletionTimestamp: &metav1.Time{Time: time.Now()},
+					Annotations: map[string]string{
+						annotation.ValuesSources: `[{"kind":`,
+					},
+				},
+				Spec: v1alpha1.ChartSpec{
+					Name:    "test-app",
+					Version: "1.0.0",
+				},
+			},
+			expectedValues: map[string]interface{}{},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			cc := controllercontext.Context{}
+			ctx := controllercontext.NewContext(context.Background(), cc)
+			ctx = resourcecanceledcontext.NewContext(ctx, make(chan struct{}))
+
+			helmClients, err := clientpair.NewClientPair(clientpair.ClientPairConfig{
+				Logger: microloggertest.New(),
+
+				PrvHelmClient: helmclienttest.New(helmclienttest.Config{}),
+				PubHelmClient: helmclienttest.New(helmclienttest.Config{}),
+			})
+			if err != nil {
+				t.Fatal("expected", nil, "got", err)
+			}
+
+			c := Config{
+				ChartCache:     newTestChartCache(t),
+				ChartVerifier:  newTestChartVerifier(t),
+				Event:          &record.FakeRecorder{},
+				Fs:             afero.NewMemMapFs(),
+				CtrlClient:     fake.NewFakeClient(), //nolint:staticcheck
+				HelmClients:    helmClients,
+				K8sClient:      k8sfake.NewClientset(tc.objs...),
+				Logger:         microloggertest.New(),
+				Operations:     operation.New(),
+				ReleaseMetrics: releasemetrics.New(),
+
+				TillerNamespace: "giantswarm",
+			}
+			r, err := New(c)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			result, err := r.GetDesiredState(ctx, tc.obj)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			canceled := resourcecanceledcontext.IsCanceled(ctx)
+			if canceled != tc.expectedCanceled {
+				t.Fatalf("canceled == %t, want %t", canceled, tc.expectedCanceled)
+			}
+
+			resultCC, err := controllercontext.FromContext(ctx)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if !cmp.Equal(resultCC.Status, tc.expectedStatus) {
+				t.Fatalf("want matching status \n %s", cmp.Diff(resultCC.Status, tc.expectedStatus))
+			}
+
+			if tc.expectedCanceled {
+				return
+			}
+
+			releaseState, err := toReleaseState(result)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if !cmp.Equal(releaseState.Values, tc.expectedValues) {
+				t.Fatalf("want matching values \n %s", cmp.Diff(releaseState.Values, tc.expectedValues))
+			}
+		})
+	}
+}
@@ -5,11 +5,11 @@ import (
 	"fmt"
 	"sort"
 	"strings"
-	"time"
 
 	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
 	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
 	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -18,10 +18,12 @@ import (
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
 )
 
-// EnsureCreated checks if the helm release has failed the max number of
-// attempts. If so we delete the oldest revision if it is over 1 minute old.
-// So we still retry the update but at a reduced rate. This is needed because
-// the max history setting for Helm update does not count failures.
+// EnsureCreated checks if the last revisions of the helm release all failed.
+// If so we delete the oldest of them so failed revisions do not pile up. This
+// is needed because the max history setting for Helm update does not count
+// failures. Retries of failed releases are throttled by the release resource.
+// The history is added to the controller context so the status resource can
+// report it without fetching it again.
 func (r *Resource) EnsureCreated(ctx context.Context, obj interface{}) error {
 	cr, err := key.ToCustomResource(obj)
 	if err != nil {
@@ -32,44 +34,39 @@ func (r *Resource) EnsureCreated(ctx context.Context, obj interface{}) error {
 		return microerror.Mask(err)
 	}
 
-	r.logger.Debugf(ctx, "finding out if release %#q in namespace %#q has failed max attempts", key.ReleaseName(cr), key.Namespace(cr))
+	r.logger.Debugf(ctx, "finding out if release %#q in namespace %#q has failed max revisions", key.ReleaseName(cr), key.Namespace(cr))
 
-	history, err := r.getReleaseHistory(ctx, cr) // key.Namespace(cr), key.ReleaseName(cr))
+	history, err := r.getReleaseHistory(ctx, cr)
 	if err != nil {
 		return microerror.Mask(err)
 	}
 
-	failedMaxAttempts, err := isReleaseFailedMaxAttempts(ctx, history)
-	if err != nil {
-		return microerror.Mask(err)
-	}
+	cc.ReleaseHistory = history
 
-	cc.Status.Release.FailedMaxAttempts = failedMaxAttempts
-	if !failedMaxAttempts {
-		r.logger.Debugf(ctx, "release %#q has not failed max attempts", key.ReleaseName(cr))
+	if !isReleaseFailedMaxRevisions(history) {
+		r.logger.Debugf(ctx, "release %#q has not failed max revisions", key.ReleaseName(cr))
 		return nil
 	}
 
-	r.logger.Debugf(ctx, "release %#q has failed max attempts", key.ReleaseName(cr))
+	r.logger.Debugf(ctx, "release %#q has failed max revisions", key.ReleaseName(cr))
 
 	secretDeleted, err := r.deleteFailedRelease(ctx, key.Namespace(cr), key.ReleaseName(cr), history)
 	if err != nil {
 		return microerror.Mask(err)
 	}
 	if secretDeleted {
-		// We deleted a failed release secret. So we can try to update the release again.
-		cc.Status.Release.FailedMaxAttempts = false
+		r.event.Eventf(&cr, corev1.EventTypeNormal, failedRevisionDeletedEventReason, "deleted oldest of %d failed revisions in a row of release %#q", project.ReleaseFailedMaxRevisions, key.ReleaseName(cr))
 	}
 
 	return nil
 }
 
 func (r *Resource) deleteFailedRelease(ctx context.Context, namespace, releaseName string, history []helmclient.ReleaseHistory) (bool, error) {
-	if len(history) < project.ReleaseFailedMaxAttempts {
+	if len(history) < project.ReleaseFailedMaxRevisions {
 		// Fall through
 		return false, nil
 	}
-	rev := history[project.ReleaseFailedMaxAttempts-1]
+	rev := history[project.ReleaseFailedMaxRevisions-1]
 
 	r.logger.Debugf(ctx, "deleting failed revision %d for release %#q", rev.Revision, releaseName)
 
@@ -91,11 +88,6 @@ func (r *Resource) deleteFailedRelease(ctx context.Context, namespace, releaseNa
 	}
 
 	secret := secrets.Items[0]
-	diff := time.Since(secret.CreationTimestamp.Time)
-	if diff.Minutes() < 1 {
-		r.logger.Debugf(ctx, "revision %d for release %#q is < 1 minutes old", rev.Revision, releaseName)
-		return false, nil
-	}
 
 	err = r.k8sClient.CoreV1().Secrets(secret.Namespace).Delete(ctx, secret.Name, metav1.DeleteOptions{})
 	if apierrors.IsNotFound(err) {
@@ -127,17 +119,17 @@ func (r *Resource) getReleaseHistory(ctx context.Context, cr v1alpha1.Chart) ([]
 	return history, nil
 }
 
-func isReleaseFailedMaxAttempts(ctx context.Context, history []helmclient.ReleaseHistory) (bool, error) {
-	if len(history) < project.ReleaseFailedMaxAttempts {
-		return false, nil
+func isReleaseFailedMaxRevisions(history []helmclient.ReleaseHistory) bool {
+	if len(history) < project.ReleaseFailedMaxRevisions {
+		return false
 	}
 
-	for i := 0; i < project.ReleaseFailedMaxAttempts; i++ {
+	for i := 0; i < project.ReleaseFailedMaxRevisions; i++ {
 		if history[i].Status != helmclient.StatusFailed {
-			return false, nil
+			return false
 		}
 	}
 
-	// All failed so we exceeded the max attempts.
-	return true, nil
+	// All failed so we exceeded the max revisions.
+	return true
 }
@@ -4,6 +4,7 @@ import (
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 
 	"github.com/giantswarm/chart-operator/v4/service/internal/clientpair"
 )
@@ -12,8 +13,14 @@ const (
 	Name = "releasemaxhistory"
 )
 
+// Reasons of the Kubernetes events emitted for the chart CR.
+const (
+	failedRevisionDeletedEventReason = "FailedRevisionDeleted"
+)
+
 type Config struct {
 	// Dependencies.
+	Event       record.EventRecorder
 	HelmClients *clientpair.ClientPair
 	K8sClient   kubernetes.Interface
 	Logger      micrologger.Logger
@@ -21,6 +28,7 @@ type Config struct {
 
 type Resource struct {
 	// Dependencies.
+	event       record.EventRecorder
 	helmClients *clientpair.ClientPair
 	k8sClient   kubernetes.Interface
 	logger      micrologger.Logger
@@ -29,6 +37,9 @@ type Resource struct {
 // New creates a new configured releasemaxhistory resource.
 func New(config Config) (*Resource, error) {
 	// Dependencies.
+	if config.Event == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Event must not be empty", config)
+	}
 	if config.HelmClients == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.ClientPair must not be empty", config)
 	}
@@ -40,6 +51,7 @@ func New(config Config) (*Resource, error) {
 	}
 
 	r := &Resource{
+		event:       config.Event,
 		helmClients: config.HelmClients,
 		k8sClient:   config.K8sClient,
 		logger:      config.Logger,
@@ -0,0 +1,66 @@
+package releasesecret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	"github.com/giantswarm/microerror"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/project"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+)
+
+// EnsureCreated adds the managed-by label to the release secrets of the chart
+// CR which do not have it yet, e.g. releases installed by older versions of
+// chart-operator.
+func (r *Resource) EnsureCreated(ctx context.Context, obj interface{}) error {
+	cr, err := key.ToCustomResource(obj)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	lo := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("owner=helm,name=%s", key.ReleaseName(cr)),
+	}
+	secrets, err := r.k8sClient.CoreV1().Secrets(key.Namespace(cr)).List(ctx, lo)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]string{
+				label.ManagedBy: project.Name(),
+			},
+		},
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, s := range secrets.Items {
+		if s.Labels[label.ManagedBy] == project.Name() {
+			continue
+		}
+
+		r.logger.Debugf(ctx, "labelling release secret %#q in namespace %#q", s.Name, s.Namespace)
+
+		_, err = r.k8sClient.CoreV1().Secrets(s.Namespace).Patch(ctx, s.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		if apierrors.IsNotFound(err) {
+			// The revision was deleted in the meantime, e.g. by the Helm
+			// max history.
+			continue
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+
+		r.logger.Debugf(ctx, "labelled release secret %#q in namespace %#q", s.Name, s.Namespace)
+	}
+
+	return nil
+}
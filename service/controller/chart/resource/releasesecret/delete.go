@@ -0,0 +1,8 @@
+package releasesecret
+
+import "context"
+
+// EnsureDeleted is a no-op.
+func (r *Resource) EnsureDeleted(ctx context.Context, obj interface{}) error {
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
@@ -17,9 +18,9 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
-	"github.com/giantswarm/chart-operator/v4/pkg/project"
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/releasestatus"
 )
 
 func (r *Resource) EnsureCreated(ctx context.Context, obj interface{}) error {
@@ -73,14 +74,15 @@ func (r *Resource) EnsureCreated(ctx context.Context, obj interface{}) error {
 	var status, reason string
 	{
 		if key.IsCordoned(cr) {
-			status = releaseStatusCordoned
+			status = releasestatus.Cordoned
 			reason = key.CordonReason(cr)
 		} else {
 			status = releaseContent.Status
 			if releaseContent.Status != helmclient.StatusDeployed {
-				if cc.Status.Release.FailedMaxAttempts {
-					reason = fmt.Sprintf("Release has failed %d times.\nReason: %s",
-						project.ReleaseFailedMaxAttempts,
+				if cc.Status.Release.FailedAttempts > 0 {
+					reason = fmt.Sprintf("Release has failed %d times, next retry at %s.\nReason: %s",
+						cc.Status.Release.FailedAttempts,
+						cc.Status.Release.NextRetry.UTC().Format(time.RFC3339),
 						releaseContent.Description)
 				} else {
 					reason = releaseContent.Description
@@ -90,6 +92,18 @@ func (r *Resource) EnsureCreated(ctx context.Context, obj interface{}) error {
 				// but a follow-up deployment failed for another version, so we want to set a reason indicating the issue
 				reason = cc.Status.Reason
 				status = cc.Status.Release.Status
+			} else if key.HasHealthCheckAnnotation(cr) {
+				unhealthy, err := r.unhealthyWorkloads(ctx, cr)
+				if err != nil {
+					return microerror.Mask(err)
+				}
+
+				if len(unhealthy) > 0 {
+					r.logger.Debugf(ctx, "release %#q has %d workloads that are not ready", releaseName, len(unhealthy))
+
+					status = releasestatus.DeployedUnhealthy
+					reason = fmt.Sprintf("Workloads are not ready.\n%s", strings.Join(unhealthy, "\n"))
+				}
 			}
 		}
 	}
@@ -119,6 +133,11 @@ func (r *Resource) EnsureCreated(ctx context.Context, obj interface{}) error {
 		r.logger.Debugf(ctx, "status for release %#q already set to %#q", releaseName, releaseContent.Status)
 	}
 
+	err = r.setReleaseHistory(ctx, cr, cc)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
 	return nil
 }
 
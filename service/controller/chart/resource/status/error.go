@@ -21,3 +21,12 @@ var wrongStatusError = &microerror.Error{
 func IsWrongStatusError(err error) bool {
 	return microerror.Cause(err) == wrongStatusError
 }
+
+var invalidReleaseHistoryError = &microerror.Error{
+	Kind: "invalidReleaseHistoryError",
+}
+
+// IsInvalidReleaseHistory asserts invalidReleaseHistoryError.
+func IsInvalidReleaseHistory(err error) bool {
+	return microerror.Cause(err) == invalidReleaseHistoryError
+}
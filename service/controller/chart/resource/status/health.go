@@ -0,0 +1,234 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+)
+
+// workload is a Deployment, StatefulSet, DaemonSet or Job of a release
+// manifest.
+type workload struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+}
+
+// unhealthyWorkloads returns the reasons why the workloads of the deployed
+// release of the chart CR are not ready, sorted by workload. It returns no
+// reasons when all workloads are ready.
+func (r *Resource) unhealthyWorkloads(ctx context.Context, cr v1alpha1.Chart) ([]string, error) {
+	s := driver.NewSecrets(r.k8sClient.CoreV1().Secrets(key.Namespace(cr)))
+	store := storage.Init(s)
+
+	rel, err := store.Deployed(key.ReleaseName(cr))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	workloads, err := parseWorkloads(rel.Manifest, key.Namespace(cr))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var reasons []string
+
+	for _, w := range workloads {
+		reason, err := r.workloadHealth(ctx, w)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		if reason != "" {
+			reasons = append(reasons, fmt.Sprintf("%s %#q in namespace %#q: %s", w.Kind, w.Metadata.Name, w.Metadata.Namespace, reason))
+		}
+	}
+
+	return reasons, nil
+}
+
+// workloadHealth returns the reason why the workload is not ready or an
+// empty string when it is ready.
+func (r *Resource) workloadHealth(ctx context.Context, w workload) (string, error) {
+	var reason string
+
+	switch w.Kind {
+	case "Deployment":
+		d, err := r.k8sClient.AppsV1().Deployments(w.Metadata.Namespace).Get(ctx, w.Metadata.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return "not found", nil
+		} else if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		reason = deploymentHealth(d)
+	case "StatefulSet":
+		s, err := r.k8sClient.AppsV1().StatefulSets(w.Metadata.Namespace).Get(ctx, w.Metadata.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return "not found", nil
+		} else if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		reason = statefulSetHealth(s)
+	case "DaemonSet":
+		d, err := r.k8sClient.AppsV1().DaemonSets(w.Metadata.Namespace).Get(ctx, w.Metadata.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return "not found", nil
+		} else if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		reason = daemonSetHealth(d)
+	case "Job":
+		j, err := r.k8sClient.BatchV1().Jobs(w.Metadata.Namespace).Get(ctx, w.Metadata.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			// Jobs may be deleted after they complete, e.g. by their
+			// ttlSecondsAfterFinished.
+			return "", nil
+		} else if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		reason = jobHealth(j)
+	}
+
+	return reason, nil
+}
+
+// parseWorkloads returns the workloads of the release manifest sorted by
+// kind, namespace and name. Workloads without namespace are deployed to the
+// release namespace.
+func parseWorkloads(manifest, namespace string) ([]workload, error) {
+	var workloads []workload
+
+	for _, doc := range releaseutil.SplitManifests(manifest) {
+		var w workload
+
+		err := yaml.Unmarshal([]byte(doc), &w)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		switch w.Kind {
+		case "Deployment", "StatefulSet", "DaemonSet", "Job":
+		default:
+			continue
+		}
+
+		if w.Metadata.Namespace == "" {
+			w.Metadata.Namespace = namespace
+		}
+
+		workloads = append(workloads, w)
+	}
+
+	sort.Slice(workloads, func(i, j int) bool {
+		a, b := workloads[i], workloads[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Metadata.Namespace != b.Metadata.Namespace {
+			return a.Metadata.Namespace < b.Metadata.Namespace
+		}
+		return a.Metadata.Name < b.Metadata.Name
+	})
+
+	return workloads, nil
+}
+
+func deploymentHealth(d *appsv1.Deployment) string {
+	if d.Status.ObservedGeneration < d.Generation {
+		return "rollout not observed yet"
+	}
+
+	for _, c := range d.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			return fmt.Sprintf("rollout exceeded its progress deadline: %s", c.Message)
+		}
+	}
+
+	replicas := replicasOrDefault(d.Spec.Replicas)
+	if d.Status.UpdatedReplicas < replicas {
+		return fmt.Sprintf("%d of %d replicas updated", d.Status.UpdatedReplicas, replicas)
+	}
+	if d.Status.AvailableReplicas < replicas {
+		return fmt.Sprintf("%d of %d replicas available", d.Status.AvailableReplicas, replicas)
+	}
+
+	return ""
+}
+
+func statefulSetHealth(s *appsv1.StatefulSet) string {
+	if s.Status.ObservedGeneration < s.Generation {
+		return "rollout not observed yet"
+	}
+
+	replicas := replicasOrDefault(s.Spec.Replicas)
+	if s.Spec.UpdateStrategy.Type != appsv1.OnDeleteStatefulSetStrategyType && s.Status.UpdatedReplicas < replicas {
+		return fmt.Sprintf("%d of %d replicas updated", s.Status.UpdatedReplicas, replicas)
+	}
+	if s.Status.ReadyReplicas < replicas {
+		return fmt.Sprintf("%d of %d replicas ready", s.Status.ReadyReplicas, replicas)
+	}
+
+	return ""
+}
+
+func daemonSetHealth(d *appsv1.DaemonSet) string {
+	if d.Status.ObservedGeneration < d.Generation {
+		return "rollout not observed yet"
+	}
+
+	desired := d.Status.DesiredNumberScheduled
+	if d.Spec.UpdateStrategy.Type != appsv1.OnDeleteDaemonSetStrategyType && d.Status.UpdatedNumberScheduled < desired {
+		return fmt.Sprintf("%d of %d pods updated", d.Status.UpdatedNumberScheduled, desired)
+	}
+	if d.Status.NumberReady < desired {
+		return fmt.Sprintf("%d of %d pods ready", d.Status.NumberReady, desired)
+	}
+
+	return ""
+}
+
+func jobHealth(j *batchv1.Job) string {
+	for _, c := range j.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		switch c.Type {
+		case batchv1.JobComplete:
+			return ""
+		case batchv1.JobFailed:
+			return fmt.Sprintf("failed: %s", strings.TrimSpace(c.Message))
+		}
+	}
+
+	return "not completed yet"
+}
+
+func replicasOrDefault(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+
+	return *replicas
+}
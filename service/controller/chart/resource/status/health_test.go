@@ -0,0 +1,358 @@
+package status
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/giantswarm/to"
+	"github.com/google/go-cmp/cmp"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testManifest = `---
+# Source: hello-world/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: hello-world
+---
+# Source: hello-world/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: hello-world
+---
+# Source: hello-world/templates/daemonset.yaml
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: hello-world-agent
+  namespace: kube-system
+---
+# Source: hello-world/templates/job.yaml
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: hello-world-migration
+`
+
+func Test_parseWorkloads(t *testing.T) {
+	workloads, err := parseWorkloads(testManifest, "default")
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	var result []string
+	for _, w := range workloads {
+		result = append(result, w.Kind+"/"+w.Metadata.Namespace+"/"+w.Metadata.Name)
+	}
+
+	expected := []string{
+		"DaemonSet/kube-system/hello-world-agent",
+		"Deployment/default/hello-world",
+		"Job/default/hello-world-migration",
+	}
+	if !cmp.Equal(result, expected) {
+		t.Fatalf("want matching workloads \n %s", cmp.Diff(result, expected))
+	}
+}
+
+func Test_deploymentHealth(t *testing.T) {
+	testCases := []struct {
+		name           string
+		deployment     *appsv1.Deployment
+		expectedReason string
+	}{
+		{
+			name: "case 0: all replicas available",
+			deployment: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: to.Int32P(3)},
+				Status: appsv1.DeploymentStatus{
+					ObservedGeneration: 2,
+					UpdatedReplicas:    3,
+					AvailableReplicas:  3,
+				},
+			},
+		},
+		{
+			name: "case 1: rollout not observed",
+			deployment: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: to.Int32P(3)},
+				Status: appsv1.DeploymentStatus{
+					ObservedGeneration: 1,
+					UpdatedReplicas:    3,
+					AvailableReplicas:  3,
+				},
+			},
+			expectedReason: "rollout not observed yet",
+		},
+		{
+			name: "case 2: replicas not available",
+			deployment: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: to.Int32P(3)},
+				Status: appsv1.DeploymentStatus{
+					ObservedGeneration: 2,
+					UpdatedReplicas:    3,
+					AvailableReplicas:  1,
+				},
+			},
+			expectedReason: "1 of 3 replicas available",
+		},
+		{
+			name: "case 3: progress deadline exceeded",
+			deployment: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Status: appsv1.DeploymentStatus{
+					ObservedGeneration: 2,
+					Conditions: []appsv1.DeploymentCondition{
+						{
+							Type:    appsv1.DeploymentProgressing,
+							Status:  corev1.ConditionFalse,
+							Reason:  "ProgressDeadlineExceeded",
+							Message: `ReplicaSet "hello-world-5d4f" has timed out progressing.`,
+						},
+					},
+				},
+			},
+			expectedReason: `rollout exceeded its progress deadline: ReplicaSet "hello-world-5d4f" has timed out progressing.`,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			result := deploymentHealth(tc.deployment)
+			if result != tc.expectedReason {
+				t.Fatalf("reason == %#q, want %#q", result, tc.expectedReason)
+			}
+		})
+	}
+}
+
+func Test_statefulSetHealth(t *testing.T) {
+	testCases := []struct {
+		name           string
+		statefulSet    *appsv1.StatefulSet
+		expectedReason string
+	}{
+		{
+			name: "case 0: all replicas ready",
+			statefulSet: &appsv1.StatefulSet{
+				Spec: appsv1.StatefulSetSpec{Replicas: to.Int32P(2)},
+				Status: appsv1.StatefulSetStatus{
+					UpdatedReplicas: 2,
+					ReadyReplicas:   2,
+				},
+			},
+		},
+		{
+			name: "case 1: replicas not updated",
+			statefulSet: &appsv1.StatefulSet{
+				Spec: appsv1.StatefulSetSpec{Replicas: to.Int32P(2)},
+				Status: appsv1.StatefulSetStatus{
+					UpdatedReplicas: 1,
+					ReadyReplicas:   2,
+				},
+			},
+			expectedReason: "1 of 2 replicas updated",
+		},
+		{
+			name: "case 2: replicas not updated with on delete strategy",
+			statefulSet: &appsv1.StatefulSet{
+				Spec: appsv1.StatefulSetSpec{
+					Replicas: to.Int32P(2),
+					UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+						Type: appsv1.OnDeleteStatefulSetStrategyType,
+					},
+				},
+				Status: appsv1.StatefulSetStatus{
+					UpdatedReplicas: 0,
+					ReadyReplicas:   2,
+				},
+			},
+		},
+		{
+			name: "case 3: replicas not ready",
+			statefulSet: &appsv1.StatefulSet{
+				Status: appsv1.StatefulSetStatus{
+					UpdatedReplicas: 1,
+				},
+			},
+			expectedReason: "0 of 1 replicas ready",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			result := statefulSetHealth(tc.statefulSet)
+			if result != tc.expectedReason {
+				t.Fatalf("reason == %#q, want %#q", result, tc.expectedReason)
+			}
+		})
+	}
+}
+
+func Test_daemonSetHealth(t *testing.T) {
+	testCases := []struct {
+		name           string
+		daemonSet      *appsv1.DaemonSet
+		expectedReason string
+	}{
+		{
+			name: "case 0: all pods ready",
+			daemonSet: &appsv1.DaemonSet{
+				Status: appsv1.DaemonSetStatus{
+					DesiredNumberScheduled: 3,
+					UpdatedNumberScheduled: 3,
+					NumberReady:            3,
+				},
+			},
+		},
+		{
+			name: "case 1: pods not ready",
+			daemonSet: &appsv1.DaemonSet{
+				Status: appsv1.DaemonSetStatus{
+					DesiredNumberScheduled: 3,
+					UpdatedNumberScheduled: 3,
+					NumberReady:            2,
+				},
+			},
+			expectedReason: "2 of 3 pods ready",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			result := daemonSetHealth(tc.daemonSet)
+			if result != tc.expectedReason {
+				t.Fatalf("reason == %#q, want %#q", result, tc.expectedReason)
+			}
+		})
+	}
+}
+
+func Test_jobHealth(t *testing.T) {
+	testCases := []struct {
+		name           string
+		job            *batchv1.Job
+		expectedReason string
+	}{
+		{
+			name: "case 0: job complete",
+			job: &batchv1.Job{
+				Status: batchv1.JobStatus{
+					Conditions: []batchv1.JobCondition{
+						{Type: batchv1.JobComplete, Status: corev1.ConditionTrue},
+					},
+				},
+			},
+		},
+		{
+			name: "case 1: job failed",
+			job: &batchv1.Job{
+				Status: batchv1.JobStatus{
+					Conditions: []batchv1.JobCondition{
+						{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "Job has reached the specified backoff limit"},
+					},
+				},
+			},
+			expectedReason: "failed: Job has reached the specified backoff limit",
+		},
+		{
+			name:           "case 2: job running",
+			job:            &batchv1.Job{},
+			expectedReason: "not completed yet",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			result := jobHealth(tc.job)
+			if result != tc.expectedReason {
+				t.Fatalf("reason == %#q, want %#q", result, tc.expectedReason)
+			}
+		})
+	}
+}
+
+func Test_Resource_unhealthyWorkloads(t *testing.T) {
+	ctx := context.Background()
+
+	objs := []runtime.Object{
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "hello-world", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Replicas: to.Int32P(2)},
+			Status: appsv1.DeploymentStatus{
+				UpdatedReplicas:   2,
+				AvailableReplicas: 1,
+			},
+		},
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "hello-world-agent", Namespace: "kube-system"},
+			Status: appsv1.DaemonSetStatus{
+				DesiredNumberScheduled: 1,
+				UpdatedNumberScheduled: 1,
+				NumberReady:            1,
+			},
+		},
+	}
+	k8sClient := fake.NewSimpleClientset(objs...)
+
+	err := driver.NewSecrets(k8sClient.CoreV1().Secrets("default")).Create("sh.helm.release.v1.hello-world.v1", &release.Release{
+		Name:      "hello-world",
+		Namespace: "default",
+		Version:   1,
+		Manifest:  testManifest,
+		Info: &release.Info{
+			Status: release.StatusDeployed,
+		},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	r := &Resource{
+		k8sClient: k8sClient,
+		logger:    microloggertest.New(),
+	}
+
+	cr := v1alpha1.Chart{
+		Spec: v1alpha1.ChartSpec{
+			Name:      "hello-world",
+			Namespace: "default",
+		},
+	}
+
+	result, err := r.unhealthyWorkloads(ctx, cr)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	// The job was deleted after it completed.
+	expected := []string{
+		"Deployment `hello-world` in namespace `default`: 1 of 2 replicas available",
+	}
+	if !cmp.Equal(result, expected) {
+		t.Fatalf("want matching reasons \n %s", cmp.Diff(result, expected))
+	}
+}
@@ -0,0 +1,162 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
+	"github.com/giantswarm/microerror"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/controllercontext"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+)
+
+const (
+	// maxReleaseHistory is the number of revisions kept in the
+	// release-history annotation.
+	maxReleaseHistory = 10
+)
+
+// desiredReleaseHistory merges the history of the Helm release into the
+// revisions stored in the annotation, so revisions whose release secrets
+// were pruned are still reported. It returns the newest revisions first,
+// bounded by maxReleaseHistory.
+func desiredReleaseHistory(cr v1alpha1.Chart, history []helmclient.ReleaseHistory, existing []ReleaseRevision) []ReleaseRevision {
+	revisions := map[int]ReleaseRevision{}
+	for _, r := range existing {
+		revisions[r.Revision] = r
+	}
+
+	var latest int
+	for _, h := range history {
+		if h.Revision > latest {
+			latest = h.Revision
+		}
+	}
+
+	for _, h := range history {
+		r := ReleaseRevision{
+			AppVersion:  h.AppVersion,
+			Description: h.Description,
+			Revision:    h.Revision,
+			Status:      h.Status,
+			Version:     h.Version,
+		}
+		if !h.LastDeployed.IsZero() {
+			// We convert the timestamp to the nearest second to match the
+			// value stored in the annotation.
+			r.LastDeployed = &metav1.Time{Time: time.Unix(h.LastDeployed.Unix(), 0)}
+		}
+
+		// Helm does not keep the checksum of the values. The values checksum
+		// annotation is set by the release resource when it installs or
+		// upgrades, so it belongs to the latest revision.
+		r.ValuesChecksum = revisions[h.Revision].ValuesChecksum
+		if r.ValuesChecksum == "" && h.Revision == latest {
+			r.ValuesChecksum = key.ValuesChecksumAnnotation(cr)
+		}
+
+		revisions[h.Revision] = r
+	}
+
+	result := make([]ReleaseRevision, 0, len(revisions))
+	for _, r := range revisions {
+		result = append(result, r)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Revision > result[j].Revision
+	})
+
+	if len(result) > maxReleaseHistory {
+		result = result[:maxReleaseHistory]
+	}
+
+	return result
+}
+
+// setReleaseHistory updates the release-history annotation with the history
+// of the Helm release fetched by the releasemaxhistory resource. A patch
+// operation is used because app-operator also sets annotations for chart
+// CRs.
+func (r *Resource) setReleaseHistory(ctx context.Context, cr v1alpha1.Chart, cc *controllercontext.Context) error {
+	if len(cc.ReleaseHistory) == 0 {
+		// The stored history is kept when there is no Helm release.
+		return nil
+	}
+
+	// Get chart CR again to ensure the resource version and annotations
+	// are correct.
+	var currentCR v1alpha1.Chart
+
+	err := r.ctrlClient.Get(
+		ctx,
+		types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace},
+		&currentCR,
+	)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	existing, err := currentReleaseHistory(currentCR)
+	if err != nil {
+		// An invalid history is overwritten instead of blocking the
+		// status from being updated.
+		r.logger.Errorf(ctx, err, "parsing release history of chart CR %#q failed", cr.Name)
+		existing = nil
+	}
+
+	history := desiredReleaseHistory(currentCR, cc.ReleaseHistory, existing)
+	if reflect.DeepEqual(existing, history) {
+		r.logger.Debugf(ctx, "release history for release %#q already set", key.ReleaseName(cr))
+		return nil
+	}
+
+	b, err := json.Marshal(history)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	modifiedCR := currentCR.DeepCopy()
+	if modifiedCR.Annotations == nil {
+		modifiedCR.Annotations = map[string]string{}
+	}
+	modifiedCR.Annotations[annotation.ReleaseHistory] = string(b)
+
+	r.logger.Debugf(ctx, "setting release history for release %#q", key.ReleaseName(cr))
+
+	err = r.ctrlClient.Patch(ctx, modifiedCR, client.MergeFrom(&currentCR))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	r.logger.Debugf(ctx, "set release history for release %#q", key.ReleaseName(cr))
+
+	return nil
+}
+
+// currentReleaseHistory returns the revisions stored in the annotation of
+// the chart CR.
+func currentReleaseHistory(cr v1alpha1.Chart) ([]ReleaseRevision, error) {
+	value, ok := cr.GetAnnotations()[annotation.ReleaseHistory]
+	if !ok || value == "" {
+		return nil, nil
+	}
+
+	var history []ReleaseRevision
+
+	err := json.Unmarshal([]byte(value), &history)
+	if err != nil {
+		return nil, microerror.Maskf(invalidReleaseHistoryError, "%s", err.Error())
+	}
+
+	return history, nil
+}
@@ -0,0 +1,149 @@
+package status
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/annotation"
+)
+
+func Test_desiredReleaseHistory(t *testing.T) {
+	deployed := time.Date(2026, 10, 1, 9, 0, 0, 500, time.UTC)
+	lastDeployed := &metav1.Time{Time: time.Unix(deployed.Unix(), 0)}
+
+	cr := v1alpha1.Chart{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				annotation.ValuesChecksum: "c2",
+			},
+		},
+	}
+
+	testCases := []struct {
+		name            string
+		history         []helmclient.ReleaseHistory
+		existing        []ReleaseRevision
+		expectedHistory []ReleaseRevision
+	}{
+		{
+			name: "case 0: first revisions are recorded",
+			history: []helmclient.ReleaseHistory{
+				{
+					AppVersion:   "0.2.0",
+					Description:  "Upgrade complete",
+					LastDeployed: deployed,
+					Revision:     2,
+					Status:       helmclient.StatusDeployed,
+					Version:      "1.1.0",
+				},
+				{
+					AppVersion:  "0.1.0",
+					Description: "Superseded",
+					Revision:    1,
+					Status:      "superseded",
+					Version:     "1.0.0",
+				},
+			},
+			expectedHistory: []ReleaseRevision{
+				{
+					AppVersion:     "0.2.0",
+					Description:    "Upgrade complete",
+					LastDeployed:   lastDeployed,
+					Revision:       2,
+					Status:         helmclient.StatusDeployed,
+					ValuesChecksum: "c2",
+					Version:        "1.1.0",
+				},
+				{
+					AppVersion:  "0.1.0",
+					Description: "Superseded",
+					Revision:    1,
+					Status:      "superseded",
+					Version:     "1.0.0",
+				},
+			},
+		},
+		{
+			name: "case 1: stored values checksums and pruned revisions are kept",
+			history: []helmclient.ReleaseHistory{
+				{
+					Revision: 3,
+					Status:   helmclient.StatusDeployed,
+					Version:  "1.1.0",
+				},
+				{
+					Revision: 2,
+					Status:   "superseded",
+					Version:  "1.1.0",
+				},
+			},
+			existing: []ReleaseRevision{
+				{
+					Revision:       2,
+					Status:         helmclient.StatusDeployed,
+					ValuesChecksum: "c1",
+					Version:        "1.1.0",
+				},
+				{
+					Revision: 1,
+					Status:   helmclient.StatusFailed,
+					Version:  "1.0.0",
+				},
+			},
+			expectedHistory: []ReleaseRevision{
+				{
+					Revision:       3,
+					Status:         helmclient.StatusDeployed,
+					ValuesChecksum: "c2",
+					Version:        "1.1.0",
+				},
+				{
+					Revision:       2,
+					Status:         "superseded",
+					ValuesChecksum: "c1",
+					Version:        "1.1.0",
+				},
+				{
+					Revision: 1,
+					Status:   helmclient.StatusFailed,
+					Version:  "1.0.0",
+				},
+			},
+		},
+		{
+			name: "case 2: history is bounded",
+			history: func() []helmclient.ReleaseHistory {
+				var history []helmclient.ReleaseHistory
+				for i := 12; i > 0; i-- {
+					history = append(history, helmclient.ReleaseHistory{Revision: i, Status: "superseded"})
+				}
+				return history
+			}(),
+			expectedHistory: func() []ReleaseRevision {
+				var history []ReleaseRevision
+				for i := 12; i > 2; i-- {
+					history = append(history, ReleaseRevision{Revision: i, Status: "superseded"})
+				}
+				history[0].ValuesChecksum = "c2"
+				return history
+			}(),
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			history := desiredReleaseHistory(cr, tc.history, tc.existing)
+			if !cmp.Equal(history, tc.expectedHistory) {
+				t.Fatalf("want matching history \n %s", cmp.Diff(history, tc.expectedHistory))
+			}
+		})
+	}
+}
@@ -20,7 +20,6 @@ const (
 	// defaultHTTPClientTimeout is the timeout when updating app status.
 	defaultHTTPClientTimeout = 5
 	namespace                = "giantswarm"
-	releaseStatusCordoned    = "CORDONED"
 	token                    = "token"
 )
 
@@ -9,3 +9,21 @@ type Request struct {
 	Status       string  `json:"status"`
 	Version      string  `json:"version"`
 }
+
+// ReleaseRevision is a revision of the Helm release stored in the
+// release-history annotation of the chart CR.
+type ReleaseRevision struct {
+	AppVersion  string `json:"appVersion,omitempty"`
+	Description string `json:"description,omitempty"`
+	// LastDeployed is when the revision was deployed, rounded to the
+	// second.
+	LastDeployed *v1.Time `json:"lastDeployed,omitempty"`
+	Revision     int      `json:"revision"`
+	Status       string   `json:"status"`
+	// ValuesChecksum is the SHA-256 checksum of the values chart-operator
+	// deployed the revision with. It is empty for revisions deployed before
+	// the history was recorded.
+	ValuesChecksum string `json:"valuesChecksum,omitempty"`
+	// Version is the chart version of the revision.
+	Version string `json:"version,omitempty"`
+}
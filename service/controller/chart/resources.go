@@ -11,35 +11,61 @@ import (
 	"github.com/giantswarm/operatorkit/v7/pkg/resource/wrapper/retryresource"
 	"github.com/spf13/afero"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart/resource/namespace"
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart/resource/release"
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart/resource/releasemaxhistory"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/resource/releasesecret"
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart/resource/status"
 
+	"github.com/giantswarm/chart-operator/v4/service/internal/chartcache"
+	"github.com/giantswarm/chart-operator/v4/service/internal/chartverifier"
 	"github.com/giantswarm/chart-operator/v4/service/internal/clientpair"
+	"github.com/giantswarm/chart-operator/v4/service/internal/imagerewrite"
+	"github.com/giantswarm/chart-operator/v4/service/internal/maintenancewindow"
+	"github.com/giantswarm/chart-operator/v4/service/internal/operation"
+	"github.com/giantswarm/chart-operator/v4/service/internal/releasemetrics"
+	"github.com/giantswarm/chart-operator/v4/service/internal/retrybackoff"
 )
 
 type chartResourcesConfig struct {
 	// Dependencies.
-	Fs          afero.Fs
-	CtrlClient  client.Client
-	HelmClients *clientpair.ClientPair
-	K8sClient   kubernetes.Interface
-	Logger      micrologger.Logger
+	ChartCache     *chartcache.Cache
+	ChartVerifier  *chartverifier.Verifier
+	Event          record.EventRecorder
+	Fs             afero.Fs
+	CtrlClient     client.Client
+	HelmClients    *clientpair.ClientPair
+	K8sClient      kubernetes.Interface
+	Logger         micrologger.Logger
+	ReleaseMetrics *releasemetrics.Metrics
 
 	// Settings.
-	HTTPClientTimeout time.Duration
-	K8sWaitTimeout    time.Duration
-	MaxRollback       int
-	TillerNamespace   string
+	HTTPClientTimeout     time.Duration
+	ImageRewriter         *imagerewrite.Rewriter
+	K8sWaitTimeout        time.Duration
+	MaintenanceWindow     maintenancewindow.Window
+	MaxRollback           int
+	MigrateHelmV2Releases bool
+	RetryPolicy           retrybackoff.Policy
+	TillerNamespace       string
 }
 
 func newChartResources(config chartResourcesConfig) ([]resource.Interface, error) {
 	var err error
 
 	// Dependencies.
+	if config.ChartCache == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ChartCache must not be empty", config)
+	}
+	if config.ChartVerifier == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ChartVerifier must not be empty", config)
+	}
+	if config.Event == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Event must not be empty", config)
+	}
 	if config.Fs == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Fs must not be empty", config)
 	}
@@ -55,6 +81,9 @@ func newChartResources(config chartResourcesConfig) ([]resource.Interface, error
 	if config.Logger == nil {
 		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
 	}
+	if config.ReleaseMetrics == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.ReleaseMetrics must not be empty", config)
+	}
 
 	if config.TillerNamespace == "" {
 		return nil, microerror.Maskf(invalidConfigError, "%T.TillerNamespace must not be empty", config)
@@ -79,16 +108,25 @@ func newChartResources(config chartResourcesConfig) ([]resource.Interface, error
 	{
 		c := release.Config{
 			// Dependencies
-			Fs:          config.Fs,
-			CtrlClient:  config.CtrlClient,
-			HelmClients: config.HelmClients,
-			K8sClient:   config.K8sClient,
-			Logger:      config.Logger,
+			ChartCache:     config.ChartCache,
+			ChartVerifier:  config.ChartVerifier,
+			Event:          config.Event,
+			Fs:             config.Fs,
+			CtrlClient:     config.CtrlClient,
+			HelmClients:    config.HelmClients,
+			K8sClient:      config.K8sClient,
+			Logger:         config.Logger,
+			Operations:     operation.New(),
+			ReleaseMetrics: config.ReleaseMetrics,
 
 			// Settings
-			K8sWaitTimeout:  config.K8sWaitTimeout,
-			MaxRollback:     config.MaxRollback,
-			TillerNamespace: config.TillerNamespace,
+			ImageRewriter:         config.ImageRewriter,
+			K8sWaitTimeout:        config.K8sWaitTimeout,
+			MaintenanceWindow:     config.MaintenanceWindow,
+			MaxRollback:           config.MaxRollback,
+			MigrateHelmV2Releases: config.MigrateHelmV2Releases,
+			RetryPolicy:           config.RetryPolicy,
+			TillerNamespace:       config.TillerNamespace,
 		}
 
 		ops, err := release.New(c)
@@ -106,6 +144,7 @@ func newChartResources(config chartResourcesConfig) ([]resource.Interface, error
 	{
 		c := releasemaxhistory.Config{
 			// Dependencies
+			Event:       config.Event,
 			HelmClients: config.HelmClients,
 			K8sClient:   config.K8sClient,
 			Logger:      config.Logger,
@@ -117,6 +156,19 @@ func newChartResources(config chartResourcesConfig) ([]resource.Interface, error
 		}
 	}
 
+	var releaseSecretResource resource.Interface
+	{
+		c := releasesecret.Config{
+			K8sClient: config.K8sClient,
+			Logger:    config.Logger,
+		}
+
+		releaseSecretResource, err = releasesecret.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var statusResource resource.Interface
 	{
 		c := status.Config{
@@ -141,6 +193,9 @@ func newChartResources(config chartResourcesConfig) ([]resource.Interface, error
 		releaseMaxHistoryResource,
 		// release manages Helm releases and is the most important resource.
 		releaseResource,
+		// release secret labels the helm release secrets so orphan releases
+		// can be found.
+		releaseSecretResource,
 		// status resource manages the chart CR status.
 		statusResource,
 	}
@@ -0,0 +1,491 @@
+// Package chartcache implements a content addressed on-disk cache for chart
+// tarballs so charts are only pulled again when their tarball URL changes or
+// the cached tarball of the URL has to be revalidated.
+package chartcache
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/afero"
+)
+
+// tarballPattern matches the names of the tarballs stored by the cache and
+// of the temporary files they are written to.
+var tarballPattern = regexp.MustCompile(`^[0-9a-f]{64}\.tgz(\.[0-9]+)?$`)
+
+type Config struct {
+	Fs     afero.Fs
+	Logger micrologger.Logger
+
+	// Directory is the directory the cached tarballs are stored in. Cached
+	// tarballs left in it are removed when the cache is created since the
+	// index is kept in memory. Other files in it are kept.
+	Directory string
+	// MaxSize is the maximum size of all cached tarballs in bytes. The least
+	// recently used tarballs are evicted when it is exceeded. Caching is
+	// disabled when it is 0.
+	MaxSize int64
+	// MaxAge is the duration after which the tarball cached for a tarball
+	// URL is pulled again, as tags and tarball URLs may be published again
+	// with other content. Tarballs requested by digest and tarball URLs
+	// pinned to a digest are not revalidated. Tarballs are never
+	// revalidated when it is 0.
+	MaxAge time.Duration
+}
+
+// Stats are the counters and the size of the cache.
+type Stats struct {
+	Evictions uint64
+	Hits      uint64
+	Misses    uint64
+	Size      int64
+}
+
+// Cache stores chart tarballs by their SHA-256 digest and indexes them by
+// tarball URL.
+type Cache struct {
+	fs     afero.Fs
+	logger micrologger.Logger
+
+	directory string
+	maxAge    time.Duration
+	maxSize   int64
+
+	evictions atomic.Uint64
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+
+	mutex sync.Mutex
+	// digests maps tarball URLs to the digest of their tarball.
+	digests map[string]urlEntry
+	// entries maps digests to their element in the LRU list.
+	entries map[string]*list.Element
+	lru     *list.List
+	size    int64
+}
+
+type urlEntry struct {
+	digest    string
+	indexedAt time.Time
+	// verified is true when the tarball with the digest has been verified
+	// against the provenance of the URL.
+	verified bool
+}
+
+type entry struct {
+	digest string
+	path   string
+	size   int64
+}
+
+func New(config Config) (*Cache, error) {
+	if config.Fs == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Fs must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.MaxSize < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.MaxSize must not be negative", config)
+	}
+	if config.MaxAge < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.MaxAge must not be negative", config)
+	}
+	if config.MaxSize > 0 && config.Directory == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Directory must not be empty", config)
+	}
+
+	if config.MaxSize > 0 {
+		err := config.Fs.MkdirAll(config.Directory, 0755)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		err = removeTarballs(config.Fs, config.Directory)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	c := &Cache{
+		fs:     config.Fs,
+		logger: config.Logger,
+
+		directory: config.Directory,
+		maxAge:    config.MaxAge,
+		maxSize:   config.MaxSize,
+
+		digests: map[string]urlEntry{},
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+
+	return c, nil
+}
+
+// Get returns the path of a copy of the cached tarball for the given URL.
+// The caller owns the copy and must remove it. When expectedDigest is not
+// empty the tarball with this digest is returned, no matter which URL it was
+// pulled from. The returned bool is false when there is no matching tarball
+// in the cache or the tarball of the URL must be revalidated.
+func (c *Cache) Get(ctx context.Context, url, expectedDigest string) (string, bool, error) {
+	if c.maxSize == 0 {
+		return "", false, nil
+	}
+
+	cachedPath, ok := c.lookup(ctx, url, expectedDigest)
+	if !ok {
+		c.misses.Add(1)
+		return "", false, nil
+	}
+
+	// The tarball is copied without holding the mutex so pulls of other
+	// charts are not serialised. When it was evicted in the meantime it is
+	// pulled again.
+	path, err := c.copyToTemp(cachedPath)
+	if errors.Is(err, os.ErrNotExist) {
+		c.misses.Add(1)
+		return "", false, nil
+	} else if err != nil {
+		return "", false, microerror.Mask(err)
+	}
+
+	c.hits.Add(1)
+
+	return path, true, nil
+}
+
+// Stats returns the counters and the size of the cache.
+func (c *Cache) Stats() Stats {
+	c.mutex.Lock()
+	size := c.size
+	c.mutex.Unlock()
+
+	return Stats{
+		Evictions: c.evictions.Load(),
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Size:      size,
+	}
+}
+
+// Put adds the tarball pulled from the given URL to the cache and returns
+// its digest. When expectedDigest is not empty and does not match the
+// digest of the tarball a digestMismatchError is returned and the tarball
+// is not cached.
+func (c *Cache) Put(ctx context.Context, url, tarballPath, expectedDigest string) (string, error) {
+	digest, size, err := c.digest(tarballPath)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	if expectedDigest != "" && digest != NormalizeDigest(expectedDigest) {
+		return "", microerror.Maskf(digestMismatchError, "tarball %#q has digest %#q, want %#q", url, digest, NormalizeDigest(expectedDigest))
+	}
+
+	if c.maxSize == 0 {
+		return digest, nil
+	}
+	if size > c.maxSize {
+		c.logger.Debugf(ctx, "not caching tarball %#q, size %d exceeds max size %d", url, size, c.maxSize)
+		return digest, nil
+	}
+
+	if c.index(url, digest) {
+		return digest, nil
+	}
+
+	// The tarball is copied to a temporary file without holding the mutex
+	// and only renamed once it is written completely.
+	tmpPath, err := c.copyToDirectory(tarballPath, digest)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// The same tarball may have been stored concurrently.
+	if e, ok := c.entries[digest]; ok {
+		c.removeFile(ctx, tmpPath)
+		c.digests[url] = urlEntry{digest: digest, indexedAt: time.Now()}
+		c.lru.MoveToFront(e)
+		return digest, nil
+	}
+
+	path := filepath.Join(c.directory, fmt.Sprintf("%s.tgz", digest))
+
+	err = c.fs.Rename(tmpPath, path)
+	if err != nil {
+		c.removeFile(ctx, tmpPath)
+		return "", microerror.Mask(err)
+	}
+
+	// The URL is only indexed once the tarball is stored so a failed copy
+	// does not leave a URL without entry.
+	c.entries[digest] = c.lru.PushFront(&entry{
+		digest: digest,
+		path:   path,
+		size:   size,
+	})
+	c.digests[url] = urlEntry{digest: digest, indexedAt: time.Now()}
+	c.size += size
+
+	for c.size > c.maxSize {
+		c.evict(ctx)
+	}
+
+	return digest, nil
+}
+
+// Verify returns the digest of the tarball without caching it. When
+// expectedDigest is not empty and does not match the digest of the tarball a
+// digestMismatchError is returned.
+func (c *Cache) Verify(tarballPath, expectedDigest string) (string, error) {
+	digest, _, err := c.digest(tarballPath)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	if expectedDigest != "" && digest != NormalizeDigest(expectedDigest) {
+		return "", microerror.Maskf(digestMismatchError, "tarball %#q has digest %#q, want %#q", tarballPath, digest, NormalizeDigest(expectedDigest))
+	}
+
+	return digest, nil
+}
+
+// SetVerified records that the tarball with the given digest has been
+// verified against the provenance of the URL. It is a no-op when the URL is
+// not indexed with this digest.
+func (c *Cache) SetVerified(url, digest string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	u, ok := c.digests[url]
+	if !ok || u.digest != NormalizeDigest(digest) {
+		return
+	}
+
+	u.verified = true
+	c.digests[url] = u
+}
+
+// IsVerified returns true when the tarball with the given digest has been
+// verified against the provenance of the URL.
+func (c *Cache) IsVerified(url, digest string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	u, ok := c.digests[url]
+
+	return ok && u.verified && u.digest == NormalizeDigest(digest)
+}
+
+// Remove evicts the tarball with the given digest from the cache together
+// with the URLs indexed with it.
+func (c *Cache) Remove(ctx context.Context, digest string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, ok := c.entries[NormalizeDigest(digest)]
+	if !ok {
+		return
+	}
+
+	c.remove(ctx, e)
+}
+
+// NormalizeDigest returns the hex encoded SHA-256 digest without the
+// optional `sha256:` prefix.
+func NormalizeDigest(digest string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(digest)), "sha256:")
+}
+
+// lookup returns the path of the cached tarball for the URL or the expected
+// digest and marks it as recently used.
+func (c *Cache) lookup(ctx context.Context, url, expectedDigest string) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	digest := NormalizeDigest(expectedDigest)
+	if digest == "" {
+		u, ok := c.digests[url]
+		if !ok {
+			return "", false
+		}
+		if c.maxAge > 0 && !isPinnedURL(url) && time.Since(u.indexedAt) > c.maxAge {
+			c.logger.Debugf(ctx, "cached tarball for %#q is older than %s, revalidating", url, c.maxAge)
+			delete(c.digests, url)
+			return "", false
+		}
+
+		digest = u.digest
+	}
+
+	e, ok := c.entries[digest]
+	if !ok {
+		return "", false
+	}
+	c.lru.MoveToFront(e)
+
+	return e.Value.(*entry).path, true
+}
+
+// index indexes the URL with the digest of its tarball when the tarball is
+// already stored and marks it as recently used.
+func (c *Cache) index(url, digest string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, ok := c.entries[digest]
+	if !ok {
+		return false
+	}
+
+	c.digests[url] = urlEntry{digest: digest, indexedAt: time.Now()}
+	c.lru.MoveToFront(e)
+
+	return true
+}
+
+// copyToDirectory copies the tarball to a temporary file in the cache
+// directory. Partially written files are removed.
+func (c *Cache) copyToDirectory(src, digest string) (string, error) {
+	in, err := c.fs.Open(src)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := afero.TempFile(c.fs, c.directory, fmt.Sprintf("%s.tgz.*", digest))
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	defer func() { _ = out.Close() }()
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		_ = c.fs.Remove(out.Name())
+		return "", microerror.Mask(err)
+	}
+
+	return out.Name(), nil
+}
+
+func (c *Cache) copyToTemp(src string) (string, error) {
+	in, err := c.fs.Open(src)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := afero.TempFile(c.fs, "", "chart-*.tgz")
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	defer func() { _ = out.Close() }()
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return out.Name(), nil
+}
+
+func (c *Cache) digest(path string) (string, int64, error) {
+	f, err := c.fs.Open(path)
+	if err != nil {
+		return "", 0, microerror.Mask(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, microerror.Mask(err)
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), size, nil
+}
+
+// removeTarballs removes the tarballs stored in the directory by a previous
+// cache. Files not created by the cache are kept so the directory may be
+// shared.
+func removeTarballs(fs afero.Fs, directory string) error {
+	infos, err := afero.ReadDir(fs, directory)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, info := range infos {
+		if !info.Mode().IsRegular() || !tarballPattern.MatchString(info.Name()) {
+			continue
+		}
+
+		err = fs.Remove(filepath.Join(directory, info.Name()))
+		if err != nil && !os.IsNotExist(err) {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// evict removes the least recently used tarball. It must be called with the
+// mutex held.
+func (c *Cache) evict(ctx context.Context) {
+	e := c.lru.Back()
+	if e == nil {
+		return
+	}
+
+	c.remove(ctx, e)
+}
+
+// remove removes the tarball of the LRU list element and the URLs indexed
+// with it. It must be called with the mutex held.
+func (c *Cache) remove(ctx context.Context, e *list.Element) {
+	evicted := c.lru.Remove(e).(*entry)
+	delete(c.entries, evicted.digest)
+	c.size -= evicted.size
+
+	for url, u := range c.digests {
+		if u.digest == evicted.digest {
+			delete(c.digests, url)
+		}
+	}
+
+	c.removeFile(ctx, evicted.path)
+
+	c.evictions.Add(1)
+
+	c.logger.Debugf(ctx, "evicted tarball with digest %#q from cache", evicted.digest)
+}
+
+func (c *Cache) removeFile(ctx context.Context, path string) {
+	err := c.fs.Remove(path)
+	if err != nil {
+		c.logger.Errorf(ctx, err, "deletion of %#q failed", path)
+	}
+}
+
+// isPinnedURL returns true for oci:// references pinned to a manifest
+// digest whose content cannot change.
+func isPinnedURL(url string) bool {
+	return strings.Contains(url, "@sha256:")
+}
@@ -0,0 +1,420 @@
+package chartcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/spf13/afero"
+)
+
+func Test_Cache(t *testing.T) {
+	ctx := context.Background()
+
+	testCases := []struct {
+		name string
+		// tarballs are the URLs of the tarballs put into the cache in
+		// order.
+		tarballs       []string
+		maxSize        int64
+		url            string
+		expectedDigest string
+		expectedHit    bool
+	}{
+		{
+			name:        "case 0: cached tarball is returned",
+			tarballs:    []string{"a"},
+			maxSize:     1024,
+			url:         "a",
+			expectedHit: true,
+		},
+		{
+			name:     "case 1: missing tarball is not returned",
+			tarballs: []string{"a"},
+			maxSize:  1024,
+			url:      "b",
+		},
+		{
+			name:           "case 2: cached tarball with matching digest is returned",
+			tarballs:       []string{"a"},
+			maxSize:        1024,
+			url:            "a",
+			expectedDigest: "sha256:" + digestOf("a"),
+			expectedHit:    true,
+		},
+		{
+			name:           "case 3: cached tarball with different digest is not returned",
+			tarballs:       []string{"a"},
+			maxSize:        1024,
+			url:            "a",
+			expectedDigest: digestOf("b"),
+		},
+		{
+			name:     "case 4: least recently used tarball is evicted",
+			tarballs: []string{"a", "b", "c"},
+			maxSize:  int64(2 * len(content("a"))),
+			url:      "a",
+		},
+		{
+			name:        "case 5: recently used tarball is not evicted",
+			tarballs:    []string{"a", "b", "c"},
+			maxSize:     int64(2 * len(content("a"))),
+			url:         "c",
+			expectedHit: true,
+		},
+		{
+			name:     "case 6: disabled cache does not return tarballs",
+			tarballs: []string{"a"},
+			url:      "a",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			fs := afero.NewMemMapFs()
+
+			c, err := New(Config{
+				Fs:     fs,
+				Logger: microloggertest.New(),
+
+				Directory: "/cache",
+				MaxSize:   tc.maxSize,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			for _, url := range tc.tarballs {
+				path := fmt.Sprintf("/tmp/%s.tgz", url)
+
+				err = afero.WriteFile(fs, path, []byte(content(url)), 0644)
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+
+				digest, err := c.Put(ctx, url, path, "")
+				if err != nil {
+					t.Fatalf("error == %#v, want nil", err)
+				}
+				if digest != digestOf(url) {
+					t.Fatalf("digest == %#q, want %#q", digest, digestOf(url))
+				}
+			}
+
+			path, hit, err := c.Get(ctx, tc.url, tc.expectedDigest)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if hit != tc.expectedHit {
+				t.Fatalf("hit == %t, want %t", hit, tc.expectedHit)
+			}
+			if !hit {
+				return
+			}
+
+			b, err := afero.ReadFile(fs, path)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if string(b) != content(tc.url) {
+				t.Fatalf("tarball == %#q, want %#q", b, content(tc.url))
+			}
+		})
+	}
+}
+
+func Test_Cache_Put_digestMismatch(t *testing.T) {
+	ctx := context.Background()
+	fs := afero.NewMemMapFs()
+
+	c, err := New(Config{
+		Fs:     fs,
+		Logger: microloggertest.New(),
+
+		Directory: "/cache",
+		MaxSize:   1024,
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	err = afero.WriteFile(fs, "/tmp/a.tgz", []byte(content("a")), 0644)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	_, err = c.Put(ctx, "a", "/tmp/a.tgz", digestOf("b"))
+	if !IsDigestMismatch(err) {
+		t.Fatalf("error == %#v, want digest mismatch error", err)
+	}
+
+	_, hit, err := c.Get(ctx, "a", "")
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if hit {
+		t.Fatalf("hit == %t, want %t", hit, false)
+	}
+}
+
+func Test_Cache_verified(t *testing.T) {
+	ctx := context.Background()
+	fs := afero.NewMemMapFs()
+
+	c, err := New(Config{
+		Fs:     fs,
+		Logger: microloggertest.New(),
+
+		Directory: "/cache",
+		MaxSize:   1024,
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	for _, url := range []string{"a", "b"} {
+		err = afero.WriteFile(fs, "/tmp/"+url+".tgz", []byte(content(url)), 0644)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+
+		_, err = c.Put(ctx, url, "/tmp/"+url+".tgz", "")
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+	}
+
+	if c.IsVerified("a", digestOf("a")) {
+		t.Fatalf("verified == %t, want %t", true, false)
+	}
+
+	// Digests not indexed for the URL are not recorded.
+	c.SetVerified("a", "sha256:"+digestOf("b"))
+	if c.IsVerified("a", digestOf("b")) {
+		t.Fatalf("verified == %t, want %t", true, false)
+	}
+
+	c.SetVerified("a", "sha256:"+digestOf("a"))
+	if !c.IsVerified("a", digestOf("a")) {
+		t.Fatalf("verified == %t, want %t", false, true)
+	}
+	if c.IsVerified("b", digestOf("b")) {
+		t.Fatalf("verified == %t, want %t", true, false)
+	}
+
+	c.Remove(ctx, digestOf("a"))
+
+	_, hit, err := c.Get(ctx, "a", "")
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if hit {
+		t.Fatalf("hit == %t, want %t", hit, false)
+	}
+	if c.IsVerified("a", digestOf("a")) {
+		t.Fatalf("verified == %t, want %t", true, false)
+	}
+
+	_, hit, err = c.Get(ctx, "b", "")
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if !hit {
+		t.Fatalf("hit == %t, want %t", hit, true)
+	}
+
+	if c.Stats().Evictions != 1 {
+		t.Fatalf("evictions == %d, want %d", c.Stats().Evictions, 1)
+	}
+}
+
+func Test_Cache_Put_copyFailed(t *testing.T) {
+	ctx := context.Background()
+	fs := afero.NewMemMapFs()
+
+	c, err := New(Config{
+		Fs:     fs,
+		Logger: microloggertest.New(),
+
+		Directory: "/cache",
+		MaxSize:   1024,
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	err = afero.WriteFile(fs, "/tmp/a.tgz", []byte(content("a")), 0644)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	// Storing the tarball in the cache directory fails on a read-only
+	// filesystem.
+	c.fs = afero.NewReadOnlyFs(fs)
+
+	_, err = c.Put(ctx, "a", "/tmp/a.tgz", "")
+	if err == nil {
+		t.Fatalf("error == nil, want non-nil")
+	}
+
+	_, hit, err := c.Get(ctx, "a", "")
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if hit {
+		t.Fatalf("hit == %t, want %t", hit, false)
+	}
+}
+
+func Test_Cache_Get_revalidation(t *testing.T) {
+	ctx := context.Background()
+
+	testCases := []struct {
+		name           string
+		putURL         string
+		maxAge         time.Duration
+		url            string
+		expectedDigest string
+		expectedHit    bool
+	}{
+		{
+			name:        "case 0: tarball is returned without max age",
+			putURL:      "a",
+			url:         "a",
+			expectedHit: true,
+		},
+		{
+			name:   "case 1: tarball older than max age is revalidated",
+			putURL: "a",
+			maxAge: time.Nanosecond,
+			url:    "a",
+		},
+		{
+			name:           "case 2: tarball requested by digest is not revalidated",
+			putURL:         "a",
+			maxAge:         time.Nanosecond,
+			url:            "a",
+			expectedDigest: "sha256:" + digestOf("a"),
+			expectedHit:    true,
+		},
+		{
+			name:        "case 3: tarball of pinned oci reference is not revalidated",
+			putURL:      "oci://example.com/charts/a@sha256:" + digestOf("manifest"),
+			maxAge:      time.Nanosecond,
+			url:         "oci://example.com/charts/a@sha256:" + digestOf("manifest"),
+			expectedHit: true,
+		},
+		{
+			name:           "case 4: tarball requested by digest is returned for other url",
+			putURL:         "a",
+			url:            "b",
+			expectedDigest: digestOf("a"),
+			expectedHit:    true,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			fs := afero.NewMemMapFs()
+
+			c, err := New(Config{
+				Fs:     fs,
+				Logger: microloggertest.New(),
+
+				Directory: "/cache",
+				MaxAge:    tc.maxAge,
+				MaxSize:   1024,
+			})
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			err = afero.WriteFile(fs, "/tmp/a.tgz", []byte(content("a")), 0644)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			_, err = c.Put(ctx, tc.putURL, "/tmp/a.tgz", "")
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			time.Sleep(time.Millisecond)
+
+			_, hit, err := c.Get(ctx, tc.url, tc.expectedDigest)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+			if hit != tc.expectedHit {
+				t.Fatalf("hit == %t, want %t", hit, tc.expectedHit)
+			}
+
+			expectedStats := Stats{
+				Size: int64(len(content("a"))),
+			}
+			if hit {
+				expectedStats.Hits = 1
+			} else {
+				expectedStats.Misses = 1
+			}
+			if c.Stats() != expectedStats {
+				t.Fatalf("stats == %#v, want %#v", c.Stats(), expectedStats)
+			}
+		})
+	}
+}
+
+func Test_New_removesCachedTarballsOnly(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	files := map[string]bool{
+		fmt.Sprintf("/cache/%s.tgz", digestOf("a")):      false,
+		fmt.Sprintf("/cache/%s.tgz.1234", digestOf("a")): false,
+		"/cache/values.yaml":                             true,
+		"/cache/chart.tgz":                               true,
+	}
+
+	for path := range files {
+		err := afero.WriteFile(fs, path, []byte(content("a")), 0644)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+	}
+
+	_, err := New(Config{
+		Fs:     fs,
+		Logger: microloggertest.New(),
+
+		Directory: "/cache",
+		MaxSize:   1024,
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	for path, expectedExists := range files {
+		exists, err := afero.Exists(fs, path)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		if exists != expectedExists {
+			t.Fatalf("%#q exists == %t, want %t", path, exists, expectedExists)
+		}
+	}
+}
+
+func content(url string) string {
+	return fmt.Sprintf("tarball %s", url)
+}
+
+func digestOf(url string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(content(url))))
+}
@@ -0,0 +1,21 @@
+package chartcache
+
+import "github.com/giantswarm/microerror"
+
+var digestMismatchError = &microerror.Error{
+	Kind: "digestMismatchError",
+}
+
+// IsDigestMismatch asserts digestMismatchError.
+func IsDigestMismatch(err error) bool {
+	return microerror.Cause(err) == digestMismatchError
+}
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
@@ -0,0 +1,187 @@
+// Package chartverifier verifies chart tarballs against their Helm provenance
+// files using the keyrings configured for the catalogs they are pulled from.
+package chartverifier
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/afero"
+	"golang.org/x/crypto/openpgp" //nolint:staticcheck
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+// Keyring configures the public keys charts are verified with whose tarball
+// URL starts with URLPrefix.
+type Keyring struct {
+	// URLPrefix is the prefix of the tarball URLs, e.g. the catalog URL
+	// https://giantswarm.github.io/giantswarm-catalog/ or the OCI repository
+	// oci://gsoci.azurecr.io/charts/giantswarm/.
+	URLPrefix string
+	// Path is the path of the binary or ASCII armored keyring file.
+	Path string
+}
+
+type Config struct {
+	Fs     afero.Fs
+	Logger micrologger.Logger
+
+	Keyrings []Keyring
+}
+
+// Verifier verifies chart tarballs against their Helm provenance files.
+type Verifier struct {
+	logger micrologger.Logger
+
+	// signatories maps tarball URL prefixes to the signatory of their
+	// keyring. prefixes is sorted by descending length so the longest prefix
+	// matches first.
+	signatories map[string]*provenance.Signatory
+	prefixes    []string
+}
+
+func New(config Config) (*Verifier, error) {
+	if config.Fs == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Fs must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	v := &Verifier{
+		logger: config.Logger,
+
+		signatories: map[string]*provenance.Signatory{},
+	}
+
+	for _, k := range config.Keyrings {
+		if k.URLPrefix == "" {
+			return nil, microerror.Maskf(invalidConfigError, "keyring %#q must have a URL prefix", k.Path)
+		}
+		if _, ok := v.signatories[k.URLPrefix]; ok {
+			return nil, microerror.Maskf(invalidConfigError, "URL prefix %#q must only have one keyring", k.URLPrefix)
+		}
+
+		keyring, err := readKeyring(config.Fs, k.Path)
+		if err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "reading keyring %#q failed: %s", k.Path, err.Error())
+		}
+
+		v.signatories[k.URLPrefix] = &provenance.Signatory{KeyRing: keyring}
+		v.prefixes = append(v.prefixes, k.URLPrefix)
+	}
+
+	sort.Slice(v.prefixes, func(i, j int) bool {
+		return len(v.prefixes[i]) > len(v.prefixes[j])
+	})
+
+	return v, nil
+}
+
+// Enabled returns true when charts pulled from the tarball URL must be
+// verified.
+func (v *Verifier) Enabled(url string) bool {
+	return v.signatory(url) != nil
+}
+
+// Verify verifies the chart tarball pulled from the tarball URL against its
+// provenance file and returns the verified digest in sha256:<hex> format. It
+// returns a verificationFailedError when the provenance file is not signed
+// by a key of the keyring for the tarball URL or does not list the digest of
+// the tarball for the file name of the packaged chart.
+func (v *Verifier) Verify(ctx context.Context, url string, tarball, prov []byte) (string, error) {
+	signatory := v.signatory(url)
+	if signatory == nil {
+		return "", microerror.Maskf(verificationFailedError, "no keyring configured for %#q", url)
+	}
+
+	// The signed checksums are keyed by the file name of the packaged chart
+	// which does not have to match the tarball URL, e.g. for OCI references.
+	chart, err := loader.LoadArchive(bytes.NewReader(tarball))
+	if err != nil {
+		return "", microerror.Maskf(verificationFailedError, "loading chart %#q failed: %s", url, err.Error())
+	}
+	fileName := fmt.Sprintf("%s-%s.tgz", chart.Metadata.Name, chart.Metadata.Version)
+
+	// Helm only verifies files so the tarball and its provenance file are
+	// written to a temporary directory.
+	dir, err := os.MkdirTemp("", "chart-verifier-")
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			v.logger.Errorf(ctx, err, "deletion of %#q failed", dir)
+		}
+	}()
+
+	chartPath := filepath.Join(dir, fileName)
+	err = os.WriteFile(chartPath, tarball, 0600)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	provPath := chartPath + ".prov"
+	err = os.WriteFile(provPath, prov, 0600)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	verification, err := signatory.Verify(chartPath, provPath)
+	if err != nil {
+		return "", microerror.Maskf(verificationFailedError, "verifying provenance of %#q failed: %s", url, err.Error())
+	}
+
+	v.logger.Debugf(ctx, "verified chart %#q with digest %#q signed by %s", url, verification.FileHash, signerName(verification))
+
+	return verification.FileHash, nil
+}
+
+func (v *Verifier) signatory(url string) *provenance.Signatory {
+	for _, prefix := range v.prefixes {
+		if strings.HasPrefix(url, prefix) {
+			return v.signatories[prefix]
+		}
+	}
+
+	return nil
+}
+
+func readKeyring(fs afero.Fs, path string) (openpgp.EntityList, error) {
+	b, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var keyring openpgp.EntityList
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("-----BEGIN")) {
+		keyring, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(b))
+	} else {
+		keyring, err = openpgp.ReadKeyRing(bytes.NewReader(b))
+	}
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if len(keyring) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "keyring has no keys")
+	}
+
+	return keyring, nil
+}
+
+func signerName(verification *provenance.Verification) string {
+	for name := range verification.SignedBy.Identities {
+		return fmt.Sprintf("%#q", name)
+	}
+
+	return fmt.Sprintf("key %X", verification.SignedBy.PrimaryKey.KeyId)
+}
@@ -0,0 +1,278 @@
+package chartverifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/giantswarm/micrologger/microloggertest"
+	"github.com/spf13/afero"
+	"golang.org/x/crypto/openpgp"       //nolint:staticcheck
+	"golang.org/x/crypto/openpgp/armor" //nolint:staticcheck
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+const (
+	catalogURL = "https://giantswarm.github.io/giantswarm-catalog/"
+	tarballURL = catalogURL + "hello-world-1.0.0.tgz"
+)
+
+func Test_Verifier_Verify(t *testing.T) {
+	catalogKey := newEntity(t, "catalog")
+	otherKey := newEntity(t, "other")
+
+	tarball := newChartTarball(t, "1.0.0", "hello-world")
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(tarball))
+
+	testCases := []struct {
+		name           string
+		url            string
+		provenance     []byte
+		expectedDigest string
+		errorMatcher   func(error) bool
+	}{
+		{
+			name:           "case 0: provenance signed by catalog key",
+			url:            tarballURL,
+			provenance:     signProvenance(t, catalogKey, tarball),
+			expectedDigest: digest,
+		},
+		{
+			name:         "case 1: provenance signed by other key",
+			url:          tarballURL,
+			provenance:   signProvenance(t, otherKey, tarball),
+			errorMatcher: IsVerificationFailed,
+		},
+		{
+			name:         "case 2: provenance of tampered chart",
+			url:          tarballURL,
+			provenance:   signProvenance(t, catalogKey, newChartTarball(t, "1.0.0", "tampered")),
+			errorMatcher: IsVerificationFailed,
+		},
+		{
+			name:         "case 3: provenance not signed",
+			url:          tarballURL,
+			provenance:   []byte(provenanceBody(digest)),
+			errorMatcher: IsVerificationFailed,
+		},
+		{
+			name:         "case 4: provenance of other chart version",
+			url:          tarballURL,
+			provenance:   signProvenance(t, catalogKey, newChartTarball(t, "1.0.1", "hello-world")),
+			errorMatcher: IsVerificationFailed,
+		},
+		{
+			name:         "case 5: provenance signed by catalog key of other catalog",
+			url:          catalogURL + "internal/hello-world-1.0.0.tgz",
+			provenance:   signProvenance(t, catalogKey, tarball),
+			errorMatcher: IsVerificationFailed,
+		},
+		{
+			name:         "case 6: no keyring for tarball URL",
+			url:          "https://example.com/hello-world-1.0.0.tgz",
+			provenance:   signProvenance(t, catalogKey, tarball),
+			errorMatcher: IsVerificationFailed,
+		},
+	}
+
+	fs := afero.NewMemMapFs()
+	writeKeyring(t, fs, "/keyrings/catalog.asc", catalogKey)
+	writeKeyring(t, fs, "/keyrings/other.asc", otherKey)
+
+	v, err := New(Config{
+		Fs:     fs,
+		Logger: microloggertest.New(),
+
+		Keyrings: []Keyring{
+			{
+				URLPrefix: catalogURL,
+				Path:      "/keyrings/catalog.asc",
+			},
+			{
+				URLPrefix: catalogURL + "internal/",
+				Path:      "/keyrings/other.asc",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			result, err := v.Verify(context.Background(), tc.url, tarball, tc.provenance)
+			switch {
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case err != nil && !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if result != tc.expectedDigest {
+				t.Fatalf("digest == %#q, want %#q", result, tc.expectedDigest)
+			}
+		})
+	}
+}
+
+func Test_Verifier_Enabled(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	writeKeyring(t, fs, "/keyrings/catalog.asc", newEntity(t, "catalog"))
+
+	v, err := New(Config{
+		Fs:     fs,
+		Logger: microloggertest.New(),
+
+		Keyrings: []Keyring{
+			{
+				URLPrefix: catalogURL,
+				Path:      "/keyrings/catalog.asc",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	if !v.Enabled(tarballURL) {
+		t.Fatalf("Enabled(%#q) == false, want true", tarballURL)
+	}
+	if v.Enabled("https://example.com/hello-world-1.0.0.tgz") {
+		t.Fatalf("Enabled(%#q) == true, want false", "https://example.com/hello-world-1.0.0.tgz")
+	}
+}
+
+func Test_New_invalidKeyring(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	err := afero.WriteFile(fs, "/keyrings/invalid.asc", []byte("not a keyring"), 0644)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	_, err = New(Config{
+		Fs:     fs,
+		Logger: microloggertest.New(),
+
+		Keyrings: []Keyring{
+			{
+				URLPrefix: catalogURL,
+				Path:      "/keyrings/invalid.asc",
+			},
+		},
+	})
+	if !IsInvalidConfig(err) {
+		t.Fatalf("error == %#v, want invalid config error", err)
+	}
+}
+
+func newEntity(t *testing.T, name string) *openpgp.Entity {
+	t.Helper()
+
+	e, err := openpgp.NewEntity(name, "", fmt.Sprintf("%s@example.com", name), nil)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	return e
+}
+
+func provenanceBody(digest string) string {
+	return fmt.Sprintf(`apiVersion: v2
+name: hello-world
+version: 1.0.0
+
+...
+files:
+  hello-world-1.0.0.tgz: %s
+`, digest)
+}
+
+func newChartTarball(t *testing.T, version, description string) []byte {
+	t.Helper()
+
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{
+			APIVersion:  chart.APIVersionV2,
+			Name:        "hello-world",
+			Version:     version,
+			Description: description,
+		},
+	}
+
+	path, err := chartutil.Save(c, t.TempDir())
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	return b
+}
+
+func signProvenance(t *testing.T, e *openpgp.Entity, tarball []byte) []byte {
+	t.Helper()
+
+	c, err := loader.LoadArchive(bytes.NewReader(tarball))
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	// Helm signs the checksum of the tarball under its file name.
+	path := filepath.Join(t.TempDir(), fmt.Sprintf("%s-%s.tgz", c.Metadata.Name, c.Metadata.Version))
+
+	err = os.WriteFile(path, tarball, 0600)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	signatory := &provenance.Signatory{Entity: e}
+
+	sig, err := signatory.ClearSign(path)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	return []byte(sig)
+}
+
+func writeKeyring(t *testing.T, fs afero.Fs, path string, e *openpgp.Entity) {
+	t.Helper()
+
+	var b bytes.Buffer
+
+	w, err := armor.Encode(&b, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	err = e.Serialize(w)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	err = afero.WriteFile(fs, path, b.Bytes(), 0644)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+}
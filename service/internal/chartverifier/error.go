@@ -0,0 +1,21 @@
+package chartverifier
+
+import "github.com/giantswarm/microerror"
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var verificationFailedError = &microerror.Error{
+	Kind: "verificationFailedError",
+}
+
+// IsVerificationFailed asserts verificationFailedError.
+func IsVerificationFailed(err error) bool {
+	return microerror.Cause(err) == verificationFailedError
+}
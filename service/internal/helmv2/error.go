@@ -0,0 +1,12 @@
+package helmv2
+
+import "github.com/giantswarm/microerror"
+
+var invalidReleaseError = &microerror.Error{
+	Kind: "invalidReleaseError",
+}
+
+// IsInvalidRelease asserts invalidReleaseError.
+func IsInvalidRelease(err error) bool {
+	return microerror.Cause(err) == invalidReleaseError
+}
@@ -0,0 +1,594 @@
+// Package helmv2 converts Helm 2 releases stored by Tiller in config maps
+// into Helm 3 releases, like the helm-2to3 plugin does. The releases are
+// decoded from their protobuf wire format so the Helm 2 libraries are not
+// needed.
+package helmv2
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+	"strconv"
+
+	"github.com/giantswarm/microerror"
+	"google.golang.org/protobuf/encoding/protowire"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+	helmtime "helm.sh/helm/v3/pkg/time"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// releaseKey is the config map data key holding the encoded release.
+	releaseKey = "release"
+
+	// requirementsFile and requirementsLockFile hold the dependencies of
+	// Helm 2 charts. Helm 3 reads them into the chart metadata and lock of
+	// charts with API version v1.
+	requirementsFile     = "requirements.yaml"
+	requirementsLockFile = "requirements.lock"
+)
+
+// gzipMagic is the header of gzip compressed releases.
+var gzipMagic = []byte{0x1f, 0x8b, 0x08}
+
+// statuses maps the Helm 2 status codes to Helm 3 release statuses.
+var statuses = map[uint64]release.Status{
+	0: release.StatusUnknown,
+	1: release.StatusDeployed,
+	2: release.StatusUninstalled,
+	3: release.StatusSuperseded,
+	4: release.StatusFailed,
+	5: release.StatusUninstalling,
+	6: release.StatusPendingInstall,
+	7: release.StatusPendingUpgrade,
+	8: release.StatusPendingRollback,
+}
+
+// hookEvents maps the Helm 2 hook events to Helm 3 hook events. The Helm 2
+// crd-install event is dropped since Helm 3 installs CRDs from the crds
+// directory instead.
+var hookEvents = map[uint64]release.HookEvent{
+	1:  release.HookPreInstall,
+	2:  release.HookPostInstall,
+	3:  release.HookPreDelete,
+	4:  release.HookPostDelete,
+	5:  release.HookPreUpgrade,
+	6:  release.HookPostUpgrade,
+	7:  release.HookPreRollback,
+	8:  release.HookPostRollback,
+	9:  release.HookTest,
+	10: release.HookTest,
+}
+
+// hookDeletePolicies maps the Helm 2 hook delete policies to Helm 3 hook
+// delete policies.
+var hookDeletePolicies = map[uint64]release.HookDeletePolicy{
+	0: release.HookSucceeded,
+	1: release.HookFailed,
+	2: release.HookBeforeHookCreation,
+}
+
+// Version returns the revision of the Helm 2 release config map from its
+// VERSION label.
+func Version(cm corev1.ConfigMap) (int, error) {
+	v, err := strconv.Atoi(cm.Labels["VERSION"])
+	if err != nil {
+		return 0, microerror.Maskf(invalidReleaseError, "config map %#q has invalid VERSION label %#q", cm.Name, cm.Labels["VERSION"])
+	}
+
+	return v, nil
+}
+
+// ConvertConfigMap decodes the Helm 2 release stored in the config map and
+// converts it into a Helm 3 release.
+func ConvertConfigMap(cm corev1.ConfigMap) (*release.Release, error) {
+	data, ok := cm.Data[releaseKey]
+	if !ok {
+		return nil, microerror.Maskf(invalidReleaseError, "config map %#q has no %#q key", cm.Name, releaseKey)
+	}
+
+	rel, err := Decode(data)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return rel, nil
+}
+
+// Decode decodes a Helm 2 release encoded the way Tiller stores it, base64
+// encoded and optionally gzip compressed protobuf, into a Helm 3 release.
+func Decode(data string) (*release.Release, error) {
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, microerror.Maskf(invalidReleaseError, "release is not base64 encoded: %s", err.Error())
+	}
+
+	if bytes.HasPrefix(b, gzipMagic) {
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, microerror.Maskf(invalidReleaseError, "release is not gzip compressed: %s", err.Error())
+		}
+		defer r.Close()
+
+		b, err = io.ReadAll(r)
+		if err != nil {
+			return nil, microerror.Maskf(invalidReleaseError, "release is not gzip compressed: %s", err.Error())
+		}
+	}
+
+	rel, err := decodeRelease(b)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return rel, nil
+}
+
+// field is a decoded protobuf field. Only the wire types used by the Helm 2
+// release messages are kept.
+type field struct {
+	num    protowire.Number
+	typ    protowire.Type
+	bytes  []byte
+	varint uint64
+}
+
+func (f field) string() string {
+	return string(f.bytes)
+}
+
+// parseFields decodes the fields of a protobuf message in wire order.
+func parseFields(b []byte) ([]field, error) {
+	var fields []field
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, microerror.Maskf(invalidReleaseError, "invalid protobuf tag: %s", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		f := field{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.varint, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return nil, microerror.Maskf(invalidReleaseError, "invalid protobuf field %d: %s", num, protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
+
+// varints returns the values of a repeated varint field, which is packed
+// into a single bytes field by proto3 encoders.
+func varints(f field) ([]uint64, error) {
+	if f.typ == protowire.VarintType {
+		return []uint64{f.varint}, nil
+	}
+
+	var values []uint64
+	b := f.bytes
+	for len(b) > 0 {
+		v, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return nil, microerror.Maskf(invalidReleaseError, "invalid packed protobuf field %d: %s", f.num, protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		values = append(values, v)
+	}
+
+	return values, nil
+}
+
+// decodeRelease decodes a hapi.release.Release message.
+func decodeRelease(b []byte) (*release.Release, error) {
+	fields, err := parseFields(b)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	rel := &release.Release{
+		Info: &release.Info{},
+	}
+
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			rel.Name = f.string()
+		case 2:
+			rel.Info, err = decodeInfo(f.bytes)
+		case 3:
+			rel.Chart, err = decodeChart(f.bytes)
+		case 4:
+			var raw string
+			raw, err = decodeConfig(f.bytes)
+			if err == nil {
+				rel.Config, err = readValues(raw)
+			}
+		case 5:
+			rel.Manifest = f.string()
+		case 6:
+			var hook *release.Hook
+			hook, err = decodeHook(f.bytes)
+			rel.Hooks = append(rel.Hooks, hook)
+		case 7:
+			rel.Version = int(int32(f.varint))
+		case 8:
+			rel.Namespace = f.string()
+		}
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	if rel.Name == "" {
+		return nil, microerror.Maskf(invalidReleaseError, "release has no name")
+	}
+	if rel.Chart == nil {
+		return nil, microerror.Maskf(invalidReleaseError, "release %#q has no chart", rel.Name)
+	}
+
+	return rel, nil
+}
+
+// decodeInfo decodes a hapi.release.Info message.
+func decodeInfo(b []byte) (*release.Info, error) {
+	fields, err := parseFields(b)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	info := &release.Info{}
+
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			err = decodeStatus(f.bytes, info)
+		case 2:
+			info.FirstDeployed, err = decodeTimestamp(f.bytes)
+		case 3:
+			info.LastDeployed, err = decodeTimestamp(f.bytes)
+		case 4:
+			info.Deleted, err = decodeTimestamp(f.bytes)
+		case 5:
+			info.Description = f.string()
+		}
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	if info.Status == "" {
+		info.Status = release.StatusUnknown
+	}
+
+	return info, nil
+}
+
+// decodeStatus decodes a hapi.release.Status message into the info.
+func decodeStatus(b []byte, info *release.Info) error {
+	fields, err := parseFields(b)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			status, ok := statuses[f.varint]
+			if !ok {
+				return microerror.Maskf(invalidReleaseError, "unknown release status code %d", f.varint)
+			}
+			info.Status = status
+		case 4:
+			info.Notes = f.string()
+		}
+	}
+
+	return nil
+}
+
+// decodeTimestamp decodes a google.protobuf.Timestamp message.
+func decodeTimestamp(b []byte) (helmtime.Time, error) {
+	fields, err := parseFields(b)
+	if err != nil {
+		return helmtime.Time{}, microerror.Mask(err)
+	}
+
+	var seconds, nanos int64
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			seconds = int64(f.varint)
+		case 2:
+			nanos = int64(int32(f.varint))
+		}
+	}
+
+	return helmtime.Unix(seconds, nanos).UTC(), nil
+}
+
+// decodeConfig decodes the raw values of a hapi.chart.Config message.
+func decodeConfig(b []byte) (string, error) {
+	fields, err := parseFields(b)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	var raw string
+	for _, f := range fields {
+		if f.num == 1 {
+			raw = f.string()
+		}
+	}
+
+	return raw, nil
+}
+
+func readValues(raw string) (map[string]interface{}, error) {
+	values, err := chartutil.ReadValues([]byte(raw))
+	if err != nil {
+		return nil, microerror.Maskf(invalidReleaseError, "values are not valid YAML: %s", err.Error())
+	}
+
+	return values, nil
+}
+
+// decodeChart decodes a hapi.chart.Chart message including its
+// dependencies.
+func decodeChart(b []byte) (*chart.Chart, error) {
+	fields, err := parseFields(b)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{},
+	}
+	var dependencies []*chart.Chart
+
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			c.Metadata, err = decodeMetadata(f.bytes)
+		case 2:
+			var file *chart.File
+			file, err = decodeFile(f.bytes)
+			c.Templates = append(c.Templates, file)
+		case 3:
+			var dependency *chart.Chart
+			dependency, err = decodeChart(f.bytes)
+			dependencies = append(dependencies, dependency)
+		case 4:
+			var raw string
+			raw, err = decodeConfig(f.bytes)
+			if err == nil {
+				c.Values, err = readValues(raw)
+			}
+		case 5:
+			var file *chart.File
+			file, err = decodeFile(f.bytes)
+			c.Files = append(c.Files, file)
+		}
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	if c.Metadata.APIVersion == "" {
+		c.Metadata.APIVersion = chart.APIVersionV1
+	}
+
+	// Dependencies of Helm 2 charts are declared in the requirements
+	// files. Helm 3 reads them like its chart loader does for charts with
+	// API version v1.
+	for _, file := range c.Files {
+		switch file.Name {
+		case requirementsFile:
+			err = yaml.Unmarshal(file.Data, c.Metadata)
+			if err != nil {
+				return nil, microerror.Maskf(invalidReleaseError, "chart %#q has invalid %#q: %s", c.Metadata.Name, requirementsFile, err.Error())
+			}
+		case requirementsLockFile:
+			c.Lock = &chart.Lock{}
+			err = yaml.Unmarshal(file.Data, c.Lock)
+			if err != nil {
+				return nil, microerror.Maskf(invalidReleaseError, "chart %#q has invalid %#q: %s", c.Metadata.Name, requirementsLockFile, err.Error())
+			}
+		}
+	}
+
+	c.SetDependencies(dependencies...)
+
+	return c, nil
+}
+
+// decodeMetadata decodes a hapi.chart.Metadata message. The Helm 2 engine
+// and tillerVersion fields have no Helm 3 equivalent and are dropped.
+func decodeMetadata(b []byte) (*chart.Metadata, error) {
+	fields, err := parseFields(b)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	m := &chart.Metadata{}
+
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			m.Name = f.string()
+		case 2:
+			m.Home = f.string()
+		case 3:
+			m.Sources = append(m.Sources, f.string())
+		case 4:
+			m.Version = f.string()
+		case 5:
+			m.Description = f.string()
+		case 6:
+			m.Keywords = append(m.Keywords, f.string())
+		case 7:
+			var maintainer *chart.Maintainer
+			maintainer, err = decodeMaintainer(f.bytes)
+			m.Maintainers = append(m.Maintainers, maintainer)
+		case 9:
+			m.Icon = f.string()
+		case 10:
+			m.APIVersion = f.string()
+		case 11:
+			m.Condition = f.string()
+		case 12:
+			m.Tags = f.string()
+		case 13:
+			m.AppVersion = f.string()
+		case 14:
+			m.Deprecated = f.varint != 0
+		case 16:
+			var k, v string
+			k, v, err = decodeMapEntry(f.bytes)
+			if m.Annotations == nil {
+				m.Annotations = map[string]string{}
+			}
+			m.Annotations[k] = v
+		case 17:
+			m.KubeVersion = f.string()
+		}
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return m, nil
+}
+
+// decodeMaintainer decodes a hapi.chart.Maintainer message.
+func decodeMaintainer(b []byte) (*chart.Maintainer, error) {
+	fields, err := parseFields(b)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	m := &chart.Maintainer{}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			m.Name = f.string()
+		case 2:
+			m.Email = f.string()
+		case 3:
+			m.URL = f.string()
+		}
+	}
+
+	return m, nil
+}
+
+// decodeMapEntry decodes an entry of a map<string, string> field.
+func decodeMapEntry(b []byte) (string, string, error) {
+	fields, err := parseFields(b)
+	if err != nil {
+		return "", "", microerror.Mask(err)
+	}
+
+	var k, v string
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			k = f.string()
+		case 2:
+			v = f.string()
+		}
+	}
+
+	return k, v, nil
+}
+
+// decodeFile decodes a hapi.chart.Template message or a
+// google.protobuf.Any message holding a chart file. Both keep the file name
+// in field 1 and its content in field 2.
+func decodeFile(b []byte) (*chart.File, error) {
+	fields, err := parseFields(b)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	file := &chart.File{}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			file.Name = f.string()
+		case 2:
+			file.Data = f.bytes
+		}
+	}
+
+	return file, nil
+}
+
+// decodeHook decodes a hapi.release.Hook message.
+func decodeHook(b []byte) (*release.Hook, error) {
+	fields, err := parseFields(b)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	hook := &release.Hook{}
+
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			hook.Name = f.string()
+		case 2:
+			hook.Kind = f.string()
+		case 3:
+			hook.Path = f.string()
+		case 4:
+			hook.Manifest = f.string()
+		case 5:
+			var events []uint64
+			events, err = varints(f)
+			for _, e := range events {
+				if event, ok := hookEvents[e]; ok {
+					hook.Events = append(hook.Events, event)
+				}
+			}
+		case 6:
+			var lastRun helmtime.Time
+			lastRun, err = decodeTimestamp(f.bytes)
+			hook.LastRun = release.HookExecution{
+				StartedAt:   lastRun,
+				CompletedAt: lastRun,
+				Phase:       release.HookPhaseSucceeded,
+			}
+		case 7:
+			hook.Weight = int(int32(f.varint))
+		case 8:
+			var policies []uint64
+			policies, err = varints(f)
+			for _, p := range policies {
+				if policy, ok := hookDeletePolicies[p]; ok {
+					hook.DeletePolicies = append(hook.DeletePolicies, policy)
+				}
+			}
+		}
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return hook, nil
+}
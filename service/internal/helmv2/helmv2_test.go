@@ -0,0 +1,357 @@
+package helmv2
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// message encodes the fields of a protobuf message. Values are strings,
+// nested messages as []byte, varints as uint64 or packed varints as
+// []uint64.
+func message(fields ...interface{}) []byte {
+	var b []byte
+
+	for i := 0; i < len(fields); i += 2 {
+		num := protowire.Number(fields[i].(int))
+
+		switch v := fields[i+1].(type) {
+		case string:
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			b = protowire.AppendString(b, v)
+		case []byte:
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			b = protowire.AppendBytes(b, v)
+		case uint64:
+			b = protowire.AppendTag(b, num, protowire.VarintType)
+			b = protowire.AppendVarint(b, v)
+		case []uint64:
+			var packed []byte
+			for _, p := range v {
+				packed = protowire.AppendVarint(packed, p)
+			}
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			b = protowire.AppendBytes(b, packed)
+		}
+	}
+
+	return b
+}
+
+func newRelease(status uint64) []byte {
+	return message(
+		1, "hello-world",
+		2, message(
+			1, message(1, status, 4, "Thank you for installing."),
+			3, message(1, uint64(1704164645)),
+			5, "Upgrade complete",
+		),
+		3, message(
+			1, message(
+				1, "hello-world-app",
+				4, "1.0.0",
+				7, message(1, "Giant Swarm", 2, "info@giantswarm.io"),
+				8, "gotpl",
+				13, "0.2.0",
+				16, message(1, "team", 2, "honeybadger"),
+			),
+			2, message(1, "templates/deployment.yaml", 2, []byte("kind: Deployment")),
+			3, message(
+				1, message(1, "redis", 4, "2.0.0"),
+			),
+			4, message(1, "replicas: 1\n"),
+			5, message(1, "requirements.yaml", 2, []byte("dependencies:\n- name: redis\n  version: 2.0.0\n")),
+		),
+		4, message(1, "replicas: 2\n"),
+		5, "kind: Deployment",
+		6, message(
+			1, "hello-world-test",
+			2, "Pod",
+			5, []uint64{9, 11},
+			7, uint64(5),
+			8, []uint64{2},
+		),
+		7, uint64(3),
+		8, "default",
+	)
+}
+
+func encode(t *testing.T, b []byte, compress bool) string {
+	if compress {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write(b)
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		err = w.Close()
+		if err != nil {
+			t.Fatalf("error == %#v, want nil", err)
+		}
+		b = buf.Bytes()
+	}
+
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func Test_ConvertConfigMap(t *testing.T) {
+	testCases := []struct {
+		name           string
+		data           map[string]string
+		expectedStatus release.Status
+		errorMatcher   func(error) bool
+	}{
+		{
+			name: "case 0: gzip compressed deployed release",
+			data: map[string]string{
+				"release": encode(t, newRelease(1), true),
+			},
+			expectedStatus: release.StatusDeployed,
+		},
+		{
+			name: "case 1: uncompressed superseded release",
+			data: map[string]string{
+				"release": encode(t, newRelease(3), false),
+			},
+			expectedStatus: release.StatusSuperseded,
+		},
+		{
+			name:         "case 2: missing release",
+			data:         map[string]string{},
+			errorMatcher: IsInvalidRelease,
+		},
+		{
+			name: "case 3: release is not base64 encoded",
+			data: map[string]string{
+				"release": "not base64!",
+			},
+			errorMatcher: IsInvalidRelease,
+		},
+		{
+			name: "case 4: unknown status code",
+			data: map[string]string{
+				"release": encode(t, newRelease(42), true),
+			},
+			errorMatcher: IsInvalidRelease,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			cm := corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "hello-world.v3",
+				},
+				Data: tc.data,
+			}
+
+			rel, err := ConvertConfigMap(cm)
+			switch {
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case err != nil && !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+
+			if rel.Name != "hello-world" || rel.Namespace != "default" || rel.Version != 3 {
+				t.Fatalf("release == %s/%s v%d, want default/hello-world v3", rel.Namespace, rel.Name, rel.Version)
+			}
+			if rel.Info.Status != tc.expectedStatus {
+				t.Fatalf("status == %#q, want %#q", rel.Info.Status, tc.expectedStatus)
+			}
+			if rel.Info.LastDeployed.Unix() != 1704164645 {
+				t.Fatalf("last deployed == %v, want 1704164645", rel.Info.LastDeployed.Unix())
+			}
+			if rel.Info.Notes != "Thank you for installing." || rel.Info.Description != "Upgrade complete" {
+				t.Fatalf("info == %#v, want notes and description", rel.Info)
+			}
+			if !reflect.DeepEqual(rel.Config, map[string]interface{}{"replicas": float64(2)}) {
+				t.Fatalf("config == %#v, want replicas 2", rel.Config)
+			}
+
+			c := rel.Chart
+			if c.Metadata.Name != "hello-world-app" || c.Metadata.Version != "1.0.0" || c.Metadata.AppVersion != "0.2.0" {
+				t.Fatalf("metadata == %#v, want hello-world-app 1.0.0", c.Metadata)
+			}
+			if c.Metadata.APIVersion != "v1" {
+				t.Fatalf("api version == %#q, want %#q", c.Metadata.APIVersion, "v1")
+			}
+			if c.Metadata.Annotations["team"] != "honeybadger" {
+				t.Fatalf("annotations == %v, want team", c.Metadata.Annotations)
+			}
+			if len(c.Metadata.Maintainers) != 1 || c.Metadata.Maintainers[0].Email != "info@giantswarm.io" {
+				t.Fatalf("maintainers == %v, want 1", c.Metadata.Maintainers)
+			}
+			if len(c.Metadata.Dependencies) != 1 || c.Metadata.Dependencies[0].Name != "redis" {
+				t.Fatalf("dependencies == %v, want redis", c.Metadata.Dependencies)
+			}
+			if len(c.Dependencies()) != 1 || c.Dependencies()[0].Name() != "redis" {
+				t.Fatalf("subcharts == %v, want redis", c.Dependencies())
+			}
+			if len(c.Templates) != 1 || string(c.Templates[0].Data) != "kind: Deployment" {
+				t.Fatalf("templates == %v, want 1", c.Templates)
+			}
+			if !reflect.DeepEqual(c.Values, map[string]interface{}{"replicas": float64(1)}) {
+				t.Fatalf("values == %#v, want replicas 1", c.Values)
+			}
+			err = c.Validate()
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			expectedHook := &release.Hook{
+				Name:           "hello-world-test",
+				Kind:           "Pod",
+				Events:         []release.HookEvent{release.HookTest},
+				Weight:         5,
+				DeletePolicies: []release.HookDeletePolicy{release.HookBeforeHookCreation},
+			}
+			if len(rel.Hooks) != 1 || !reflect.DeepEqual(rel.Hooks[0], expectedHook) {
+				t.Fatalf("hooks == %#v, want %#v", rel.Hooks, expectedHook)
+			}
+		})
+	}
+}
+
+// Test_ConvertConfigMap_tillerFixture converts a release config map in the
+// format Tiller stores it in kube-system. The release is gzip compressed
+// with the best compression and base64 encoded. Its protobuf message was
+// encoded from the hapi release, chart and hook schemas of Helm 2, not by
+// the message helper of this test.
+func Test_ConvertConfigMap_tillerFixture(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "hello-world.v2.yaml"))
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	var cm corev1.ConfigMap
+	err = yaml.Unmarshal(b, &cm)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	expectedManifest, err := os.ReadFile(filepath.Join("testdata", "hello-world.v2.manifest.yaml"))
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	version, err := Version(cm)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+	if version != 2 {
+		t.Fatalf("version label == %d, want %d", version, 2)
+	}
+
+	rel, err := ConvertConfigMap(cm)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	if rel.Name != "hello-world" || rel.Namespace != "default" || rel.Version != 2 {
+		t.Fatalf("release == %s/%s v%d, want default/hello-world v2", rel.Namespace, rel.Name, rel.Version)
+	}
+	if rel.Manifest != string(expectedManifest) {
+		t.Fatalf("manifest == %q, want %q", rel.Manifest, expectedManifest)
+	}
+	if !reflect.DeepEqual(rel.Config, map[string]interface{}{"replicaCount": float64(2)}) {
+		t.Fatalf("config == %#v, want replicaCount 2", rel.Config)
+	}
+
+	if rel.Info.Status != release.StatusDeployed {
+		t.Fatalf("status == %#q, want %#q", rel.Info.Status, release.StatusDeployed)
+	}
+	if rel.Info.Description != "Upgrade complete" || rel.Info.Notes != "Thank you for installing hello-world-app.\n" {
+		t.Fatalf("info == %#v, want description and notes", rel.Info)
+	}
+	if rel.Info.FirstDeployed.UnixNano() != 1583143020412835101 {
+		t.Fatalf("first deployed == %d, want %d", rel.Info.FirstDeployed.UnixNano(), int64(1583143020412835101))
+	}
+	if rel.Info.LastDeployed.UnixNano() != 1583747124098234771 {
+		t.Fatalf("last deployed == %d, want %d", rel.Info.LastDeployed.UnixNano(), int64(1583747124098234771))
+	}
+
+	expectedMetadata := &chart.Metadata{
+		Name:        "hello-world-app",
+		Home:        "https://github.com/giantswarm/hello-world-app",
+		Sources:     []string{"https://github.com/giantswarm/hello-world-app"},
+		Version:     "0.2.1",
+		Description: "A Helm chart for the hello world app",
+		Keywords:    []string{"hello-world"},
+		Maintainers: []*chart.Maintainer{
+			{
+				Name:  "giantswarm",
+				Email: "info@giantswarm.io",
+			},
+		},
+		Icon:       "https://s.giantswarm.io/app-icons/1/png/default-app-light.png",
+		APIVersion: "v1",
+		AppVersion: "0.2.0",
+		Annotations: map[string]string{
+			"application.giantswarm.io/team": "batman",
+		},
+	}
+	if !reflect.DeepEqual(rel.Chart.Metadata, expectedMetadata) {
+		t.Fatalf("metadata == %#v, want %#v", rel.Chart.Metadata, expectedMetadata)
+	}
+
+	var templates []string
+	for _, template := range rel.Chart.Templates {
+		templates = append(templates, template.Name)
+	}
+	expectedTemplates := []string{"templates/deployment.yaml", "templates/service.yaml", "templates/NOTES.txt"}
+	if !reflect.DeepEqual(templates, expectedTemplates) {
+		t.Fatalf("templates == %v, want %v", templates, expectedTemplates)
+	}
+
+	var files []string
+	for _, file := range rel.Chart.Files {
+		files = append(files, file.Name)
+	}
+	expectedFiles := []string{".helmignore", "README.md"}
+	if !reflect.DeepEqual(files, expectedFiles) {
+		t.Fatalf("files == %v, want %v", files, expectedFiles)
+	}
+
+	if rel.Chart.Values["replicaCount"] != float64(1) {
+		t.Fatalf("values == %#v, want replicaCount 1", rel.Chart.Values)
+	}
+	err = rel.Chart.Validate()
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	if len(rel.Hooks) != 1 {
+		t.Fatalf("hooks == %d, want %d", len(rel.Hooks), 1)
+	}
+	hook := rel.Hooks[0]
+	if hook.Name != "hello-world-test-connection" || hook.Kind != "Pod" || hook.Path != "hello-world-app/templates/tests/test-connection.yaml" {
+		t.Fatalf("hook == %#v, want test connection pod", hook)
+	}
+	if !reflect.DeepEqual(hook.Events, []release.HookEvent{release.HookTest}) {
+		t.Fatalf("hook events == %v, want %v", hook.Events, []release.HookEvent{release.HookTest})
+	}
+	if !reflect.DeepEqual(hook.DeletePolicies, []release.HookDeletePolicy{release.HookBeforeHookCreation}) {
+		t.Fatalf("hook delete policies == %v, want %v", hook.DeletePolicies, []release.HookDeletePolicy{release.HookBeforeHookCreation})
+	}
+}
@@ -0,0 +1,277 @@
+// Package imagerewrite rewrites the container images of rendered manifests
+// to a registry mirror, e.g. so air-gapped clusters can run upstream charts
+// unchanged.
+package imagerewrite
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// dockerHubRegistry is the registry of images without registry.
+	dockerHubRegistry = "docker.io"
+)
+
+// containerFields are the fields holding lists of containers in pod specs
+// and pod spec like fields of custom resources.
+var containerFields = []string{
+	"containers",
+	"ephemeralContainers",
+	"initContainers",
+}
+
+type Config struct {
+	// Registry is the registry mirror images are rewritten to, optionally
+	// with a path prefix, e.g. registry.example.com/mirror.
+	Registry string
+	// SourceRegistries are the registries whose images are rewritten, e.g.
+	// docker.io or quay.io.
+	SourceRegistries []string
+}
+
+// Rewriter rewrites images of the source registries to the registry mirror.
+// It is a Helm post-renderer.
+type Rewriter struct {
+	registry         string
+	sourceRegistries map[string]bool
+}
+
+func New(config Config) (*Rewriter, error) {
+	if config.Registry == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Registry must not be empty", config)
+	}
+	if len(config.SourceRegistries) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.SourceRegistries must not be empty", config)
+	}
+
+	sourceRegistries := map[string]bool{}
+	for _, s := range config.SourceRegistries {
+		if s == "" {
+			return nil, microerror.Maskf(invalidConfigError, "%T.SourceRegistries must not contain empty registries", config)
+		}
+
+		sourceRegistries[normalizeRegistry(s)] = true
+	}
+
+	r := &Rewriter{
+		registry:         strings.TrimSuffix(config.Registry, "/"),
+		sourceRegistries: sourceRegistries,
+	}
+
+	return r, nil
+}
+
+// Rewrite returns the image rewritten to the registry mirror when it is
+// pulled from one of the source registries. Other images are returned
+// unchanged. Images of Docker Hub are rewritten with their full path, e.g.
+// nginx:1.25 to <registry>/library/nginx:1.25.
+func (r *Rewriter) Rewrite(image string) string {
+	registry, path := splitImage(image)
+	if !r.sourceRegistries[registry] {
+		return image
+	}
+
+	return fmt.Sprintf("%s/%s", r.registry, path)
+}
+
+// RewriteManifest rewrites the images of all containers in the given
+// manifest. Documents without rewritten images are kept as they are.
+func (r *Rewriter) RewriteManifest(manifest string) (string, error) {
+	split := releaseutil.SplitManifests(manifest)
+
+	keys := make([]string, 0, len(split))
+	for k := range split {
+		keys = append(keys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	docs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		var object map[string]interface{}
+
+		err := yaml.Unmarshal([]byte(split[k]), &object)
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		if !r.rewriteContainers(object) {
+			docs = append(docs, split[k])
+			continue
+		}
+
+		b, err := yaml.Marshal(object)
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		docs = append(docs, string(b))
+	}
+
+	if len(docs) == 0 {
+		return "", nil
+	}
+
+	return fmt.Sprintf("---\n%s\n", strings.Join(docs, "\n---\n")), nil
+}
+
+// Applies returns true when the given manifest has containers with images
+// of the source registries or of the registry mirror, i.e. images the
+// rewriter rewrites or has already rewritten.
+func (r *Rewriter) Applies(manifest string) (bool, error) {
+	for _, doc := range releaseutil.SplitManifests(manifest) {
+		var object map[string]interface{}
+
+		err := yaml.Unmarshal([]byte(doc), &object)
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+
+		for _, image := range containerImages(object) {
+			if r.Rewrite(image) != image || strings.HasPrefix(image, r.registry+"/") {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// Run implements the Helm post-renderer interface.
+func (r *Rewriter) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	modified, err := r.RewriteManifest(renderedManifests.String())
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return bytes.NewBufferString(modified), nil
+}
+
+// String returns the configuration of the rewriter, e.g. to detect
+// configuration changes.
+func (r *Rewriter) String() string {
+	sourceRegistries := make([]string, 0, len(r.sourceRegistries))
+	for s := range r.sourceRegistries {
+		sourceRegistries = append(sourceRegistries, s)
+	}
+	sort.Strings(sourceRegistries)
+
+	return fmt.Sprintf("%s=%s", strings.Join(sourceRegistries, ","), r.registry)
+}
+
+// rewriteContainers rewrites the images of all containers nested in the
+// given value and returns true when an image was rewritten.
+func (r *Rewriter) rewriteContainers(v interface{}) bool {
+	rewritten := false
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for _, f := range containerFields {
+			containers, ok := val[f].([]interface{})
+			if !ok {
+				continue
+			}
+
+			for _, c := range containers {
+				container, ok := c.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				image, ok := container["image"].(string)
+				if !ok {
+					continue
+				}
+
+				if rewrittenImage := r.Rewrite(image); rewrittenImage != image {
+					container["image"] = rewrittenImage
+					rewritten = true
+				}
+			}
+		}
+
+		for _, e := range val {
+			if r.rewriteContainers(e) {
+				rewritten = true
+			}
+		}
+	case []interface{}:
+		for _, e := range val {
+			if r.rewriteContainers(e) {
+				rewritten = true
+			}
+		}
+	}
+
+	return rewritten
+}
+
+// containerImages returns the images of all containers nested in the given
+// value.
+func containerImages(v interface{}) []string {
+	var images []string
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for _, f := range containerFields {
+			containers, ok := val[f].([]interface{})
+			if !ok {
+				continue
+			}
+
+			for _, c := range containers {
+				container, ok := c.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				image, ok := container["image"].(string)
+				if !ok {
+					continue
+				}
+
+				images = append(images, image)
+			}
+		}
+
+		for _, e := range val {
+			images = append(images, containerImages(e)...)
+		}
+	case []interface{}:
+		for _, e := range val {
+			images = append(images, containerImages(e)...)
+		}
+	}
+
+	return images
+}
+
+// splitImage returns the normalized registry of the image and the path of
+// the image in the registry including its tag or digest.
+func splitImage(image string) (string, string) {
+	registry, path, ok := strings.Cut(image, "/")
+	if !ok || (!strings.ContainsAny(registry, ".:") && registry != "localhost") {
+		registry = dockerHubRegistry
+		path = image
+	}
+
+	registry = normalizeRegistry(registry)
+	if registry == dockerHubRegistry && !strings.Contains(path, "/") {
+		path = fmt.Sprintf("library/%s", path)
+	}
+
+	return registry, path
+}
+
+func normalizeRegistry(registry string) string {
+	switch registry {
+	case "index.docker.io", "registry-1.docker.io":
+		return dockerHubRegistry
+	}
+
+	return registry
+}
@@ -0,0 +1,249 @@
+package imagerewrite
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func Test_New(t *testing.T) {
+	testCases := []struct {
+		name         string
+		config       Config
+		errorMatcher func(error) bool
+	}{
+		{
+			name: "case 0: valid config",
+			config: Config{
+				Registry:         "registry.example.com/mirror",
+				SourceRegistries: []string{"docker.io", "quay.io"},
+			},
+		},
+		{
+			name: "case 1: missing registry",
+			config: Config{
+				SourceRegistries: []string{"docker.io"},
+			},
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 2: missing source registries",
+			config: Config{
+				Registry: "registry.example.com",
+			},
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 3: empty source registry",
+			config: Config{
+				Registry:         "registry.example.com",
+				SourceRegistries: []string{""},
+			},
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			_, err := New(tc.config)
+			switch {
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case err != nil && !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
+
+func Test_Rewrite(t *testing.T) {
+	r, err := New(Config{
+		Registry:         "registry.example.com/mirror/",
+		SourceRegistries: []string{"index.docker.io", "quay.io", "localhost:5000"},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	testCases := []struct {
+		name          string
+		image         string
+		expectedImage string
+	}{
+		{
+			name:          "case 0: official Docker Hub image",
+			image:         "nginx:1.25",
+			expectedImage: "registry.example.com/mirror/library/nginx:1.25",
+		},
+		{
+			name:          "case 1: Docker Hub image with organization",
+			image:         "bitnami/redis:7.2",
+			expectedImage: "registry.example.com/mirror/bitnami/redis:7.2",
+		},
+		{
+			name:          "case 2: Docker Hub image with registry",
+			image:         "docker.io/nginx@sha256:abc",
+			expectedImage: "registry.example.com/mirror/library/nginx@sha256:abc",
+		},
+		{
+			name:          "case 3: allowed registry",
+			image:         "quay.io/prometheus/prometheus:v2.50.0",
+			expectedImage: "registry.example.com/mirror/prometheus/prometheus:v2.50.0",
+		},
+		{
+			name:          "case 4: allowed registry with port",
+			image:         "localhost:5000/hello-world",
+			expectedImage: "registry.example.com/mirror/hello-world",
+		},
+		{
+			name:          "case 5: registry not allowed",
+			image:         "gsoci.azurecr.io/giantswarm/chart-operator:4.0.0",
+			expectedImage: "gsoci.azurecr.io/giantswarm/chart-operator:4.0.0",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			image := r.Rewrite(tc.image)
+			if image != tc.expectedImage {
+				t.Fatalf("image == %#q, want %#q", image, tc.expectedImage)
+			}
+		})
+	}
+}
+
+func Test_RewriteManifest(t *testing.T) {
+	r, err := New(Config{
+		Registry:         "registry.example.com",
+		SourceRegistries: []string{"docker.io"},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	manifest := `---
+# Source: hello-world/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: hello-world
+data:
+  image: nginx:1.25
+---
+# Source: hello-world/templates/cronjob.yaml
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: hello-world
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          initContainers:
+          - name: init
+            image: busybox
+          containers:
+          - name: hello-world
+            image: gsoci.azurecr.io/giantswarm/hello-world:1.0.0
+`
+
+	modified, err := r.RewriteManifest(manifest)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	for _, s := range []string{
+		"# Source: hello-world/templates/configmap.yaml",
+		"image: nginx:1.25",
+		"image: registry.example.com/library/busybox",
+		"image: gsoci.azurecr.io/giantswarm/hello-world:1.0.0",
+	} {
+		if !strings.Contains(modified, s) {
+			t.Fatalf("modified manifest does not contain %#q\n%s", s, modified)
+		}
+	}
+}
+
+func Test_Applies(t *testing.T) {
+	r, err := New(Config{
+		Registry:         "registry.example.com",
+		SourceRegistries: []string{"docker.io"},
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	newManifest := func(image string) string {
+		return `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: hello-world
+data:
+  image: nginx:1.25
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: hello-world
+spec:
+  template:
+    spec:
+      containers:
+      - name: hello-world
+        image: ` + image + "\n"
+	}
+
+	testCases := []struct {
+		name            string
+		manifest        string
+		expectedApplies bool
+	}{
+		{
+			name:            "case 0: image of source registry",
+			manifest:        newManifest("busybox"),
+			expectedApplies: true,
+		},
+		{
+			name:            "case 1: image of registry mirror",
+			manifest:        newManifest("registry.example.com/library/busybox"),
+			expectedApplies: true,
+		},
+		{
+			name:     "case 2: image of other registry",
+			manifest: newManifest("gsoci.azurecr.io/giantswarm/hello-world:1.0.0"),
+		},
+		{
+			name: "case 3: no containers",
+			manifest: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: hello-world
+data:
+  image: nginx:1.25
+`,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			applies, err := r.Applies(tc.manifest)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if applies != tc.expectedApplies {
+				t.Fatalf("applies == %t, want %t", applies, tc.expectedApplies)
+			}
+		})
+	}
+}
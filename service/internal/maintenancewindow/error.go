@@ -0,0 +1,21 @@
+package maintenancewindow
+
+import "github.com/giantswarm/microerror"
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var invalidScheduleError = &microerror.Error{
+	Kind: "invalidScheduleError",
+}
+
+// IsInvalidSchedule asserts invalidScheduleError.
+func IsInvalidSchedule(err error) bool {
+	return microerror.Cause(err) == invalidScheduleError
+}
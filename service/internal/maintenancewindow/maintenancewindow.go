@@ -0,0 +1,232 @@
+// Package maintenancewindow implements cron style maintenance windows used to
+// defer upgrades of releases to the times changes are allowed.
+package maintenancewindow
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+// Window is open for its duration starting at every time matched by its
+// schedule. Times are evaluated in UTC. The zero value is always open.
+type Window struct {
+	schedule *schedule
+	duration time.Duration
+}
+
+// New parses the schedule in the standard five field cron format, i.e.
+// minute, hour, day of month, month and day of week, e.g. `0 22 * * 1-5` for
+// 22:00 UTC from Monday to Friday. Fields support `*`, lists, ranges and
+// steps.
+func New(schedule string, duration time.Duration) (Window, error) {
+	if duration <= 0 {
+		return Window{}, microerror.Maskf(invalidConfigError, "duration must be positive")
+	}
+
+	s, err := parseSchedule(schedule)
+	if err != nil {
+		return Window{}, microerror.Mask(err)
+	}
+
+	w := Window{
+		schedule: s,
+		duration: duration,
+	}
+
+	return w, nil
+}
+
+// IsZero returns true when the window has no schedule and is always open.
+func (w Window) IsZero() bool {
+	return w.schedule == nil
+}
+
+// Contains returns true when the window is open at the given time.
+func (w Window) Contains(t time.Time) bool {
+	if w.IsZero() {
+		return true
+	}
+
+	// The window is open when it started within its duration before t.
+	start := w.schedule.next(t.Add(-w.duration).Add(time.Nanosecond))
+
+	return !start.After(t)
+}
+
+// Next returns the time the window opens next at or after the given time.
+// The zero time is returned for windows which are always open.
+func (w Window) Next(t time.Time) time.Time {
+	if w.IsZero() {
+		return time.Time{}
+	}
+
+	return w.schedule.next(t)
+}
+
+type bounds struct {
+	min int
+	max int
+}
+
+var (
+	minuteBounds = bounds{0, 59}
+	hourBounds   = bounds{0, 23}
+	domBounds    = bounds{1, 31}
+	monthBounds  = bounds{1, 12}
+	// Day of week 7 is accepted as Sunday like in most cron implementations.
+	dowBounds = bounds{0, 7}
+)
+
+type schedule struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	// domAny and dowAny are true when the day of month or day of week field
+	// is `*`. When both are restricted, days matching either field match,
+	// as in cron.
+	domAny bool
+	dowAny bool
+}
+
+func parseSchedule(s string) (*schedule, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return nil, microerror.Maskf(invalidScheduleError, "schedule %#q must have 5 fields, got %d", s, len(fields))
+	}
+
+	var err error
+	var sched schedule
+
+	sched.minute, err = parseField(fields[0], minuteBounds)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	sched.hour, err = parseField(fields[1], hourBounds)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	sched.dom, err = parseField(fields[2], domBounds)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	sched.month, err = parseField(fields[3], monthBounds)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	sched.dow, err = parseField(fields[4], dowBounds)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if sched.dow&(1<<7) != 0 {
+		sched.dow |= 1
+	}
+
+	sched.domAny = strings.HasPrefix(fields[2], "*")
+	sched.dowAny = strings.HasPrefix(fields[4], "*")
+
+	return &sched, nil
+}
+
+// parseField returns the values matched by the field as bits.
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+
+	for _, expr := range strings.Split(field, ",") {
+		rangeExpr, stepExpr, hasStep := strings.Cut(expr, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepExpr)
+			if err != nil || step <= 0 {
+				return 0, microerror.Maskf(invalidScheduleError, "step %#q of %#q must be a positive number", stepExpr, field)
+			}
+		}
+
+		start, end := b.min, b.max
+		if rangeExpr != "*" {
+			low, high, isRange := strings.Cut(rangeExpr, "-")
+
+			var err error
+			start, err = strconv.Atoi(low)
+			if err != nil {
+				return 0, microerror.Maskf(invalidScheduleError, "%#q of %#q must be a number", low, field)
+			}
+
+			end = start
+			if isRange {
+				end, err = strconv.Atoi(high)
+				if err != nil {
+					return 0, microerror.Maskf(invalidScheduleError, "%#q of %#q must be a number", high, field)
+				}
+			} else if hasStep {
+				// `a/n` matches every nth value starting at a.
+				end = b.max
+			}
+		}
+
+		if start < b.min || end > b.max || start > end {
+			return 0, microerror.Maskf(invalidScheduleError, "%#q must be within %d-%d", expr, b.min, b.max)
+		}
+
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	return bits, nil
+}
+
+// next returns the first time matched by the schedule at or after t,
+// rounded up to the minute.
+func (s *schedule) next(t time.Time) time.Time {
+	t = t.UTC()
+	if !t.Equal(t.Truncate(time.Minute)) {
+		t = t.Truncate(time.Minute).Add(time.Minute)
+	}
+
+	// Schedules like `0 0 30 2 *` never match. Stop searching after five
+	// years which covers all leap year combinations.
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return limit
+}
+
+func (s *schedule) matchesDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+
+	return dom || dow
+}
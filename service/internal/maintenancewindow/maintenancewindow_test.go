@@ -0,0 +1,158 @@
+package maintenancewindow
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_New(t *testing.T) {
+	testCases := []struct {
+		name         string
+		schedule     string
+		duration     time.Duration
+		errorMatcher func(error) bool
+	}{
+		{
+			name:     "case 0: valid schedule",
+			schedule: "0 22 * * 1-5",
+			duration: 4 * time.Hour,
+		},
+		{
+			name:     "case 1: lists, ranges and steps",
+			schedule: "0,30 */2 1-15/7 1,6-8 7",
+			duration: time.Hour,
+		},
+		{
+			name:         "case 2: missing field",
+			schedule:     "0 22 * *",
+			duration:     time.Hour,
+			errorMatcher: IsInvalidSchedule,
+		},
+		{
+			name:         "case 3: value out of range",
+			schedule:     "0 24 * * *",
+			duration:     time.Hour,
+			errorMatcher: IsInvalidSchedule,
+		},
+		{
+			name:         "case 4: invalid step",
+			schedule:     "*/0 * * * *",
+			duration:     time.Hour,
+			errorMatcher: IsInvalidSchedule,
+		},
+		{
+			name:         "case 5: no duration",
+			schedule:     "0 22 * * *",
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			_, err := New(tc.schedule, tc.duration)
+			switch {
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case err != nil && !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
+
+func Test_Window(t *testing.T) {
+	testCases := []struct {
+		name             string
+		schedule         string
+		duration         time.Duration
+		now              time.Time
+		expectedContains bool
+		expectedNext     time.Time
+	}{
+		{
+			name:             "case 0: before nightly window",
+			schedule:         "0 22 * * *",
+			duration:         4 * time.Hour,
+			now:              time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC),
+			expectedContains: false,
+			expectedNext:     time.Date(2026, 3, 10, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			name:             "case 1: within nightly window after midnight",
+			schedule:         "0 22 * * *",
+			duration:         4 * time.Hour,
+			now:              time.Date(2026, 3, 11, 1, 59, 0, 0, time.UTC),
+			expectedContains: true,
+			expectedNext:     time.Date(2026, 3, 11, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			name:             "case 2: window closed at its end",
+			schedule:         "0 22 * * *",
+			duration:         4 * time.Hour,
+			now:              time.Date(2026, 3, 11, 2, 0, 0, 0, time.UTC),
+			expectedContains: false,
+			expectedNext:     time.Date(2026, 3, 11, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			name:             "case 3: weekdays only on saturday",
+			schedule:         "0 22 * * 1-5",
+			duration:         time.Hour,
+			now:              time.Date(2026, 3, 14, 22, 30, 0, 0, time.UTC),
+			expectedContains: false,
+			expectedNext:     time.Date(2026, 3, 16, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			name:             "case 4: day of month or day of week",
+			schedule:         "0 3 1 * 0",
+			duration:         time.Hour,
+			now:              time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+			expectedContains: false,
+			expectedNext:     time.Date(2026, 3, 8, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			name:             "case 5: next window in the next year",
+			schedule:         "30 4 29 2 *",
+			duration:         time.Hour,
+			now:              time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			expectedContains: false,
+			expectedNext:     time.Date(2028, 2, 29, 4, 30, 0, 0, time.UTC),
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			w, err := New(tc.schedule, tc.duration)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			contains := w.Contains(tc.now)
+			if contains != tc.expectedContains {
+				t.Fatalf("contains == %t, want %t", contains, tc.expectedContains)
+			}
+
+			next := w.Next(tc.now)
+			if !next.Equal(tc.expectedNext) {
+				t.Fatalf("next == %s, want %s", next, tc.expectedNext)
+			}
+		})
+	}
+}
+
+func Test_Window_IsZero(t *testing.T) {
+	var w Window
+
+	if !w.Contains(time.Now()) {
+		t.Fatalf("contains == false, want true")
+	}
+	if !w.Next(time.Now()).IsZero() {
+		t.Fatalf("next == %s, want zero", w.Next(time.Now()))
+	}
+}
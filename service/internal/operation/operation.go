@@ -0,0 +1,112 @@
+// Package operation implements an in-process registry of the Helm operations
+// running for chart CRs. Installs and upgrades continue in the background
+// when they take longer than the reconciliation wait timeout, so the
+// registry lets the next reconciliation loop know whether an operation is
+// still running and how it finished.
+package operation
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	Install = "install"
+	Upgrade = "upgrade"
+)
+
+// Operation is a Helm install or upgrade of the release of a chart CR.
+type Operation struct {
+	// Kind is either install or upgrade.
+	Kind string
+	// Release is the name of the Helm release.
+	Release string
+	// Version is the chart version being installed or upgraded to.
+	Version string
+	// ValuesChecksum is the checksum of the values being installed or
+	// upgraded to.
+	ValuesChecksum string
+
+	StartedAt  time.Time
+	FinishedAt time.Time
+	// Err is the error the operation finished with.
+	Err error
+}
+
+// Running returns true when the operation has not finished yet.
+func (o Operation) Running() bool {
+	return o.FinishedAt.IsZero()
+}
+
+// Registry tracks the last operation of each chart CR by its UID.
+type Registry struct {
+	mutex      sync.Mutex
+	operations map[types.UID]Operation
+	now        func() time.Time
+}
+
+func New() *Registry {
+	r := &Registry{
+		operations: map[types.UID]Operation{},
+		now:        time.Now,
+	}
+
+	return r
+}
+
+// Start records the operation as running for the chart CR. When an
+// operation is already running for the chart CR it is returned together
+// with false and the new operation must not be started.
+func (r *Registry) Start(uid types.UID, o Operation) (Operation, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	current, ok := r.operations[uid]
+	if ok && current.Running() {
+		return current, false
+	}
+
+	o.StartedAt = r.now()
+	o.FinishedAt = time.Time{}
+	o.Err = nil
+	r.operations[uid] = o
+
+	return o, true
+}
+
+// Finish records the result of the running operation of the chart CR. It is
+// a no-op when the chart CR was deleted in the meantime.
+func (r *Registry) Finish(uid types.UID, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	o, ok := r.operations[uid]
+	if !ok || !o.Running() {
+		return
+	}
+
+	o.FinishedAt = r.now()
+	o.Err = err
+	r.operations[uid] = o
+}
+
+// Get returns the last operation of the chart CR, which may still be
+// running.
+func (r *Registry) Get(uid types.UID) (Operation, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	o, ok := r.operations[uid]
+
+	return o, ok
+}
+
+// Delete removes the operations of the deleted chart CR.
+func (r *Registry) Delete(uid types.UID) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.operations, uid)
+}
@@ -0,0 +1,75 @@
+package operation
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func Test_Registry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	r := New()
+	r.now = func() time.Time { return now }
+
+	uid := types.UID("b6f4e8a2-5b0c-4c1e-9d4f-3f0e2c6a7d11")
+
+	_, ok := r.Get(uid)
+	if ok {
+		t.Fatalf("Get == true, want false")
+	}
+
+	o, ok := r.Start(uid, Operation{Kind: Install, Release: "hello-world", Version: "1.0.0"})
+	if !ok {
+		t.Fatalf("Start == false, want true")
+	}
+	if !o.StartedAt.Equal(now) {
+		t.Fatalf("StartedAt == %s, want %s", o.StartedAt, now)
+	}
+
+	// A second operation must not be started while the first one runs.
+	running, ok := r.Start(uid, Operation{Kind: Upgrade, Release: "hello-world", Version: "1.1.0"})
+	if ok {
+		t.Fatalf("Start == true, want false")
+	}
+	if running.Kind != Install || running.Version != "1.0.0" {
+		t.Fatalf("running operation == %#v, want install of 1.0.0", running)
+	}
+
+	now = now.Add(time.Minute)
+	r.Finish(uid, errors.New("timed out waiting for the condition"))
+
+	o, ok = r.Get(uid)
+	if !ok {
+		t.Fatalf("Get == false, want true")
+	}
+	if o.Running() {
+		t.Fatalf("Running == true, want false")
+	}
+	if o.Err == nil || o.Err.Error() != "timed out waiting for the condition" {
+		t.Fatalf("error == %#v, want timeout", o.Err)
+	}
+	if !o.FinishedAt.Equal(now) {
+		t.Fatalf("FinishedAt == %s, want %s", o.FinishedAt, now)
+	}
+
+	// Starting a new operation resets the result of the finished one.
+	o, ok = r.Start(uid, Operation{Kind: Upgrade, Release: "hello-world", Version: "1.1.0"})
+	if !ok {
+		t.Fatalf("Start == false, want true")
+	}
+	if o.Err != nil || !o.FinishedAt.IsZero() {
+		t.Fatalf("operation == %#v, want running", o)
+	}
+
+	// Results of chart CRs deleted while the operation runs are dropped.
+	r.Delete(uid)
+	r.Finish(uid, nil)
+
+	_, ok = r.Get(uid)
+	if ok {
+		t.Fatalf("Get == true, want false")
+	}
+}
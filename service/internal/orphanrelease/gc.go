@@ -0,0 +1,161 @@
+package orphanrelease
+
+import (
+	"context"
+	"time"
+
+	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
+	"github.com/giantswarm/k8sclient/v7/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// defaultInterval is the time between two garbage collections when no
+	// interval is configured.
+	defaultInterval = 5 * time.Minute
+)
+
+// Reasons of the Kubernetes events emitted for the release secrets.
+const (
+	orphanReleaseUninstalledEventReason     = "OrphanReleaseUninstalled"
+	orphanReleaseUninstallFailedEventReason = "OrphanReleaseUninstallFailed"
+)
+
+type GCConfig struct {
+	Event      record.EventRecorder
+	HelmClient helmclient.Interface
+	K8sClient  k8sclient.Interface
+	Logger     micrologger.Logger
+
+	// GracePeriod is how long a release must be orphaned before it is
+	// uninstalled.
+	GracePeriod time.Duration
+	// Interval is the time between two garbage collections. It defaults to
+	// 5 minutes.
+	Interval time.Duration
+}
+
+// GC uninstalls Helm releases managed by chart-operator which have been
+// without chart CR for the grace period. Releases are tracked in memory, so
+// the grace period starts again when chart-operator restarts.
+type GC struct {
+	event      record.EventRecorder
+	helmClient helmclient.Interface
+	k8sClient  k8sclient.Interface
+	logger     micrologger.Logger
+
+	gracePeriod time.Duration
+	interval    time.Duration
+
+	orphanedSince map[string]time.Time
+}
+
+func NewGC(config GCConfig) (*GC, error) {
+	if config.Event == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Event must not be empty", config)
+	}
+	if config.HelmClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.HelmClient must not be empty", config)
+	}
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.GracePeriod < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.GracePeriod must not be negative", config)
+	}
+	if config.Interval < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Interval must not be negative", config)
+	}
+	if config.Interval == 0 {
+		config.Interval = defaultInterval
+	}
+
+	g := &GC{
+		event:      config.Event,
+		helmClient: config.HelmClient,
+		k8sClient:  config.K8sClient,
+		logger:     config.Logger,
+
+		gracePeriod: config.GracePeriod,
+		interval:    config.Interval,
+
+		orphanedSince: map[string]time.Time{},
+	}
+
+	return g, nil
+}
+
+// Boot runs the garbage collection every interval until the context is
+// done.
+func (g *GC) Boot(ctx context.Context) {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+
+	for {
+		err := g.Collect(ctx, time.Now())
+		if err != nil {
+			g.logger.Errorf(ctx, err, "garbage collection of orphan releases failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Collect uninstalls the releases which have been orphaned for the grace
+// period at the given time. An event is emitted for the release secret of
+// every uninstalled release.
+func (g *GC) Collect(ctx context.Context, now time.Time) error {
+	orphans, err := Find(ctx, g.k8sClient)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	orphanedSince := map[string]time.Time{}
+
+	for _, o := range orphans {
+		since, ok := g.orphanedSince[o.String()]
+		if !ok {
+			g.logger.Debugf(ctx, "found orphan release %#q, uninstalling it after %s", o.String(), g.gracePeriod)
+			since = now
+		}
+
+		if now.Sub(since) < g.gracePeriod {
+			orphanedSince[o.String()] = since
+			continue
+		}
+
+		g.logger.Debugf(ctx, "uninstalling orphan release %#q", o.String())
+
+		err = g.helmClient.DeleteRelease(ctx, o.Namespace, o.Name, helmclient.DeleteOptions{})
+		if helmclient.IsReleaseNotFound(err) {
+			g.logger.Debugf(ctx, "orphan release %#q already uninstalled", o.String())
+			continue
+		} else if err != nil {
+			g.event.Eventf(&o.Secret, corev1.EventTypeWarning, orphanReleaseUninstallFailedEventReason, "uninstalling release %#q without chart CR failed: %s", o.String(), err.Error())
+			g.logger.Errorf(ctx, err, "uninstalling orphan release %#q failed", o.String())
+
+			orphanedSince[o.String()] = since
+			continue
+		}
+
+		g.event.Eventf(&o.Secret, corev1.EventTypeNormal, orphanReleaseUninstalledEventReason, "uninstalled release %#q without chart CR for %s", o.String(), now.Sub(since).Round(time.Second))
+		g.logger.Debugf(ctx, "uninstalled orphan release %#q", o.String())
+	}
+
+	// Releases which are not orphaned anymore, e.g. because their chart CR
+	// was recreated, are forgotten.
+	g.orphanedSince = orphanedSince
+
+	return nil
+}
@@ -0,0 +1,104 @@
+// Package orphanrelease finds Helm releases installed by chart-operator whose
+// chart CR no longer exists and optionally uninstalls them.
+package orphanrelease
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8sclient/v7/pkg/k8sclient"
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/project"
+	"github.com/giantswarm/chart-operator/v4/service/controller/chart/key"
+)
+
+// Release is a Helm release managed by chart-operator.
+type Release struct {
+	Name      string
+	Namespace string
+	// Secret is the release secret of the latest revision.
+	Secret corev1.Secret
+}
+
+func (r Release) String() string {
+	return fmt.Sprintf("%s/%s", r.Namespace, r.Name)
+}
+
+// ReleaseSecretSelector returns the label selector of the release secrets
+// managed by chart-operator.
+func ReleaseSecretSelector() string {
+	return fmt.Sprintf("owner=helm,%s=%s", label.ManagedBy, project.Name())
+}
+
+// Find returns the Helm releases managed by chart-operator without chart CR,
+// sorted by namespace and name. Releases are managed by chart-operator when
+// their release secrets are labelled by the release secret resource.
+func Find(ctx context.Context, k8sClient k8sclient.Interface) ([]Release, error) {
+	chartList := &v1alpha1.ChartList{}
+	err := k8sClient.CtrlClient().List(
+		ctx,
+		chartList,
+	)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	desiredReleases := make(map[[2]string]bool)
+
+	for _, chart := range chartList.Items {
+		desiredReleases[[2]string{key.Namespace(chart), key.ReleaseName(chart)}] = true
+	}
+
+	lo := metav1.ListOptions{
+		LabelSelector: ReleaseSecretSelector(),
+	}
+	secrets, err := k8sClient.K8sClient().CoreV1().Secrets("").List(ctx, lo)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	latest := map[[2]string]corev1.Secret{}
+
+	for _, s := range secrets.Items {
+		k := [2]string{s.Namespace, s.Labels["name"]}
+		if desiredReleases[k] {
+			continue
+		}
+
+		l, ok := latest[k]
+		if !ok || revision(s) > revision(l) {
+			latest[k] = s
+		}
+	}
+
+	orphans := make([]Release, 0, len(latest))
+	for k, s := range latest {
+		orphans = append(orphans, Release{
+			Name:      k[1],
+			Namespace: k[0],
+			Secret:    s,
+		})
+	}
+
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].String() < orphans[j].String()
+	})
+
+	return orphans, nil
+}
+
+func revision(s corev1.Secret) int {
+	v, err := strconv.Atoi(s.Labels["version"])
+	if err != nil {
+		return 0
+	}
+
+	return v
+}
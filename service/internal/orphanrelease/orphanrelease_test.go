@@ -0,0 +1,175 @@
+package orphanrelease
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/helmclient/v4/pkg/helmclient"
+	"github.com/giantswarm/helmclient/v4/pkg/helmclienttest"
+	"github.com/giantswarm/k8sclient/v7/pkg/k8sclienttest"
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	"github.com/giantswarm/micrologger/microloggertest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/chart-operator/v4/pkg/project"
+)
+
+// deletingHelmClient records the deleted releases.
+type deletingHelmClient struct {
+	helmclient.Interface
+
+	deleted []string
+}
+
+func (c *deletingHelmClient) DeleteRelease(ctx context.Context, namespace, releaseName string, options helmclient.DeleteOptions) error {
+	c.deleted = append(c.deleted, fmt.Sprintf("%s/%s", namespace, releaseName))
+	return nil
+}
+
+func newReleaseSecret(namespace, name string, version int, managed bool) runtime.Object {
+	labels := map[string]string{
+		"name":    name,
+		"owner":   "helm",
+		"version": strconv.Itoa(version),
+	}
+	if managed {
+		labels[label.ManagedBy] = project.Name()
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("sh.helm.release.v1.%s.v%d", name, version),
+			Namespace: namespace,
+			Labels:    labels,
+		},
+	}
+}
+
+func newClients(t *testing.T) *k8sclienttest.Clients {
+	s := runtime.NewScheme()
+	err := v1alpha1.AddToScheme(s)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	chart := &v1alpha1.Chart{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "hello-world",
+			Namespace: "giantswarm",
+		},
+		Spec: v1alpha1.ChartSpec{
+			Name:      "hello-world",
+			Namespace: "default",
+		},
+	}
+
+	return k8sclienttest.NewClients(k8sclienttest.ClientsConfig{
+		CtrlClient: ctrlfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(chart).Build(),
+		K8sClient: fake.NewSimpleClientset(
+			// Release of the chart CR.
+			newReleaseSecret("default", "hello-world", 1, true),
+			// Release without chart CR in another namespace.
+			newReleaseSecret("giantswarm", "hello-world", 1, true),
+			// Orphan release with multiple revisions.
+			newReleaseSecret("monitoring", "prometheus", 1, true),
+			newReleaseSecret("monitoring", "prometheus", 2, true),
+			// Release not managed by chart-operator.
+			newReleaseSecret("default", "manual", 1, false),
+		),
+	})
+}
+
+func Test_Find(t *testing.T) {
+	orphans, err := Find(context.Background(), newClients(t))
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	var names []string
+	for _, o := range orphans {
+		names = append(names, fmt.Sprintf("%s (%s)", o.String(), o.Secret.Name))
+	}
+
+	expected := []string{
+		"giantswarm/hello-world (sh.helm.release.v1.hello-world.v1)",
+		"monitoring/prometheus (sh.helm.release.v1.prometheus.v2)",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("orphans == %v, want %v", names, expected)
+	}
+}
+
+func Test_GC_Collect(t *testing.T) {
+	ctx := context.Background()
+
+	helmClient := &deletingHelmClient{
+		Interface: helmclienttest.New(helmclienttest.Config{}),
+	}
+	event := record.NewFakeRecorder(10)
+
+	g, err := NewGC(GCConfig{
+		Event:      event,
+		HelmClient: helmClient,
+		K8sClient:  newClients(t),
+		Logger:     microloggertest.New(),
+
+		GracePeriod: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	now := time.Now()
+
+	testCases := []struct {
+		name            string
+		now             time.Time
+		expectedDeleted []string
+	}{
+		{
+			name: "case 0: orphans are found",
+			now:  now,
+		},
+		{
+			name: "case 1: grace period has not passed",
+			now:  now.Add(30 * time.Minute),
+		},
+		{
+			name: "case 2: grace period has passed",
+			now:  now.Add(time.Hour),
+			expectedDeleted: []string{
+				"giantswarm/hello-world",
+				"monitoring/prometheus",
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			err := g.Collect(ctx, tc.now)
+			if err != nil {
+				t.Fatalf("error == %#v, want nil", err)
+			}
+
+			if !reflect.DeepEqual(helmClient.deleted, tc.expectedDeleted) {
+				t.Fatalf("deleted == %v, want %v", helmClient.deleted, tc.expectedDeleted)
+			}
+		})
+	}
+
+	if len(event.Events) != 2 {
+		t.Fatalf("len(events) == %d, want 2", len(event.Events))
+	}
+}
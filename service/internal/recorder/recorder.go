@@ -0,0 +1,39 @@
+package recorder
+
+import (
+	"github.com/giantswarm/k8sclient/v7/pkg/k8sclient"
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+type Config struct {
+	K8sClient k8sclient.Interface
+
+	Component string
+}
+
+// New creates an event recorder emitting Kubernetes events for objects known
+// by the scheme of the given client. Events are published asynchronously by
+// a broadcaster so emitting them never blocks reconciliation.
+func New(config Config) (record.EventRecorder, error) {
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.K8sClient must not be empty", config)
+	}
+
+	if config.Component == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Component must not be empty", config)
+	}
+
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+		Interface: config.K8sClient.K8sClient().CoreV1().Events(""),
+	})
+
+	source := corev1.EventSource{
+		Component: config.Component,
+	}
+
+	return eventBroadcaster.NewRecorder(config.K8sClient.Scheme(), source), nil
+}
@@ -0,0 +1,92 @@
+// Package releasemetrics records the duration and outcome of the Helm
+// operations and the drifted objects of the releases of chart CRs. The
+// metrics are exposed by the release collector of the collector set.
+package releasemetrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "chart_operator"
+	subsystem = "release"
+)
+
+// Metrics holds the metrics of the releases of chart CRs. It implements
+// prometheus.Collector.
+type Metrics struct {
+	driftedObjects    *prometheus.GaugeVec
+	operationDuration *prometheus.HistogramVec
+	operations        *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		driftedObjects: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: subsystem,
+				Name:      "drifted_objects",
+				Help:      "Number of objects of the deployed release which were modified or deleted in the cluster.",
+			},
+			[]string{"name", "namespace"},
+		),
+		operationDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Subsystem: subsystem,
+				Name:      "operation_duration_seconds",
+				Help:      "Duration of Helm operations and chart pulls in seconds.",
+				Buckets:   []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600, 1200},
+			},
+			[]string{"operation", "outcome", "chart", "namespace"},
+		),
+		operations: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: subsystem,
+				Name:      "operations_total",
+				Help:      "Number of Helm operations and chart pulls.",
+			},
+			[]string{"operation", "outcome", "chart", "namespace"},
+		),
+	}
+
+	return m
+}
+
+// ObserveOperation records the duration and outcome of the operation of the
+// chart CR with the given name and namespace.
+func (m *Metrics) ObserveOperation(name, namespace, operation, outcome string, duration time.Duration) {
+	m.operationDuration.WithLabelValues(operation, outcome, name, namespace).Observe(duration.Seconds())
+	m.operations.WithLabelValues(operation, outcome, name, namespace).Inc()
+}
+
+// SetDriftedObjects records the number of drifted objects of the release of
+// the chart CR with the given name and namespace.
+func (m *Metrics) SetDriftedObjects(name, namespace string, count int) {
+	m.driftedObjects.WithLabelValues(name, namespace).Set(float64(count))
+}
+
+// DeleteDriftedObjects removes the drifted objects of the release of the
+// chart CR with the given name and namespace, e.g. when drift detection is
+// disabled or the chart CR is deleted.
+func (m *Metrics) DeleteDriftedObjects(name, namespace string) {
+	m.driftedObjects.DeleteLabelValues(name, namespace)
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.driftedObjects.Collect(ch)
+	m.operationDuration.Collect(ch)
+	m.operations.Collect(ch)
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.driftedObjects.Describe(ch)
+	m.operationDuration.Describe(ch)
+	m.operations.Describe(ch)
+}
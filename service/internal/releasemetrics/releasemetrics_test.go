@@ -0,0 +1,77 @@
+package releasemetrics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func Test_Metrics(t *testing.T) {
+	m := New()
+
+	registry := prometheus.NewPedanticRegistry()
+	err := registry.Register(m)
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	// Chart CRs with the same name in different namespaces are recorded
+	// separately.
+	m.ObserveOperation("hello-world", "org-acme", "upgrade", "success", time.Second)
+	m.ObserveOperation("hello-world", "org-example", "upgrade", "success", time.Second)
+	m.ObserveOperation("hello-world", "org-example", "upgrade", "success", time.Second)
+
+	m.SetDriftedObjects("hello-world", "org-acme", 2)
+	m.SetDriftedObjects("hello-world", "org-example", 1)
+	m.DeleteDriftedObjects("hello-world", "org-example")
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("error == %#v, want nil", err)
+	}
+
+	// values holds the value of every series by metric name and namespace
+	// label.
+	values := map[string]map[string]float64{}
+	for _, f := range families {
+		values[f.GetName()] = map[string]float64{}
+
+		for _, metric := range f.GetMetric() {
+			var namespace string
+			for _, l := range metric.GetLabel() {
+				if l.GetName() == "namespace" {
+					namespace = l.GetValue()
+				}
+			}
+
+			switch {
+			case metric.GetCounter() != nil:
+				values[f.GetName()][namespace] = metric.GetCounter().GetValue()
+			case metric.GetGauge() != nil:
+				values[f.GetName()][namespace] = metric.GetGauge().GetValue()
+			case metric.GetHistogram() != nil:
+				values[f.GetName()][namespace] = float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+
+	expected := map[string]map[string]float64{
+		"chart_operator_release_drifted_objects": {
+			"org-acme": 2,
+		},
+		"chart_operator_release_operation_duration_seconds": {
+			"org-acme":    1,
+			"org-example": 2,
+		},
+		"chart_operator_release_operations_total": {
+			"org-acme":    1,
+			"org-example": 2,
+		},
+	}
+
+	if !reflect.DeepEqual(values, expected) {
+		t.Fatalf("values == %v, want %v", values, expected)
+	}
+}
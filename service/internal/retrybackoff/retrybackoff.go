@@ -0,0 +1,62 @@
+// Package retrybackoff implements the exponential back-off policy used to
+// throttle retries of failed Helm installs and upgrades.
+package retrybackoff
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+// DefaultPolicy is used when no policy is configured.
+var DefaultPolicy = Policy{
+	BaseDelay: 30 * time.Second,
+	MaxDelay:  30 * time.Minute,
+	Jitter:    0.2,
+}
+
+// Policy doubles the delay with every failed attempt, starting at the base
+// delay and capped at the max delay.
+type Policy struct {
+	// BaseDelay is the delay after the first failed attempt.
+	BaseDelay time.Duration
+	// MaxDelay is the upper bound of the delay.
+	MaxDelay time.Duration
+	// Jitter is the fraction between 0 and 1 by which the delay is randomly
+	// shortened so releases failing together do not retry together.
+	Jitter float64
+}
+
+// Validate returns an invalidConfigError when the policy cannot be used.
+func (p Policy) Validate() error {
+	if p.BaseDelay <= 0 {
+		return microerror.Maskf(invalidConfigError, "%T.BaseDelay must be positive", p)
+	}
+	if p.MaxDelay < p.BaseDelay {
+		return microerror.Maskf(invalidConfigError, "%T.MaxDelay must not be less than %T.BaseDelay", p, p)
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return microerror.Maskf(invalidConfigError, "%T.Jitter must be between 0 and 1", p)
+	}
+
+	return nil
+}
+
+// Delay returns the delay before retrying after the given number of failed
+// attempts in a row.
+func (p Policy) Delay(attempts int) time.Duration {
+	return p.delay(attempts, rand.Float64()) //nolint:gosec
+}
+
+func (p Policy) delay(attempts int, random float64) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempts && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+
+	return d - time.Duration(float64(d)*p.Jitter*random)
+}
@@ -0,0 +1,113 @@
+package retrybackoff
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_Policy_delay(t *testing.T) {
+	policy := Policy{
+		BaseDelay: 30 * time.Second,
+		MaxDelay:  10 * time.Minute,
+		Jitter:    0.5,
+	}
+
+	testCases := []struct {
+		name          string
+		attempts      int
+		random        float64
+		expectedDelay time.Duration
+	}{
+		{
+			name:          "case 0: first attempt",
+			attempts:      1,
+			expectedDelay: 30 * time.Second,
+		},
+		{
+			name:          "case 1: third attempt",
+			attempts:      3,
+			expectedDelay: 2 * time.Minute,
+		},
+		{
+			name:          "case 2: capped at max delay",
+			attempts:      6,
+			expectedDelay: 10 * time.Minute,
+		},
+		{
+			name:          "case 3: many attempts do not overflow",
+			attempts:      1000,
+			expectedDelay: 10 * time.Minute,
+		},
+		{
+			name:          "case 4: full jitter",
+			attempts:      3,
+			random:        1,
+			expectedDelay: time.Minute,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			result := policy.delay(tc.attempts, tc.random)
+			if result != tc.expectedDelay {
+				t.Fatalf("delay == %s, want %s", result, tc.expectedDelay)
+			}
+		})
+	}
+}
+
+func Test_Policy_Validate(t *testing.T) {
+	testCases := []struct {
+		name         string
+		policy       Policy
+		errorMatcher func(error) bool
+	}{
+		{
+			name:   "case 0: default policy",
+			policy: DefaultPolicy,
+		},
+		{
+			name: "case 1: no base delay",
+			policy: Policy{
+				MaxDelay: time.Minute,
+			},
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 2: max delay less than base delay",
+			policy: Policy{
+				BaseDelay: time.Minute,
+				MaxDelay:  time.Second,
+			},
+			errorMatcher: IsInvalidConfig,
+		},
+		{
+			name: "case 3: jitter greater than 1",
+			policy: Policy{
+				BaseDelay: time.Second,
+				MaxDelay:  time.Minute,
+				Jitter:    1.5,
+			},
+			errorMatcher: IsInvalidConfig,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Log(tc.name)
+
+			err := tc.policy.Validate()
+			switch {
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("error == %#v, want nil", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("error == nil, want non-nil")
+			case err != nil && !tc.errorMatcher(err):
+				t.Fatalf("error == %#v, want matching", err)
+			}
+		})
+	}
+}
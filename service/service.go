@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 
 	applicationv1alpha1 "github.com/giantswarm/apiextensions-application/api/v1alpha1"
@@ -14,7 +15,10 @@ import (
 	"github.com/giantswarm/micrologger"
 	"github.com/spf13/afero"
 	"github.com/spf13/viper"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	cr "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -24,7 +28,15 @@ import (
 	"github.com/giantswarm/chart-operator/v4/service/collector"
 	"github.com/giantswarm/chart-operator/v4/service/controller/chart"
 
+	"github.com/giantswarm/chart-operator/v4/service/internal/chartcache"
+	"github.com/giantswarm/chart-operator/v4/service/internal/chartverifier"
 	"github.com/giantswarm/chart-operator/v4/service/internal/clientpair"
+	"github.com/giantswarm/chart-operator/v4/service/internal/imagerewrite"
+	"github.com/giantswarm/chart-operator/v4/service/internal/maintenancewindow"
+	"github.com/giantswarm/chart-operator/v4/service/internal/orphanrelease"
+	"github.com/giantswarm/chart-operator/v4/service/internal/recorder"
+	"github.com/giantswarm/chart-operator/v4/service/internal/releasemetrics"
+	"github.com/giantswarm/chart-operator/v4/service/internal/retrybackoff"
 )
 
 const (
@@ -50,6 +62,7 @@ type Service struct {
 	bootOnce          sync.Once
 	chartController   *chart.Chart
 	operatorCollector *collector.Set
+	orphanReleaseGC   *orphanrelease.GC
 }
 
 // New creates a new service with given configuration.
@@ -161,21 +174,144 @@ func New(config Config) (*Service, error) {
 		return nil, microerror.Mask(err)
 	}
 
+	var chartCache *chartcache.Cache
+	{
+		// The cache is disabled when no max size is configured.
+		var maxSize int64
+		if v := config.Viper.GetString(config.Flag.Service.Helm.Cache.MaxSize); v != "" {
+			q, err := resource.ParseQuantity(v)
+			if err != nil {
+				return nil, microerror.Maskf(invalidConfigError, "%#q must be a quantity: %s", config.Flag.Service.Helm.Cache.MaxSize, err.Error())
+			}
+			maxSize = q.Value()
+		}
+
+		c := chartcache.Config{
+			Fs:     fs,
+			Logger: config.Logger,
+
+			Directory: config.Viper.GetString(config.Flag.Service.Helm.Cache.Directory),
+			MaxAge:    config.Viper.GetDuration(config.Flag.Service.Helm.Cache.MaxAge),
+			MaxSize:   maxSize,
+		}
+
+		chartCache, err = chartcache.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var chartVerifier *chartverifier.Verifier
+	{
+		var keyrings []chartverifier.Keyring
+		for _, k := range config.Viper.GetStringSlice(config.Flag.Service.Helm.Verification.Keyrings) {
+			urlPrefix, path, ok := strings.Cut(k, "=")
+			if !ok {
+				return nil, microerror.Maskf(invalidConfigError, "%#q must be in <tarball URL prefix>=<keyring path> format, got %#q", config.Flag.Service.Helm.Verification.Keyrings, k)
+			}
+
+			keyrings = append(keyrings, chartverifier.Keyring{
+				URLPrefix: urlPrefix,
+				Path:      path,
+			})
+		}
+
+		c := chartverifier.Config{
+			Fs:     fs,
+			Logger: config.Logger,
+
+			Keyrings: keyrings,
+		}
+
+		chartVerifier, err = chartverifier.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var retryPolicy retrybackoff.Policy
+	{
+		retryPolicy = retrybackoff.Policy{
+			BaseDelay: config.Viper.GetDuration(config.Flag.Service.Helm.Retry.BaseDelay),
+			MaxDelay:  config.Viper.GetDuration(config.Flag.Service.Helm.Retry.MaxDelay),
+			Jitter:    config.Viper.GetFloat64(config.Flag.Service.Helm.Retry.Jitter),
+		}
+		if retryPolicy == (retrybackoff.Policy{}) {
+			retryPolicy = retrybackoff.DefaultPolicy
+		}
+
+		err = retryPolicy.Validate()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var maintenanceWindow maintenancewindow.Window
+	{
+		schedule := config.Viper.GetString(config.Flag.Service.Helm.MaintenanceWindow.Schedule)
+		if schedule != "" {
+			maintenanceWindow, err = maintenancewindow.New(schedule, config.Viper.GetDuration(config.Flag.Service.Helm.MaintenanceWindow.Duration))
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+	}
+
+	var imageRewriter *imagerewrite.Rewriter
+	{
+		sourceRegistries := config.Viper.GetStringSlice(config.Flag.Service.Image.RewriteRegistries)
+		if len(sourceRegistries) > 0 {
+			c := imagerewrite.Config{
+				Registry:         config.Viper.GetString(config.Flag.Service.Image.Registry),
+				SourceRegistries: sourceRegistries,
+			}
+
+			imageRewriter, err = imagerewrite.New(c)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+	}
+
+	var eventRecorder record.EventRecorder
+	{
+		c := recorder.Config{
+			K8sClient: k8sPrvClient,
+
+			Component: project.Name(),
+		}
+
+		eventRecorder, err = recorder.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	releaseMetrics := releasemetrics.New()
+
 	var chartController *chart.Chart
 	{
 		c := chart.Config{
-			Fs:          fs,
-			HelmClients: helmClients,
-			Logger:      config.Logger,
-			K8sClient:   k8sPrvClient,
+			ChartCache:     chartCache,
+			ChartVerifier:  chartVerifier,
+			Event:          eventRecorder,
+			Fs:             fs,
+			HelmClients:    helmClients,
+			Logger:         config.Logger,
+			K8sClient:      k8sPrvClient,
+			ReleaseMetrics: releaseMetrics,
 
 			ResyncPeriod: config.Viper.GetDuration(config.Flag.Service.Controller.ResyncPeriod),
 
-			HTTPClientTimeout: config.Viper.GetDuration(config.Flag.Service.Helm.HTTP.ClientTimeout),
-			K8sWaitTimeout:    config.Viper.GetDuration(config.Flag.Service.Helm.Kubernetes.WaitTimeout),
-			K8sWatchNamespace: config.Viper.GetString(config.Flag.Service.Kubernetes.Watch.Namespace),
-			MaxRollback:       config.Viper.GetInt(config.Flag.Service.Helm.MaxRollback),
-			TillerNamespace:   config.Viper.GetString(config.Flag.Service.Helm.TillerNamespace),
+			HTTPClientTimeout:     config.Viper.GetDuration(config.Flag.Service.Helm.HTTP.ClientTimeout),
+			ImageRewriter:         imageRewriter,
+			K8sWaitTimeout:        config.Viper.GetDuration(config.Flag.Service.Helm.Kubernetes.WaitTimeout),
+			K8sWatchNamespace:     config.Viper.GetString(config.Flag.Service.Kubernetes.Watch.Namespace),
+			MaintenanceWindow:     maintenanceWindow,
+			MaxRollback:           config.Viper.GetInt(config.Flag.Service.Helm.MaxRollback),
+			MigrateHelmV2Releases: config.Viper.GetBool(config.Flag.Service.Helm.MigrateHelmV2Releases),
+			RetryPolicy:           retryPolicy,
+			TillerNamespace:       config.Viper.GetString(config.Flag.Service.Helm.TillerNamespace),
 		}
 
 		chartController, err = chart.NewChart(c)
@@ -189,8 +325,10 @@ func New(config Config) (*Service, error) {
 		c := collector.SetConfig{
 			// Collector must use client with elevated privileges in order to
 			// look for orphaned ConfigMap and Secrets
-			K8sClient: k8sPrvClient,
-			Logger:    config.Logger,
+			ChartCache:     chartCache,
+			K8sClient:      k8sPrvClient,
+			Logger:         config.Logger,
+			ReleaseMetrics: releaseMetrics,
 
 			TillerNamespace: config.Viper.GetString(config.Flag.Service.Helm.TillerNamespace),
 		}
@@ -201,6 +339,27 @@ func New(config Config) (*Service, error) {
 		}
 	}
 
+	// orphanReleaseGC uninstalls releases whose chart CR was deleted. It is
+	// nil when garbage collection is disabled.
+	var orphanReleaseGC *orphanrelease.GC
+	if config.Viper.GetBool(config.Flag.Service.Helm.OrphanRelease.GC) {
+		c := orphanrelease.GCConfig{
+			Event: eventRecorder,
+			// Releases may be installed in any namespace, so they are
+			// uninstalled with the privileged Helm client.
+			HelmClient: prvHelmClient,
+			K8sClient:  k8sPrvClient,
+			Logger:     config.Logger,
+
+			GracePeriod: config.Viper.GetDuration(config.Flag.Service.Helm.OrphanRelease.GracePeriod),
+		}
+
+		orphanReleaseGC, err = orphanrelease.NewGC(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var versionService *version.Service
 	{
 		versionConfig := version.Config{
@@ -223,6 +382,7 @@ func New(config Config) (*Service, error) {
 		bootOnce:          sync.Once{},
 		chartController:   chartController,
 		operatorCollector: operatorCollector,
+		orphanReleaseGC:   orphanReleaseGC,
 	}
 
 	return s, nil
@@ -239,6 +399,10 @@ func (s *Service) Boot(ctx context.Context) {
 		}()
 
 		go s.chartController.Boot(ctx)
+
+		if s.orphanReleaseGC != nil {
+			go s.orphanReleaseGC.Boot(ctx)
+		}
 	})
 }
 
@@ -277,6 +441,7 @@ func newK8sClient(config Config, restConfig *rest.Config) (k8sclient.Interface,
 		Logger: config.Logger,
 		SchemeBuilder: k8sclient.SchemeBuilder{
 			applicationv1alpha1.AddToScheme,
+			apiextensionsv1.AddToScheme,
 		},
 
 		RestConfig: restConfig,